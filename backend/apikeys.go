@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix distingue une clé d'API d'un jeton JWT dans l'en-tête Authorization
+const apiKeyPrefix = "usk_"
+
+// apiKeyDisplayLength est le nombre de caractères de la clé conservés pour la reconnaître
+const apiKeyDisplayLength = len(apiKeyPrefix) + 8
+
+// generateAPIKey retourne une nouvelle clé d'API aléatoire, son préfixe affichable et son hash
+func generateAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyDisplayLength], hashAPIKey(key), nil
+}
+
+// hashAPIKey retourne le hash SHA-256 d'une clé. La clé est aléatoire et longue :
+// contrairement à un mot de passe, un hash rapide suffit et permet la recherche par hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// isAPIKey indique si la valeur de l'en-tête Authorization est une clé d'API plutôt qu'un jeton
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// AuthenticateKey retourne la clé d'API non révoquée correspondante et enregistre son utilisation.
+// Retourne ErrInvalidToken si la clé est inconnue ou révoquée.
+func (a *authenticator) AuthenticateKey(ctx context.Context, key string) (APIKey, error) {
+	if a.keys == nil {
+		return APIKey{}, ErrInvalidToken
+	}
+	k, err := a.keys.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return APIKey{}, ErrInvalidToken
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+	if err := a.keys.TouchAPIKey(ctx, k.ID, time.Now()); err != nil {
+		return APIKey{}, err
+	}
+	return k, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestAPIKey crée une clé d'API avec les portées fournies et la retourne, avec la clé en clair
+func createTestAPIKey(t *testing.T, r http.Handler, token string, scopes ...string) APIKeyResponse {
+	w := performRequestWithToken(r, "POST", "/api/api-keys", token, APIKeyRequest{Name: "Borne d'inscription", Scopes: scopes})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var key APIKeyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &key))
+	return key
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	r, store, _ := setupAuthRouter(t)
+	admin := loginTestStaff(t, r, "admin@test.com")
+	createTestStaff(t, store, "coordo@test.com", RoleCoordinator)
+	coordinator := loginTestStaff(t, r, "coordo@test.com").AccessToken
+
+	// Seul un administrateur gère les clés
+	w := performRequestWithToken(r, "POST", "/api/api-keys", coordinator, APIKeyRequest{Name: "Borne", Scopes: []string{ScopeReadUsers}})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequestWithToken(r, "POST", "/api/api-keys", admin.AccessToken, APIKeyRequest{Name: "Borne", Scopes: []string{"users:delete"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequestWithToken(r, "POST", "/api/api-keys", admin.AccessToken, APIKeyRequest{Name: "Borne"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	reader := createTestAPIKey(t, r, admin.AccessToken, ScopeReadUsers)
+	writer := createTestAPIKey(t, r, admin.AccessToken, ScopeReadUsers, ScopeWriteUsers)
+	exporter := createTestAPIKey(t, r, admin.AccessToken, ScopeExport)
+	assert.True(t, strings.HasPrefix(reader.Key, apiKeyPrefix))
+	assert.True(t, strings.HasPrefix(reader.Key, reader.Prefix))
+	assert.Equal(t, []string{ScopeReadUsers}, reader.Scopes)
+	require.NotNil(t, reader.CreatedBy)
+	assert.Equal(t, admin.Staff.ID, *reader.CreatedBy)
+
+	userID := insertTestUser(t, store.db, "jean@test.com", "NAGEUR 3")
+	userPath := "/api/users/" + strconv.Itoa(userID)
+	newUser := UserRequest{FirstName: "Léa", LastName: "Roy", Email: "lea@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"}
+
+	w = performRequestWithToken(r, "GET", userPath, reader.Key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/levels", exporter.Key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequestWithToken(r, "POST", "/api/users", reader.Key, newUser)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ScopeWriteUsers)
+	w = performRequestWithToken(r, "GET", "/api/users", exporter.Key, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequestWithToken(r, "POST", "/api/users", writer.Key, newUser)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Une clé n'accède pas aux autres routes, quelle que soit sa portée
+	for _, req := range []struct{ method, path string }{
+		{"DELETE", userPath},
+		{"GET", "/api/courses"},
+		{"GET", userPath + "/evaluations"},
+		{"GET", "/api/auth/me"},
+		{"GET", "/api/api-keys"},
+	} {
+		w = performRequestWithToken(r, req.method, req.path, writer.Key, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, req.method+" "+req.path)
+	}
+
+	w = performRequestWithToken(r, "GET", "/api/users/export", reader.Key, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/users/export", exporter.Key, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+
+	// La date de dernière utilisation est enregistrée; la clé n'est jamais relistée
+	w = performRequestWithToken(r, "GET", "/api/api-keys", admin.AccessToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), reader.Key)
+	var list struct {
+		APIKeys []APIKey `json:"api_keys"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.APIKeys, 3)
+	for _, k := range list.APIKeys {
+		assert.NotNil(t, k.LastUsedAt, k.Name)
+	}
+}
+
+func TestAPIKeyRotationAndRevocation(t *testing.T) {
+	r, _, _ := setupAuthRouter(t)
+	admin := loginTestStaff(t, r, "admin@test.com").AccessToken
+	key := createTestAPIKey(t, r, admin, ScopeReadUsers)
+	keyPath := "/api/api-keys/" + strconv.Itoa(key.ID)
+
+	w := performRequestWithToken(r, "POST", keyPath+"/rotate", admin, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rotated APIKeyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.Equal(t, key.ID, rotated.ID)
+	assert.Equal(t, key.Scopes, rotated.Scopes)
+	assert.NotEqual(t, key.Key, rotated.Key)
+
+	w = performRequestWithToken(r, "GET", "/api/users", key.Key, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/users", rotated.Key, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = performRequestWithToken(r, "DELETE", keyPath, admin, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/users", rotated.Key, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Une clé révoquée ne peut être ni renouvelée ni révoquée à nouveau
+	w = performRequestWithToken(r, "POST", keyPath+"/rotate", admin, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequestWithToken(r, "DELETE", keyPath, admin, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequestWithToken(r, "DELETE", "/api/api-keys/999", admin, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestExportUsers(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+	insertTestUser(t, testDB, "jean@test.com", "NAGEUR 3")
+	insertTestUser(t, testDB, "lea@test.com", "NAGEUR 5")
+
+	w := performRequest(r, "GET", "/api/users/export?filter_niveau=NAGEUR%203", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "usagers.csv")
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "email", records[0][3])
+	assert.Equal(t, "jean@test.com", records[1][3])
+	assert.Equal(t, "NAGEUR 3", records[1][6])
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeetingDates(t *testing.T) {
+	// Samedi 7 septembre au samedi 30 novembre 2024 : 13 samedis
+	dates, err := meetingDates(6, "2024-09-07", "2024-11-30", nil)
+	require.NoError(t, err)
+	require.Len(t, dates, 13)
+	assert.Equal(t, "2024-09-07", dates[0])
+	assert.Equal(t, "2024-11-30", dates[12])
+
+	// Le premier lundi suivant le début de session, sans le lundi de l'Action de grâce
+	dates, err = meetingDates(1, "2024-09-07", "2024-10-20", []string{"2024-10-14"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-09-09", "2024-09-16", "2024-09-23", "2024-09-30", "2024-10-07"}, dates)
+
+	// Dimanche = 7
+	dates, err = meetingDates(7, "2024-09-07", "2024-09-15", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-09-08", "2024-09-15"}, dates)
+
+	_, err = meetingDates(1, "2024-13-01", "2024-12-31", nil)
+	assert.Error(t, err)
+}
+
+func TestAttendance(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	course := createTestCourse(t, r, testCourseRequest("NAGEUR 3", 8))
+	coursePath := "/api/courses/" + strconv.Itoa(course.ID)
+
+	jean := insertTestUser(t, testDB, "jean@test.com", "NAGEUR 3")
+	marie := insertTestUser(t, testDB, "marie@test.com", "NAGEUR 3")
+	luc := insertTestUser(t, testDB, "luc@test.com", "NAGEUR 3")
+	for _, id := range []int{jean, marie, luc} {
+		w := performRequest(r, "POST", coursePath+"/enrollments", EnrollmentRequest{UserID: id})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	outsider := insertTestUser(t, testDB, "outsider@test.com", "NAGEUR 3")
+
+	// Génération du calendrier, sans le samedi de l'Action de grâce
+	w := performRequest(r, "POST", coursePath+"/meetings", MeetingsRequest{ExcludeDates: []string{"2024-10-12"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var calendar struct {
+		Meetings []Meeting `json:"meetings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &calendar))
+	require.Len(t, calendar.Meetings, 12)
+	assert.Equal(t, "2024-09-07", calendar.Meetings[0].Date)
+	assert.Equal(t, "09:00", calendar.Meetings[0].StartTime)
+
+	// Régénérer ne crée pas de doublons; le corps est optionnel
+	w = performRequest(r, "POST", coursePath+"/meetings", nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &calendar))
+	require.Len(t, calendar.Meetings, 13)
+
+	w = performRequest(r, "POST", coursePath+"/meetings", MeetingsRequest{ExcludeDates: []string{"12 octobre"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequest(r, "POST", "/api/courses/999/meetings", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	first := coursePath + "/meetings/" + strconv.Itoa(calendar.Meetings[0].ID) + "/attendance"
+	second := coursePath + "/meetings/" + strconv.Itoa(calendar.Meetings[1].ID) + "/attendance"
+
+	w = performRequest(r, "PUT", first, AttendanceRequest{Records: []AttendanceMark{
+		{UserID: jean, Status: AttendancePresent},
+		{UserID: marie, Status: AttendanceAbsent},
+		{UserID: luc, Status: AttendanceExcused, Note: "Malade"},
+	}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var sheet struct {
+		Attendance []AttendanceRecord `json:"attendance"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sheet))
+	require.Len(t, sheet.Attendance, 3)
+
+	// Un usager non inscrit ne peut pas être marqué; rien n'est enregistré
+	w = performRequest(r, "PUT", second, AttendanceRequest{Records: []AttendanceMark{
+		{UserID: jean, Status: AttendancePresent},
+		{UserID: outsider, Status: AttendancePresent},
+	}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequest(r, "GET", second, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sheet))
+	assert.Empty(t, sheet.Attendance)
+
+	w = performRequest(r, "PUT", second, AttendanceRequest{Records: []AttendanceMark{{UserID: jean, Status: "late"}}})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequest(r, "PUT", second, AttendanceRequest{})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequest(r, "PUT", coursePath+"/meetings/999/attendance", AttendanceRequest{Records: []AttendanceMark{{UserID: jean, Status: AttendancePresent}}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(r, "PUT", second, AttendanceRequest{Records: []AttendanceMark{
+		{UserID: jean, Status: AttendancePresent},
+		{UserID: marie, Status: AttendancePresent},
+		{UserID: luc, Status: AttendanceAbsent},
+	}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Marquer de nouveau corrige la présence
+	w = performRequest(r, "PUT", second, AttendanceRequest{Records: []AttendanceMark{{UserID: luc, Status: AttendancePresent}}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = performRequest(r, "GET", second, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sheet))
+	require.Len(t, sheet.Attendance, 3)
+	for _, record := range sheet.Attendance {
+		assert.Equal(t, AttendancePresent, record.Status)
+		require.NotNil(t, record.User)
+	}
+
+	w = performRequest(r, "GET", coursePath+"/meetings", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &calendar))
+	assert.Equal(t, 1, calendar.Meetings[0].Present)
+	assert.Equal(t, 1, calendar.Meetings[0].Absent)
+	assert.Equal(t, 1, calendar.Meetings[0].Excused)
+	assert.Equal(t, 3, calendar.Meetings[1].Present)
+
+	// Taux du cours : 4 présences sur 5 (l'absence motivée n'est pas comptée)
+	w = performRequest(r, "GET", coursePath+"/attendance", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report CourseAttendanceReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 13, report.Meetings)
+	assert.Equal(t, 4, report.Present)
+	require.NotNil(t, report.Rate)
+	assert.InDelta(t, 0.8, *report.Rate, 0.0001)
+	require.Len(t, report.Users, 3)
+	for _, u := range report.Users {
+		require.NotNil(t, u.Rate)
+		switch u.UserID {
+		case jean, luc:
+			assert.InDelta(t, 1.0, *u.Rate, 0.0001)
+		case marie:
+			assert.InDelta(t, 0.5, *u.Rate, 0.0001)
+		}
+	}
+
+	w = performRequest(r, "GET", "/api/users/"+strconv.Itoa(marie)+"/attendance", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var userReport UserAttendanceReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &userReport))
+	require.Len(t, userReport.Courses, 1)
+	assert.Equal(t, "NAGEUR 3", userReport.Courses[0].Level)
+	require.NotNil(t, userReport.Rate)
+	assert.InDelta(t, 0.5, *userReport.Rate, 0.0001)
+
+	// Un usager sans présence enregistrée n'a pas de taux
+	w = performRequest(r, "GET", "/api/users/"+strconv.Itoa(outsider)+"/attendance", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &userReport))
+	assert.Empty(t, userReport.Courses)
+	assert.Nil(t, userReport.Rate)
+
+	w = performRequest(r, "GET", "/api/users/999/attendance", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(r, "GET", "/api/courses/999/attendance", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
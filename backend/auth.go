@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Types de jetons émis par l'API
+const (
+	accessToken  = "access"
+	refreshToken = "refresh"
+	// Jetons du portail parents, refusés sur l'API du personnel (et inversement)
+	portalAccessToken  = "portal_access"
+	portalRefreshToken = "portal_refresh"
+)
+
+// tokenIssuer identifie l'émetteur des jetons (claim "iss")
+const tokenIssuer = "usagers-api"
+
+func init() {
+	// Dates des jetons à la microseconde : à la seconde près, un jeton émis juste après
+	// un changement de mot de passe serait refusé comme s'il avait été révoqué
+	jwt.TimePrecision = time.Microsecond
+}
+
+// Longueurs admises d'un mot de passe du personnel ou d'un tuteur (bcrypt ignore au-delà de 72 octets)
+const (
+	minPasswordLength = 10
+	maxPasswordLength = 72
+)
+
+// minSecretLength est la longueur minimale du secret HS256, en octets
+const minSecretLength = 32
+
+var (
+	// ErrInvalidCredentials est retournée lorsque l'email ou le mot de passe est invalide
+	ErrInvalidCredentials = errors.New("email ou mot de passe invalide")
+	// ErrInvalidToken est retournée lorsqu'un jeton est mal formé, expiré, révoqué ou du mauvais type
+	ErrInvalidToken = errors.New("jeton invalide ou expiré")
+)
+
+// authConfig regroupe la configuration de l'authentification
+type authConfig struct {
+	Disabled       bool          // AUTH_DISABLED=true : API ouverte (développement uniquement)
+	Algorithm      string        // HS256 (défaut) ou EdDSA
+	Secret         string        // Secret partagé HS256
+	PrivateKeyFile string        // Clé privée Ed25519 au format PEM (PKCS#8) pour EdDSA
+	AccessTTL      time.Duration // Durée de validité des jetons d'accès
+	RefreshTTL     time.Duration // Durée de validité des jetons de rafraîchissement
+}
+
+// signingKeys retourne la méthode et les clés de signature des jetons : le secret HS256
+// ou la clé privée Ed25519 lue depuis PrivateKeyFile
+func (cfg authConfig) signingKeys() (method jwt.SigningMethod, signKey, verifyKey interface{}, err error) {
+	switch cfg.Algorithm {
+	case "", "HS256":
+		if len(cfg.Secret) < minSecretLength {
+			return nil, nil, nil, fmt.Errorf("AUTH_JWT_SECRET doit contenir au moins %d caractères", minSecretLength)
+		}
+		return jwt.SigningMethodHS256, []byte(cfg.Secret), []byte(cfg.Secret), nil
+	case "EdDSA":
+		pem, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("lecture de AUTH_JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		key, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("clé privée Ed25519 invalide: %w", err)
+		}
+		return jwt.SigningMethodEdDSA, key, key.(crypto.Signer).Public().(ed25519.PublicKey), nil
+	default:
+		return nil, nil, nil, fmt.Errorf("AUTH_JWT_ALG invalide: %q (HS256 ou EdDSA)", cfg.Algorithm)
+	}
+}
+
+// tokenClaims représente le contenu d'un jeton d'accès ou de rafraîchissement
+type tokenClaims struct {
+	Type string `json:"typ"` // accessToken ou refreshToken
+	jwt.RegisteredClaims
+}
+
+// authenticator émet et valide les jetons signés du personnel et des tuteurs du portail parents
+type authenticator struct {
+	staff      StaffStore
+	portal     PortalStore // nil si le store ne gère pas le portail parents
+	keys       APIKeyStore // nil si le store ne gère pas les clés d'API
+	method     jwt.SigningMethod
+	signKey    interface{}
+	verifyKey  interface{}
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	dummyHash  []byte // Comparé lorsque l'email est inconnu, pour ne pas révéler les comptes existants
+}
+
+// newAuthenticator crée un authenticator à partir de la configuration et des clés fournies
+func newAuthenticator(cfg authConfig, staff StaffStore) (*authenticator, error) {
+	a := &authenticator{staff: staff, accessTTL: cfg.AccessTTL, refreshTTL: cfg.RefreshTTL}
+	if portal, ok := staff.(PortalStore); ok {
+		a.portal = portal
+	}
+	if keys, ok := staff.(APIKeyStore); ok {
+		a.keys = keys
+	}
+
+	var err error
+	if a.method, a.signKey, a.verifyKey, err = cfg.signingKeys(); err != nil {
+		return nil, err
+	}
+
+	dummy, err := bcrypt.GenerateFromPassword([]byte("mot de passe factice"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	a.dummyHash = dummy
+	return a, nil
+}
+
+// validatePassword vérifie que la longueur d'un mot de passe respecte la politique
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("le mot de passe doit contenir entre %d et %d caractères", minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// passwordPolicyError décrit un mot de passe refusé par validatePassword
+func passwordPolicyError(field string) FieldError {
+	return fieldError(field, "password_policy", "Le mot de passe doit contenir entre %d et %d caractères", minPasswordLength, maxPasswordLength)
+}
+
+// hashPassword retourne le hash bcrypt d'un mot de passe, après avoir vérifié sa longueur
+func hashPassword(password string) (string, error) {
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Login vérifie les identifiants d'un membre du personnel et émet ses jetons
+func (a *authenticator) Login(ctx context.Context, email, password string) (TokenResponse, error) {
+	staff, err := a.staff.GetStaffByEmail(ctx, email)
+	if err != nil && !errors.Is(err, ErrStaffNotFound) {
+		return TokenResponse{}, err
+	}
+	if !a.checkPassword(staff.PasswordHash, password) {
+		return TokenResponse{}, ErrInvalidCredentials
+	}
+	return a.issue(staff)
+}
+
+// Refresh échange un jeton de rafraîchissement contre une nouvelle paire de jetons.
+// L'ancien jeton de rafraîchissement est révoqué : il ne peut servir qu'une fois,
+// même si deux demandes concurrentes le présentent.
+func (a *authenticator) Refresh(ctx context.Context, token string) (TokenResponse, error) {
+	staff, claims, err := a.Authenticate(ctx, token, refreshToken)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	if err := a.consume(ctx, claims); err != nil {
+		return TokenResponse{}, err
+	}
+	return a.issue(staff)
+}
+
+// Revoke révoque un jeton jusqu'à son expiration
+func (a *authenticator) Revoke(ctx context.Context, claims *tokenClaims) error {
+	_, err := a.staff.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	return err
+}
+
+// consume révoque un jeton à usage unique; ErrInvalidToken s'il a déjà été utilisé
+func (a *authenticator) consume(ctx context.Context, claims *tokenClaims) error {
+	revoked, err := a.staff.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// Authenticate valide un jeton du type attendu et retourne le compte auquel il a été émis.
+// Retourne ErrInvalidToken si la signature, l'émetteur, l'expiration ou le type ne conviennent pas,
+// si le jeton a été révoqué ou si le compte n'existe plus.
+func (a *authenticator) Authenticate(ctx context.Context, token, tokenType string) (Staff, *tokenClaims, error) {
+	claims, id, err := a.verify(ctx, token, tokenType)
+	if err != nil {
+		return Staff{}, nil, err
+	}
+	staff, err := a.staff.GetStaff(ctx, id)
+	if errors.Is(err, ErrStaffNotFound) {
+		return Staff{}, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return Staff{}, nil, err
+	}
+	if issuedBefore(claims, staff.TokensRevokedAt) {
+		return Staff{}, nil, ErrInvalidToken
+	}
+	return staff, claims, nil
+}
+
+// verify vérifie la signature, l'émetteur, l'expiration et le type d'un jeton, ainsi qu'il n'a pas été révoqué.
+// Retourne ses claims et l'ID du compte auquel il a été émis.
+func (a *authenticator) verify(ctx context.Context, token, tokenType string) (*tokenClaims, int, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return a.verifyKey, nil
+	},
+		jwt.WithValidMethods([]string{a.method.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil || claims.Type != tokenType || claims.ID == "" || claims.IssuedAt == nil {
+		return nil, 0, ErrInvalidToken
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, 0, ErrInvalidToken
+	}
+
+	revoked, err := a.staff.IsTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if revoked {
+		return nil, 0, ErrInvalidToken
+	}
+	return claims, id, nil
+}
+
+// issuedBefore indique si le jeton a été émis avant la révocation de tous les jetons du compte
+func issuedBefore(claims *tokenClaims, revokedAt *time.Time) bool {
+	return revokedAt != nil && !claims.IssuedAt.Time.After(*revokedAt)
+}
+
+// checkPassword compare le mot de passe au hash bcrypt. Sans hash (compte inconnu ou sans accès),
+// un hash factice est comparé pour que le temps de réponse ne révèle pas les comptes existants.
+func (a *authenticator) checkPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// issue signe une nouvelle paire de jetons pour le compte du personnel
+func (a *authenticator) issue(staff Staff) (TokenResponse, error) {
+	pair, err := a.issuePair(staff.ID, accessToken, refreshToken)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	return TokenResponse{TokenPair: pair, Staff: staff.withPermissions()}, nil
+}
+
+// issuePair signe un jeton d'accès et un jeton de rafraîchissement des types donnés pour le compte
+func (a *authenticator) issuePair(subject int, accessType, refreshType string) (TokenPair, error) {
+	access, err := a.sign(subject, accessType, a.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := a.sign(subject, refreshType, a.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(a.accessTTL / time.Second),
+	}, nil
+}
+
+// sign signe un jeton du type donné, identifié par un jti aléatoire
+func (a *authenticator) sign(subject int, tokenType string, ttl time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := tokenClaims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   strconv.Itoa(subject),
+			ID:        hex.EncodeToString(jti),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(a.method, claims).SignedString(a.signKey)
+}
+
+// PortalLogin vérifie les identifiants d'un tuteur et émet ses jetons du portail parents
+func (a *authenticator) PortalLogin(ctx context.Context, email, password string) (PortalTokenResponse, error) {
+	acc, err := a.portal.GetGuardianAccount(ctx, email)
+	if err != nil && !errors.Is(err, ErrGuardianNotFound) {
+		return PortalTokenResponse{}, err
+	}
+	if !a.checkPassword(acc.PasswordHash, password) {
+		return PortalTokenResponse{}, ErrInvalidCredentials
+	}
+	return a.issuePortal(acc.Guardian)
+}
+
+// PortalRefresh échange un jeton de rafraîchissement du portail contre une nouvelle paire de jetons
+func (a *authenticator) PortalRefresh(ctx context.Context, token string) (PortalTokenResponse, error) {
+	guardian, claims, err := a.AuthenticateGuardian(ctx, token, portalRefreshToken)
+	if err != nil {
+		return PortalTokenResponse{}, err
+	}
+	if err := a.consume(ctx, claims); err != nil {
+		return PortalTokenResponse{}, err
+	}
+	return a.issuePortal(guardian)
+}
+
+// AuthenticateGuardian valide un jeton du portail du type attendu et retourne le tuteur auquel il a été émis.
+// Le jeton est refusé si le tuteur n'existe plus ou n'a plus accès au portail.
+func (a *authenticator) AuthenticateGuardian(ctx context.Context, token, tokenType string) (Guardian, *tokenClaims, error) {
+	claims, id, err := a.verify(ctx, token, tokenType)
+	if err != nil {
+		return Guardian{}, nil, err
+	}
+	acc, err := a.portal.GetGuardianAccountByID(ctx, id)
+	if errors.Is(err, ErrGuardianNotFound) {
+		return Guardian{}, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return Guardian{}, nil, err
+	}
+	if acc.PasswordHash == "" || issuedBefore(claims, acc.TokensRevokedAt) {
+		return Guardian{}, nil, ErrInvalidToken
+	}
+	return acc.Guardian, claims, nil
+}
+
+// issuePortal signe une nouvelle paire de jetons du portail pour le tuteur
+func (a *authenticator) issuePortal(guardian Guardian) (PortalTokenResponse, error) {
+	pair, err := a.issuePair(guardian.ID, portalAccessToken, portalRefreshToken)
+	if err != nil {
+		return PortalTokenResponse{}, err
+	}
+	return PortalTokenResponse{TokenPair: pair, Guardian: guardian}, nil
+}
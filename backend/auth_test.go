@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testStaffPassword = "mot-de-passe-du-personnel"
+
+func testAuthConfig() authConfig {
+	return authConfig{Secret: strings.Repeat("s", minSecretLength), AccessTTL: time.Minute, RefreshTTL: time.Hour}
+}
+
+// setupAuthRouter retourne un routeur dont l'API exige un jeton, avec un compte du personnel
+func setupAuthRouter(t *testing.T) (*gin.Engine, *SQLStore, *authenticator) {
+	testDB := setupTestDB(t)
+	t.Cleanup(func() { testDB.Close() })
+	store := newSQLiteStore(testDB)
+	createTestStaff(t, store, "admin@test.com", RoleAdmin)
+
+	a, err := newAuthenticator(testAuthConfig(), store)
+	require.NoError(t, err)
+	s := newServer(store)
+	s.auth = a
+	return setupRouterWithServer(s), store, a
+}
+
+// createTestStaff crée un compte du personnel avec testStaffPassword (hash bcrypt à coût minimal)
+func createTestStaff(t *testing.T, store StaffStore, email, role string) Staff {
+	hash, err := bcrypt.GenerateFromPassword([]byte(testStaffPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+	st, err := store.CreateStaff(context.Background(), email, "Personnel de test", role, string(hash))
+	require.NoError(t, err)
+	return st
+}
+
+// loginTestStaff connecte un compte du personnel et retourne ses jetons
+func loginTestStaff(t *testing.T, r http.Handler, email string) TokenResponse {
+	w := performRequest(r, "POST", "/api/auth/login", LoginRequest{Email: email, Password: testStaffPassword})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tokens TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	return tokens
+}
+
+func TestLoadConfigAuth(t *testing.T) {
+	cfg, _, err := loadConfig(nil, testEnv(nil))
+	require.NoError(t, err)
+	assert.False(t, cfg.Auth.Disabled)
+	assert.Equal(t, "HS256", cfg.Auth.Algorithm)
+	assert.Equal(t, 15*time.Minute, cfg.Auth.AccessTTL)
+	assert.Equal(t, 7*24*time.Hour, cfg.Auth.RefreshTTL)
+
+	// Le secret n'est lu que depuis l'environnement ou le fichier, pas depuis un drapeau
+	file := writeConfigFile(t, "auth.yaml", "auth:\n  jwt_secret: secret-du-fichier\n  access_ttl: 10m\n")
+	env := testEnv(map[string]string{"AUTH_DISABLED": "true", "AUTH_ACCESS_TTL": "5m"})
+	cfg, _, err = loadConfig([]string{"-config", file, "-auth-access-ttl", "1m"}, env)
+	require.NoError(t, err)
+	assert.True(t, cfg.Auth.Disabled)
+	assert.Equal(t, "secret-du-fichier", cfg.Auth.Secret)
+	assert.Equal(t, time.Minute, cfg.Auth.AccessTTL)
+	_, _, err = loadConfig([]string{"-auth-jwt-secret", "secret"}, testEnv(nil))
+	assert.Error(t, err)
+
+	for name, env := range map[string]map[string]string{
+		"durée négative":     {"AUTH_REFRESH_TTL": "-1h"},
+		"booléen invalide":   {"AUTH_DISABLED": "peut-être"},
+		"algorithme inconnu": {"AUTH_JWT_ALG": "none"},
+	} {
+		_, _, err = loadConfig(nil, testEnv(env))
+		assert.Error(t, err, name)
+	}
+
+	// Le secret et la clé privée sont vérifiés au démarrage du serveur, avant les migrations
+	cfg = defaultConfig()
+	assert.Error(t, cfg.checkAuth())
+	cfg.Auth = testAuthConfig()
+	assert.NoError(t, cfg.checkAuth())
+	cfg.Auth = authConfig{Disabled: true}
+	assert.NoError(t, cfg.checkAuth())
+}
+
+func TestNewAuthenticatorKeys(t *testing.T) {
+	cfg := testAuthConfig()
+	cfg.Secret = "trop-court"
+	_, err := newAuthenticator(cfg, nil)
+	assert.Error(t, err)
+
+	cfg = testAuthConfig()
+	cfg.Algorithm = "none"
+	_, err = newAuthenticator(cfg, nil)
+	assert.Error(t, err)
+
+	cfg.Algorithm = "EdDSA"
+	cfg.PrivateKeyFile = filepath.Join(t.TempDir(), "absente.pem")
+	_, err = newAuthenticator(cfg, nil)
+	assert.Error(t, err)
+}
+
+func TestAuthRequiredOnAPI(t *testing.T) {
+	r, _, _ := setupAuthRouter(t)
+
+	w := performRequest(r, "GET", "/api/users", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+	assert.Contains(t, w.Body.String(), `"code":"authentication_required"`)
+
+	w = performRequestWithToken(r, "GET", "/api/users", "pas-un-jeton", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Mauvais mot de passe et compte inconnu donnent la même réponse
+	w = performRequest(r, "POST", "/api/auth/login", LoginRequest{Email: "admin@test.com", Password: "mauvais-mot-de-passe"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/auth/login", LoginRequest{Email: "inconnu@test.com", Password: testStaffPassword})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/auth/login", LoginRequest{Email: "admin@test.com"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	tokens := loginTestStaff(t, r, "admin@test.com")
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, 60, tokens.ExpiresIn)
+	assert.Equal(t, "admin@test.com", tokens.Staff.Email)
+	assert.NotContains(t, performRequest(r, "POST", "/api/auth/login", LoginRequest{Email: "admin@test.com", Password: testStaffPassword}).Body.String(), "password_hash")
+
+	// La casse de l'email de connexion est ignorée, comme pour le portail
+	w = performRequest(r, "POST", "/api/auth/login", LoginRequest{Email: "Admin@Test.COM", Password: testStaffPassword})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = performRequestWithToken(r, "GET", "/api/users", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/auth/me", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "admin@test.com")
+
+	// Un jeton de rafraîchissement ne donne pas accès à l'API
+	w = performRequestWithToken(r, "GET", "/api/users", tokens.RefreshToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	r, _, _ := setupAuthRouter(t)
+	tokens := loginTestStaff(t, r, "admin@test.com")
+
+	// Rotation : l'ancien jeton de rafraîchissement ne sert qu'une fois
+	w := performRequest(r, "POST", "/api/auth/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var renewed TokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &renewed))
+	assert.NotEqual(t, tokens.RefreshToken, renewed.RefreshToken)
+	w = performRequest(r, "POST", "/api/auth/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/auth/refresh", RefreshRequest{RefreshToken: renewed.AccessToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequestWithToken(r, "POST", "/api/auth/logout", renewed.AccessToken, LogoutRequest{RefreshToken: renewed.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = performRequestWithToken(r, "GET", "/api/users", renewed.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/auth/refresh", RefreshRequest{RefreshToken: renewed.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Le premier jeton d'accès n'a pas été révoqué : il reste valide jusqu'à son expiration
+	w = performRequestWithToken(r, "GET", "/api/users", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRevokeStaffTokens(t *testing.T) {
+	r, store, a := setupAuthRouter(t)
+	tokens := loginTestStaff(t, r, "admin@test.com")
+	staff, err := store.GetStaffByEmail(context.Background(), "admin@test.com")
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeStaffTokens(context.Background(), staff.ID))
+	w := performRequestWithToken(r, "GET", "/api/users", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/auth/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Un jeton expiré est refusé
+	a.accessTTL = -time.Minute
+	expired, err := a.issue(Staff{ID: staff.ID})
+	require.NoError(t, err)
+	_, _, err = a.Authenticate(context.Background(), expired.AccessToken, accessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestEdDSATokens(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	r, store, hs256 := setupAuthRouter(t)
+	cfg := testAuthConfig()
+	cfg.Algorithm = "EdDSA"
+	cfg.PrivateKeyFile = keyFile
+	eddsa, err := newAuthenticator(cfg, store)
+	require.NoError(t, err)
+
+	tokens, err := eddsa.Login(context.Background(), "admin@test.com", testStaffPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", tokenAlgorithm(t, tokens.AccessToken))
+	staff, _, err := eddsa.Authenticate(context.Background(), tokens.AccessToken, accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "admin@test.com", staff.Email)
+
+	// Un jeton signé avec un autre algorithme est refusé
+	_, _, err = hs256.Authenticate(context.Background(), tokens.AccessToken, accessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	w := performRequestWithToken(r, "GET", "/api/users", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// tokenAlgorithm retourne l'algorithme de signature déclaré dans l'en-tête d'un jeton JWT
+func tokenAlgorithm(t *testing.T, token string) string {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	require.NoError(t, err)
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &header))
+	return header.Alg
+}
+
+func TestStaffCommand(t *testing.T) {
+	dbCfg := dbConfig{Driver: "sqlite3", DSN: filepath.Join(t.TempDir(), "staff.db")}
+	var out bytes.Buffer
+	require.NoError(t, runMigrateCommand(dbCfg, []string{"up"}, &out))
+
+	err := runStaffCommand(dbCfg, []string{"add", "admin@test.com", "Sophie", "Tremblay"}, strings.NewReader("court\n"), &out)
+	assert.Error(t, err)
+	assert.Error(t, runStaffCommand(dbCfg, []string{"add", "-role", "directeur", "admin@test.com", "Sophie"}, strings.NewReader(testStaffPassword+"\n"), &out))
+	require.NoError(t, runStaffCommand(dbCfg, []string{"add", "-role", "coordinator", "admin@test.com", "Sophie", "Tremblay"}, strings.NewReader(testStaffPassword+"\n"), &out))
+	err = runStaffCommand(dbCfg, []string{"add", "Admin@Test.com", "Autre"}, strings.NewReader(testStaffPassword+"\n"), &out)
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	out.Reset()
+	require.NoError(t, runStaffCommand(dbCfg, []string{"list"}, nil, &out))
+	assert.Contains(t, out.String(), "admin@test.com")
+	assert.Contains(t, out.String(), "Sophie Tremblay")
+	assert.Contains(t, out.String(), RoleCoordinator)
+
+	require.NoError(t, runStaffCommand(dbCfg, []string{"role", "ADMIN@test.com", RoleAdmin}, nil, &out))
+	assert.Error(t, runStaffCommand(dbCfg, []string{"role", "admin@test.com", "directeur"}, nil, &out))
+
+	require.NoError(t, runStaffCommand(dbCfg, []string{"passwd", "admin@test.com"}, strings.NewReader("nouveau-mot-de-passe\n"), &out))
+	require.NoError(t, runStaffCommand(dbCfg, []string{"revoke", "admin@test.com"}, nil, &out))
+	assert.ErrorIs(t, runStaffCommand(dbCfg, []string{"revoke", "inconnu@test.com"}, nil, &out), ErrStaffNotFound)
+	assert.Error(t, runStaffCommand(dbCfg, []string{"delete"}, nil, &out))
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runMigrateCommand implémente la commande `migrate up|down [n]|status`
+func runMigrateCommand(dbCfg dbConfig, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|status")
+	}
+
+	db, d, err := openDB(dbCfg.Driver, dbCfg.DSN, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db, d)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Fprintf(out, "appliquée  %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "Aucune migration en attente")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("nombre de migrations à annuler invalide: %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			fmt.Fprintf(out, "annulée    %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Fprintln(out, "Aucune migration à annuler")
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNOM\tÉTAT\tAPPLIQUÉE LE")
+		for _, st := range statuses {
+			state, appliedAt := "en attente", ""
+			if st.Applied {
+				state = "appliquée"
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Modified {
+				state = "modifiée"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return fmt.Errorf("sous-commande migrate inconnue: %q (attendu: up, down, status)", args[0])
+}
+
+// runStaffCommand implémente la commande
+// `staff list|add [-role rôle] <email> <nom>|role <email> <rôle>|passwd <email>|revoke <email>`.
+// Le mot de passe est lu sur l'entrée standard (ex: echo "$MOT_DE_PASSE" | ./main staff add ...).
+func runStaffCommand(dbCfg dbConfig, args []string, in io.Reader, out io.Writer) error {
+	usage := fmt.Errorf("usage: staff list|add [-role rôle] <email> <nom>|role <email> <rôle>|passwd <email>|revoke <email>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	db, d, err := openDB(dbCfg.Driver, dbCfg.DSN, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	store := newSQLStore(db, d)
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "list":
+		staff, err := store.ListStaff(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEMAIL\tNOM\tRÔLE\tCRÉÉ LE")
+		for _, st := range staff {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", st.ID, st.Email, st.Name, st.Role, st.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return w.Flush()
+
+	case args[0] == "add":
+		flags := flag.NewFlagSet("staff add", flag.ContinueOnError)
+		flags.SetOutput(out)
+		role := flags.String("role", RoleReadOnly, "rôle du compte ("+strings.Join(roles(), ", ")+")")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() < 2 {
+			return usage
+		}
+		if err := checkRole(*role); err != nil {
+			return err
+		}
+		hash, err := readPassword(in, out)
+		if err != nil {
+			return err
+		}
+		st, err := store.CreateStaff(ctx, flags.Arg(0), strings.Join(flags.Args()[1:], " "), *role, hash)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Compte %d (%s) créé pour %s\n", st.ID, st.Role, st.Email)
+		return nil
+
+	case args[0] == "role" && len(args) == 3:
+		if err := checkRole(args[2]); err != nil {
+			return err
+		}
+		st, err := store.GetStaffByEmail(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if err := store.SetStaffRole(ctx, st.ID, args[2]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Rôle de %s : %s\n", st.Email, args[2])
+		return nil
+
+	case args[0] == "passwd" && len(args) == 2:
+		st, err := store.GetStaffByEmail(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		hash, err := readPassword(in, out)
+		if err != nil {
+			return err
+		}
+		if err := store.SetStaffPassword(ctx, st.ID, hash); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Mot de passe de %s modifié; les jetons existants sont révoqués\n", st.Email)
+		return nil
+
+	case args[0] == "revoke" && len(args) == 2:
+		st, err := store.GetStaffByEmail(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if err := store.RevokeStaffTokens(ctx, st.ID); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Jetons de %s révoqués\n", st.Email)
+		return nil
+	}
+
+	return usage
+}
+
+// checkRole vérifie que le rôle existe
+func checkRole(role string) error {
+	if !validRole(role) {
+		return fmt.Errorf("rôle inconnu: %q (attendu: %s)", role, strings.Join(roles(), ", "))
+	}
+	return nil
+}
+
+// readPassword lit un mot de passe sur la première ligne de in et retourne son hash
+func readPassword(in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprint(out, "Mot de passe: ")
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("aucun mot de passe fourni sur l'entrée standard")
+	}
+	fmt.Fprintln(out)
+	return hashPassword(strings.TrimRight(scanner.Text(), "\r"))
+}
+
+// runHealthcheckCommand implémente la commande `healthcheck` : interroge /readyz du serveur local
+// et échoue s'il n'est pas prêt. Sert au healthcheck du conteneur, l'image n'ayant ni curl ni wget.
+func runHealthcheckCommand(addr string, out io.Writer) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + net.JoinHostPort(host, port) + "/readyz")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, strings.TrimSpace(string(body)))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("serveur non prêt (%s)", resp.Status)
+	}
+	return nil
+}
+
+// runCommand exécute une sous-commande de la ligne de commande, avec la base de données configurée.
+// Retourne false si args ne désigne aucune sous-commande connue.
+func runCommand(cfg config, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	var err error
+	switch args[0] {
+	case "migrate":
+		err = runMigrateCommand(cfg.DB, args[1:], os.Stdout)
+	case "staff":
+		err = runStaffCommand(cfg.DB, args[1:], os.Stdin, os.Stdout)
+	case "healthcheck":
+		err = runHealthcheckCommand(cfg.Addr, os.Stdout)
+	default:
+		return false
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Erreur:", err)
+		os.Exit(1)
+	}
+	return true
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// config regroupe la configuration du serveur. Chaque valeur est lue, par ordre de priorité :
+// drapeau de la ligne de commande, variable d'environnement, fichier de configuration, défaut
+// (voir settings pour le nom de chaque paramètre dans ces sources).
+type config struct {
+	Addr      string // Adresse d'écoute HTTP
+	Mode      string // Mode gin : debug, release ou test
+	StaticDir string // Fichiers statiques du frontend
+	IndexFile string // Page principale du frontend
+	DB        dbConfig
+
+	// Adresse d'écoute de /metrics, distincte de celle de l'API pour ne pas l'exposer publiquement
+	// (ex: 127.0.0.1:9090); vide : les métriques ne sont pas exposées
+	MetricsAddr string
+
+	Timeouts serverTimeouts
+
+	LogLevel  string // debug, info, warn ou error
+	LogFormat string // json ou text
+
+	CORS corsConfig
+	Auth authConfig
+
+	// Politique d'âge par niveau, lue par agePolicy
+	AgeCheck       string // reject, warn ou off
+	LevelAgeRanges string // Tranches remplaçant celles du catalogue, ex: "PRESCOLAIRE=3-5,NAGEUR_7=8-"
+
+	// Refuse (428) PUT, PATCH et DELETE /api/users/:id sans If-Match; désactivé par défaut,
+	// les clients de l'API n'envoyant pas tous If-Match
+	RequireIfMatch bool
+}
+
+// serverTimeouts regroupe les délais du serveur HTTP; 0 désactive le délai de lecture, d'écriture ou d'inactivité
+type serverTimeouts struct {
+	Read     time.Duration // Lecture d'une requête
+	Write    time.Duration // Écriture d'une réponse
+	Idle     time.Duration // Connexion keep-alive inactive
+	Shutdown time.Duration // Fin des requêtes en cours à l'arrêt
+}
+
+// dbConfig regroupe la connexion à la base de données
+type dbConfig struct {
+	Driver string // sqlite3 ou postgres
+	DSN    string // Chemin SQLite ou URL PostgreSQL
+}
+
+// defaultConfig retourne la configuration utilisée dans l'image Docker (répertoire de travail /app)
+func defaultConfig() config {
+	return config{
+		Addr:      ":8080",
+		Mode:      gin.DebugMode,
+		StaticDir: "./frontend/static",
+		IndexFile: "./frontend/index.html",
+		DB:        dbConfig{Driver: "sqlite3"}, // Sans DSN, SQLite utilise defaultSQLitePath
+
+		Timeouts: serverTimeouts{
+			Read:     15 * time.Second,
+			Write:    60 * time.Second, // Laisse le temps aux exports CSV volumineux
+			Idle:     120 * time.Second,
+			Shutdown: 10 * time.Second, // Inférieur au stop_grace_period de docker-compose
+		},
+
+		LogLevel:  "info",
+		LogFormat: "json",
+
+		CORS: corsConfig{MaxAge: 10 * time.Minute},
+		Auth: authConfig{Algorithm: "HS256", AccessTTL: 15 * time.Minute, RefreshTTL: 7 * 24 * time.Hour},
+
+		AgeCheck: ageCheckReject,
+	}
+}
+
+// setting est un paramètre de configuration et ses noms dans chaque source
+type setting struct {
+	key   string      // Clé du fichier; les sections sont séparées par un point (ex: database.dsn)
+	flag  string      // Drapeau de la ligne de commande; vide pour un secret, visible dans la liste des processus
+	env   []string    // Variables d'environnement, la première définie l'emporte
+	value interface{} // Champ de config : *string, *bool, *time.Duration ou *[]string (liste séparée par des virgules)
+	usage string
+}
+
+// settings retourne les paramètres de configuration, liés aux champs de cfg
+func (cfg *config) settings() []setting {
+	return []setting{
+		{"addr", "addr", []string{"HTTP_ADDR"}, &cfg.Addr, "adresse d'écoute HTTP (ex: :8080)"},
+		{"mode", "mode", []string{"GIN_MODE"}, &cfg.Mode, "mode gin : debug, release ou test"},
+		{"static_dir", "static-dir", []string{"STATIC_DIR"}, &cfg.StaticDir, "répertoire des fichiers statiques du frontend"},
+		{"index_file", "index-file", []string{"INDEX_FILE"}, &cfg.IndexFile, "page principale du frontend"},
+		{"database.driver", "db-driver", []string{"DB_DRIVER"}, &cfg.DB.Driver, "driver de base de données : sqlite3 ou postgres"},
+		{"database.dsn", "db-dsn", []string{"DB_DSN", "DATABASE_URL"}, &cfg.DB.DSN, "chemin SQLite ou URL de connexion PostgreSQL"},
+		{"metrics_addr", "metrics-addr", []string{"METRICS_ADDR"}, &cfg.MetricsAddr, "adresse d'écoute de /metrics (ex: 127.0.0.1:9090)"},
+
+		{"read_timeout", "read-timeout", []string{"HTTP_READ_TIMEOUT"}, &cfg.Timeouts.Read, "délai de lecture d'une requête (ex: 15s)"},
+		{"write_timeout", "write-timeout", []string{"HTTP_WRITE_TIMEOUT"}, &cfg.Timeouts.Write, "délai d'écriture d'une réponse (ex: 60s)"},
+		{"idle_timeout", "idle-timeout", []string{"HTTP_IDLE_TIMEOUT"}, &cfg.Timeouts.Idle, "délai d'inactivité d'une connexion keep-alive (ex: 2m)"},
+		{"shutdown_timeout", "shutdown-timeout", []string{"SHUTDOWN_TIMEOUT"}, &cfg.Timeouts.Shutdown, "délai accordé aux requêtes en cours à l'arrêt (ex: 10s)"},
+
+		{"log_level", "log-level", []string{"LOG_LEVEL"}, &cfg.LogLevel, "niveau minimal des logs : debug, info, warn ou error"},
+		{"log_format", "log-format", []string{"LOG_FORMAT"}, &cfg.LogFormat, "format des logs : json ou text"},
+
+		{"cors.allowed_origins", "cors-allowed-origins", []string{"CORS_ALLOWED_ORIGINS"}, &cfg.CORS.AllowedOrigins, "origines autorisées à appeler l'API, séparées par des virgules"},
+		{"cors.exposed_headers", "cors-exposed-headers", []string{"CORS_EXPOSED_HEADERS"}, &cfg.CORS.ExposedHeaders, "en-têtes de réponse exposés en plus de ETag et X-Request-ID"},
+		{"cors.max_age", "cors-max-age", []string{"CORS_MAX_AGE"}, &cfg.CORS.MaxAge, "durée de mise en cache des réponses preflight (ex: 10m)"},
+
+		{"auth.disabled", "auth-disabled", []string{"AUTH_DISABLED"}, &cfg.Auth.Disabled, "désactive l'authentification (développement uniquement)"},
+		{"auth.jwt_alg", "auth-jwt-alg", []string{"AUTH_JWT_ALG"}, &cfg.Auth.Algorithm, "algorithme de signature des jetons : HS256 ou EdDSA"},
+		{"auth.jwt_secret", "", []string{"AUTH_JWT_SECRET"}, &cfg.Auth.Secret, ""},
+		{"auth.jwt_private_key_file", "auth-jwt-private-key-file", []string{"AUTH_JWT_PRIVATE_KEY_FILE"}, &cfg.Auth.PrivateKeyFile, "clé privée Ed25519 (PEM) pour EdDSA"},
+		{"auth.access_ttl", "auth-access-ttl", []string{"AUTH_ACCESS_TTL"}, &cfg.Auth.AccessTTL, "durée de validité des jetons d'accès (ex: 15m)"},
+		{"auth.refresh_ttl", "auth-refresh-ttl", []string{"AUTH_REFRESH_TTL"}, &cfg.Auth.RefreshTTL, "durée de validité des jetons de rafraîchissement (ex: 168h)"},
+
+		{"age_check", "age-check", []string{"AGE_CHECK"}, &cfg.AgeCheck, "vérification de l'âge par niveau : reject, warn ou off"},
+		{"level_age_ranges", "level-age-ranges", []string{"LEVEL_AGE_RANGES"}, &cfg.LevelAgeRanges, "tranches d'âge par programme ou niveau (ex: PRESCOLAIRE=3-5,NAGEUR_7=8-)"},
+
+		{"require_if_match", "require-if-match", []string{"REQUIRE_IF_MATCH"}, &cfg.RequireIfMatch, "refuse (428) la modification ou la suppression d'un usager sans If-Match"},
+	}
+}
+
+// set lit value selon le type du champ du paramètre et l'y enregistre
+func (st setting) set(value string) error {
+	switch dst := st.value.(type) {
+	case *string:
+		*dst = value
+	case *[]string:
+		*dst = splitList(value)
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("booléen invalide: %q (true ou false)", value)
+		}
+		*dst = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("durée invalide: %q (ex: 15s, 10m)", value)
+		}
+		*dst = d
+	}
+	return nil
+}
+
+// flagValue retient la valeur d'un drapeau, appliquée après le fichier et l'environnement
+type flagValue struct {
+	value *string
+	bool  bool // Drapeau booléen : -auth-disabled équivaut à -auth-disabled=true
+}
+
+func (f *flagValue) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return *f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	*f.value = value
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.bool
+}
+
+// loadConfig lit la configuration depuis les drapeaux de args, l'environnement (getenv) et le fichier
+// de configuration (-config ou CONFIG_FILE, YAML ou TOML selon l'extension), puis la valide.
+// Retourne aussi les arguments restants après les drapeaux (sous-commande éventuelle).
+func loadConfig(args []string, getenv func(string) string) (config, []string, error) {
+	cfg := defaultConfig()
+	settings := cfg.settings()
+
+	// Les drapeaux sont lus en premier, puis appliqués après le fichier et l'environnement
+	flags := make([]*string, len(settings))
+	fs := flag.NewFlagSet("usagers", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", getenv("CONFIG_FILE"), "fichier de configuration YAML ou TOML")
+	for i, st := range settings {
+		if st.flag == "" {
+			continue
+		}
+		_, isBool := st.value.(*bool)
+		flags[i] = new(string)
+		fs.Var(&flagValue{value: flags[i], bool: isBool}, st.flag, st.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return config{}, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile, settings); err != nil {
+			return config{}, nil, err
+		}
+	}
+	for _, st := range settings {
+		for _, name := range st.env {
+			if value := getenv(name); value != "" {
+				if err := st.set(value); err != nil {
+					return config{}, nil, fmt.Errorf("%s: %w", name, err)
+				}
+				break
+			}
+		}
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for i, st := range settings {
+		if st.flag == "" || !set[st.flag] {
+			continue
+		}
+		if err := st.set(*flags[i]); err != nil {
+			return config{}, nil, fmt.Errorf("-%s: %w", st.flag, err)
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile lit le fichier de configuration; les clés inconnues sont refusées.
+// Les listes sont acceptées sous forme de tableau ou de chaîne séparée par des virgules.
+func (cfg *config) loadFile(path string, settings []setting) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("lecture du fichier de configuration: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		return fmt.Errorf("fichier de configuration %s: extension non supportée (.yaml, .yml ou .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("fichier de configuration %s: %w", path, err)
+	}
+
+	byKey := map[string]setting{}
+	for _, st := range settings {
+		byKey[st.key] = st
+	}
+	flat := map[string]string{}
+	if err := flattenConfig("", values, flat); err != nil {
+		return fmt.Errorf("fichier de configuration %s: %w", path, err)
+	}
+	for key, value := range flat {
+		st, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("fichier de configuration %s: clé inconnue: %s", path, key)
+		}
+		if err := st.set(value); err != nil {
+			return fmt.Errorf("fichier de configuration %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// flattenConfig aplatit les sections du fichier en clés séparées par un point (ex: database.dsn)
+// et convertit chaque valeur en texte, comme une variable d'environnement
+func flattenConfig(prefix string, values map[string]interface{}, flat map[string]string) error {
+	for name, value := range values {
+		key := prefix + name
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flattenConfig(key+".", v, flat); err != nil {
+				return err
+			}
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			flat[key] = strings.Join(items, ",")
+		case nil:
+			flat[key] = ""
+		case string, bool, int, int64, uint64, float64:
+			flat[key] = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("%s: valeur non supportée (%T)", key, value)
+		}
+	}
+	return nil
+}
+
+// validate vérifie la configuration lue : adresses d'écoute, mode, base de données, délais, logs,
+// CORS, format de l'authentification et politique d'âge. Les clés de signature des jetons sont
+// vérifiées par checkAuth, au démarrage du serveur seulement.
+func (cfg config) validate() error {
+	if err := checkListenAddr(cfg.Addr); err != nil {
+		return err
+	}
+	if cfg.MetricsAddr != "" {
+		if err := checkListenAddr(cfg.MetricsAddr); err != nil {
+			return fmt.Errorf("metrics_addr: %w", err)
+		}
+		if cfg.MetricsAddr == cfg.Addr {
+			return fmt.Errorf("metrics_addr doit être différente de l'adresse de l'API (%s)", cfg.Addr)
+		}
+	}
+	switch cfg.Mode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		return fmt.Errorf("mode invalide: %q (debug, release ou test)", cfg.Mode)
+	}
+	d, ok := dialectFor(cfg.DB.Driver)
+	if !ok {
+		return fmt.Errorf("driver de base de données non supporté: %q (sqlite3 ou postgres)", cfg.DB.Driver)
+	}
+	if d != sqliteDialect && cfg.DB.DSN == "" {
+		return fmt.Errorf("DB_DSN est requis pour le driver %s", cfg.DB.Driver)
+	}
+	if _, err := newLogger(io.Discard, cfg.LogLevel, cfg.LogFormat); err != nil {
+		return err
+	}
+	for name, d := range map[string]time.Duration{
+		"read_timeout":  cfg.Timeouts.Read,
+		"write_timeout": cfg.Timeouts.Write,
+		"idle_timeout":  cfg.Timeouts.Idle,
+		"cors.max_age":  cfg.CORS.MaxAge,
+	} {
+		if d < 0 {
+			return fmt.Errorf("%s ne peut pas être négatif", name)
+		}
+	}
+	for name, d := range map[string]time.Duration{
+		"shutdown_timeout": cfg.Timeouts.Shutdown,
+		"auth.access_ttl":  cfg.Auth.AccessTTL,
+		"auth.refresh_ttl": cfg.Auth.RefreshTTL,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%s doit être positif", name)
+		}
+	}
+	if _, err := newOriginMatcher(cfg.CORS.AllowedOrigins); err != nil {
+		return err
+	}
+	switch cfg.Auth.Algorithm {
+	case "HS256", "EdDSA":
+	default:
+		return fmt.Errorf("AUTH_JWT_ALG invalide: %q (HS256 ou EdDSA)", cfg.Auth.Algorithm)
+	}
+	_, err := cfg.agePolicy()
+	return err
+}
+
+// checkListenAddr vérifie une adresse d'écoute (hôte facultatif et port)
+func checkListenAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("adresse d'écoute invalide: %q (ex: :8080 ou 127.0.0.1:8080)", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("port invalide: %q", port)
+	}
+	return nil
+}
+
+// agePolicy retourne la politique d'âge par niveau (AGE_CHECK, LEVEL_AGE_RANGES)
+func (cfg config) agePolicy() (agePolicy, error) {
+	return parseAgePolicy(cfg.AgeCheck, cfg.LevelAgeRanges)
+}
+
+// checkAuth vérifie, avant de démarrer le serveur, le secret ou la clé privée de signature des jetons
+func (cfg config) checkAuth() error {
+	if cfg.Auth.Disabled {
+		return nil
+	}
+	_, _, _, err := cfg.Auth.signingKeys()
+	return err
+}
+
+// dataDir retourne le répertoire du fichier SQLite, vide pour une autre base de données
+func (c dbConfig) dataDir() string {
+	if d, _ := dialectFor(c.Driver); d != sqliteDialect {
+		return ""
+	}
+	dsn := c.DSN
+	if dsn == "" {
+		dsn = defaultSQLitePath
+	}
+	return filepath.Dir(sqlitePath(dsn))
+}
+
+// checkFrontend vérifie que les fichiers du frontend existent, avant de démarrer le serveur
+func (cfg config) checkFrontend() error {
+	if info, err := os.Stat(cfg.StaticDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("répertoire des fichiers statiques introuvable: %s", cfg.StaticDir)
+	}
+	if info, err := os.Stat(cfg.IndexFile); err != nil || info.IsDir() {
+		return fmt.Errorf("page principale introuvable: %s", cfg.IndexFile)
+	}
+	return nil
+}
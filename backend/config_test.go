@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEnv retourne un getenv lisant les variables fournies
+func testEnv(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+// writeConfigFile écrit un fichier de configuration temporaire et retourne son chemin
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, args, err := loadConfig(nil, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, defaultConfig(), cfg)
+	assert.Empty(t, args)
+	assert.Equal(t, ":8080", cfg.Addr)
+	assert.Equal(t, "sqlite3", cfg.DB.Driver)
+
+	assert.Equal(t, serverTimeouts{Read: 15 * time.Second, Write: time.Minute, Idle: 2 * time.Minute, Shutdown: 10 * time.Second}, cfg.Timeouts)
+
+	// Un délai de 0 désactive la limite, sauf pour l'arrêt
+	cfg, _, err = loadConfig([]string{"-write-timeout", "0", "-shutdown-timeout", "30s"}, testEnv(map[string]string{"HTTP_IDLE_TIMEOUT": "5m"}))
+	require.NoError(t, err)
+	assert.Equal(t, serverTimeouts{Read: 15 * time.Second, Write: 0, Idle: 5 * time.Minute, Shutdown: 30 * time.Second}, cfg.Timeouts)
+
+	// Un 0 explicite l'emporte aussi sur le fichier
+	file := writeConfigFile(t, "delais.toml", "write_timeout = \"2m\"\nidle_timeout = \"3m\"\n")
+	cfg, _, err = loadConfig([]string{"-config", file}, testEnv(map[string]string{"HTTP_IDLE_TIMEOUT": "0"}))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, cfg.Timeouts.Write)
+	assert.Equal(t, time.Duration(0), cfg.Timeouts.Idle)
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	yamlFile := writeConfigFile(t, "usagers.yaml", `
+addr: ":9000"
+mode: release
+static_dir: /srv/frontend/static
+database:
+  driver: sqlite3
+  dsn: /srv/data/fichier.db
+metrics_addr: 127.0.0.1:9090
+age_check: warn
+level_age_ranges: PRESCOLAIRE=3-5
+`)
+
+	// Fichier seul
+	cfg, _, err := loadConfig([]string{"-config", yamlFile}, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Addr)
+	assert.Equal(t, "release", cfg.Mode)
+	assert.Equal(t, "/srv/frontend/static", cfg.StaticDir)
+	assert.Equal(t, "./frontend/index.html", cfg.IndexFile)
+	assert.Equal(t, "/srv/data/fichier.db", cfg.DB.DSN)
+	assert.Equal(t, "127.0.0.1:9090", cfg.MetricsAddr)
+	ages, err := cfg.agePolicy()
+	require.NoError(t, err)
+	assert.Equal(t, ageCheckWarn, ages.mode)
+	assert.Equal(t, "PRESCOLAIRE=3-5", cfg.LevelAgeRanges)
+
+	// L'environnement l'emporte sur le fichier (désigné par CONFIG_FILE), les drapeaux sur l'environnement
+	env := testEnv(map[string]string{"CONFIG_FILE": yamlFile, "HTTP_ADDR": ":9100", "DB_DSN": "/srv/data/env.db", "GIN_MODE": "test"})
+	cfg, args, err := loadConfig([]string{"-addr", "127.0.0.1:9200", "migrate", "status"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9200", cfg.Addr)
+	assert.Equal(t, "test", cfg.Mode)
+	assert.Equal(t, "/srv/data/env.db", cfg.DB.DSN)
+	assert.Equal(t, []string{"migrate", "status"}, args)
+	cfg, _, err = loadConfig([]string{"-age-check", "off"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, ageCheckOff, cfg.AgeCheck)
+	assert.False(t, cfg.RequireIfMatch)
+	cfg, _, err = loadConfig([]string{"-require-if-match"}, env)
+	require.NoError(t, err)
+	assert.True(t, cfg.RequireIfMatch)
+
+	// DATABASE_URL est acceptée à la place de DB_DSN
+	cfg, _, err = loadConfig(nil, testEnv(map[string]string{"DB_DRIVER": "postgres", "DATABASE_URL": "postgres://localhost/usagers"}))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://localhost/usagers", cfg.DB.DSN)
+
+	tomlFile := writeConfigFile(t, "usagers.toml", `
+addr = ":9300"
+index_file = "/srv/frontend/index.html"
+
+[database]
+driver = "postgres"
+dsn = "postgres://localhost/usagers"
+`)
+	cfg, _, err = loadConfig([]string{"-config", tomlFile}, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, ":9300", cfg.Addr)
+	assert.Equal(t, "/srv/frontend/index.html", cfg.IndexFile)
+	assert.Equal(t, "postgres", cfg.DB.Driver)
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args []string
+		env  map[string]string
+	}{
+		"adresse sans port":       {args: []string{"-addr", "localhost"}},
+		"port hors limites":       {env: map[string]string{"HTTP_ADDR": ":70000"}},
+		"métriques sans port":     {args: []string{"-metrics-addr", "localhost"}},
+		"métriques sur l'API":     {env: map[string]string{"METRICS_ADDR": ":8080"}},
+		"mode inconnu":            {args: []string{"-mode", "production"}},
+		"driver inconnu":          {env: map[string]string{"DB_DRIVER": "mysql"}},
+		"postgres sans DSN":       {env: map[string]string{"DB_DRIVER": "postgres"}},
+		"drapeau inconnu":         {args: []string{"-port", "8080"}},
+		"fichier absent":          {args: []string{"-config", filepath.Join(t.TempDir(), "absent.yaml")}},
+		"clé inconnue (YAML)":     {args: []string{"-config", writeConfigFile(t, "inconnue.yaml", "port: 8080\n")}},
+		"clé inconnue (TOML)":     {args: []string{"-config", writeConfigFile(t, "inconnue.toml", "port = 8080\n")}},
+		"extension non supportée": {args: []string{"-config", writeConfigFile(t, "usagers.json", "{}")}},
+		"fichier YAML mal formé":  {args: []string{"-config", writeConfigFile(t, "invalide.yml", "addr: [\n")}},
+		"délai mal formé":         {env: map[string]string{"HTTP_WRITE_TIMEOUT": "60"}},
+		"délai négatif":           {args: []string{"-read-timeout", "-1s"}},
+		"délai d'arrêt nul":       {args: []string{"-shutdown-timeout", "0s"}},
+		"niveau de log inconnu":   {env: map[string]string{"LOG_LEVEL": "verbeux"}},
+		"format de log inconnu":   {args: []string{"-log-format", "xml"}},
+		"vérification d'âge":      {env: map[string]string{"AGE_CHECK": "strict"}},
+		"tranche d'âge invalide":  {args: []string{"-level-age-ranges", "PRESCOLAIRE=5-3"}},
+		"valeur non supportée":    {args: []string{"-config", writeConfigFile(t, "date.toml", "addr = 2024-09-07\n")}},
+	} {
+		_, _, err := loadConfig(tc.args, testEnv(tc.env))
+		assert.Error(t, err, name)
+	}
+
+	// Un fichier vide garde les valeurs par défaut
+	cfg, _, err := loadConfig([]string{"-config", writeConfigFile(t, "vide.yaml", "")}, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, defaultConfig(), cfg)
+}
+
+func TestCheckFrontend(t *testing.T) {
+	dir := t.TempDir()
+	index := filepath.Join(dir, "index.html")
+	require.NoError(t, os.WriteFile(index, []byte("<html></html>"), 0o600))
+
+	cfg := defaultConfig()
+	cfg.StaticDir, cfg.IndexFile = dir, index
+	assert.NoError(t, cfg.checkFrontend())
+
+	cfg.IndexFile = dir
+	assert.Error(t, cfg.checkFrontend())
+	cfg.StaticDir, cfg.IndexFile = index, index
+	assert.Error(t, cfg.checkFrontend())
+}
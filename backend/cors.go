@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// corsConfig regroupe la politique CORS de l'API (CORS_*, section cors du fichier de configuration).
+// Sans origine autorisée, seul le frontend servi par le backend (même origine) peut appeler l'API.
+type corsConfig struct {
+	AllowedOrigins []string      // Origines exactes ("https://app.exemple.com"), sous-domaines ("https://*.exemple.com") ou "*"
+	ExposedHeaders []string      // En-têtes de réponse lisibles par le navigateur
+	MaxAge         time.Duration // Durée de mise en cache des réponses preflight
+}
+
+// splitList découpe une liste séparée par des virgules, sans les éléments vides
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// originPattern est une origine autorisée. Avec wildcard, host est le suffixe
+// (".exemple.com") que doit avoir le nom d'hôte d'un sous-domaine.
+type originPattern struct {
+	scheme   string
+	host     string
+	port     string
+	wildcard bool
+}
+
+// originMatcher indique si une origine fait partie de la liste autorisée
+type originMatcher struct {
+	any      bool // "*" : toutes les origines, sans identifiants (credentials)
+	patterns []originPattern
+}
+
+// newOriginMatcher valide et compile la liste des origines autorisées
+func newOriginMatcher(origins []string) (*originMatcher, error) {
+	m := &originMatcher{}
+	for _, origin := range origins {
+		if origin == "*" {
+			m.any = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return nil, fmt.Errorf("origine CORS invalide: %q (ex: https://app.exemple.com ou https://*.exemple.com)", origin)
+		}
+		p := originPattern{scheme: u.Scheme, host: strings.ToLower(u.Hostname()), port: u.Port()}
+		if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+			if rest == "" || strings.Contains(rest, "*") {
+				return nil, fmt.Errorf("origine CORS invalide: %q", origin)
+			}
+			p.host, p.wildcard = "."+rest, true
+		} else if strings.Contains(p.host, "*") {
+			return nil, fmt.Errorf("origine CORS invalide: %q (le joker n'est permis qu'en début de nom d'hôte)", origin)
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// match indique si l'origine (en-tête Origin) est autorisée par un motif de la liste
+func (m *originMatcher) match(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.Path != "" {
+		return false
+	}
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	for _, p := range m.patterns {
+		if p.scheme != u.Scheme || p.port != port {
+			continue
+		}
+		if p.wildcard && strings.HasSuffix(host, p.host) && len(host) > len(p.host) {
+			return true
+		}
+		if !p.wildcard && p.host == host {
+			return true
+		}
+	}
+	return false
+}
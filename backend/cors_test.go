@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginMatcher(t *testing.T) {
+	m, err := newOriginMatcher([]string{"https://app.exemple.com", "https://*.piscine.ca", "http://localhost:3000"})
+	require.NoError(t, err)
+
+	for origin, want := range map[string]bool{
+		"https://app.exemple.com":        true,
+		"https://APP.exemple.com":        true,
+		"http://app.exemple.com":         false,
+		"https://app.exemple.com:8443":   false,
+		"https://autre.exemple.com":      false,
+		"https://inscription.piscine.ca": true,
+		"https://a.b.piscine.ca":         true,
+		"https://piscine.ca":             false,
+		"https://fauxpiscine.ca":         false,
+		"https://piscine.ca.pirate.com":  false,
+		"http://localhost:3000":          true,
+		"http://localhost:8080":          false,
+		"null":                           false,
+	} {
+		assert.Equal(t, want, m.match(origin), origin)
+	}
+
+	for _, invalid := range []string{"app.exemple.com", "https://", "ftp://exemple.com", "https://exemple.com/chemin", "https://app.*.exemple.com", "https://*."} {
+		_, err := newOriginMatcher([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLoadConfigCORS(t *testing.T) {
+	cfg, _, err := loadConfig(nil, testEnv(nil))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, 10*time.Minute, cfg.CORS.MaxAge)
+
+	env := testEnv(map[string]string{
+		"CORS_ALLOWED_ORIGINS": "https://app.exemple.com, https://*.exemple.com,",
+		"CORS_EXPOSED_HEADERS": "Content-Disposition",
+		"CORS_MAX_AGE":         "1h",
+	})
+	cfg, _, err = loadConfig(nil, env)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.exemple.com", "https://*.exemple.com"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, []string{"Content-Disposition"}, cfg.CORS.ExposedHeaders)
+	assert.Equal(t, time.Hour, cfg.CORS.MaxAge)
+
+	// Liste du fichier de configuration, remplacée par le drapeau
+	file := writeConfigFile(t, "cors.toml", "[cors]\nallowed_origins = [\"https://app.exemple.com\", \"https://admin.exemple.com\"]\nmax_age = \"5m\"\n")
+	cfg, _, err = loadConfig([]string{"-config", file}, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://app.exemple.com", "https://admin.exemple.com"}, cfg.CORS.AllowedOrigins)
+	assert.Equal(t, 5*time.Minute, cfg.CORS.MaxAge)
+	cfg, _, err = loadConfig([]string{"-config", file, "-cors-allowed-origins", "*"}, testEnv(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, cfg.CORS.AllowedOrigins)
+
+	_, _, err = loadConfig(nil, testEnv(map[string]string{"CORS_MAX_AGE": "longtemps"}))
+	assert.Error(t, err)
+	_, _, err = loadConfig([]string{"-cors-allowed-origins", "exemple.com"}, testEnv(nil))
+	assert.Error(t, err)
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(setupCORS(corsConfig{
+		AllowedOrigins: []string{"https://*.exemple.com"},
+		ExposedHeaders: []string{"Content-Disposition", "X-Total"},
+		MaxAge:         time.Hour,
+	}))
+	r.GET("/api/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method, origin string, preflight bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/users", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if preflight {
+			req.Header.Set("Access-Control-Request-Method", "PATCH")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Preflight d'une origine autorisée : l'origine est renvoyée, jamais "*"
+	w := request("OPTIONS", "https://app.exemple.com", true)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.exemple.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "PATCH")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, w.Header().Values("Vary"), "Origin")
+
+	w = request("GET", "https://app.exemple.com", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.exemple.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "ETag, X-Request-ID, Content-Disposition, X-Total", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+
+	// Origine non autorisée : preflight refusé, requête simple sans en-têtes CORS
+	w = request("OPTIONS", "https://pirate.com", true)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request("GET", "https://pirate.com", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Values("Vary"), "Origin")
+
+	// Même origine (pas d'en-tête Origin) : aucun en-tête CORS
+	w = request("GET", "", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(setupCORS(corsConfig{AllowedOrigins: []string{"*"}}))
+	r.GET("/api/levels", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/api/levels", nil)
+	req.Header.Set("Origin", "https://nimporte.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
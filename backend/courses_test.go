@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCourseRequest(level string, capacity int) CourseRequest {
+	return CourseRequest{
+		Level:      level,
+		Session:    "Automne 2024",
+		Weekday:    6,
+		StartTime:  "09:00",
+		EndTime:    "09:45",
+		StartDate:  "2024-09-07",
+		EndDate:    "2024-11-30",
+		Pool:       "Piscine du Parc",
+		Capacity:   capacity,
+		Instructor: "Sophie Tremblay",
+	}
+}
+
+// insertTestUser insère un usager directement en base et retourne son ID
+func insertTestUser(t *testing.T, db *sql.DB, email, niveau string) int {
+	var id int
+	err := db.QueryRow(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation)
+		VALUES ('Test', 'Usager', ?, '2015-05-15', ?) RETURNING id`, email, niveau).Scan(&id)
+	require.NoError(t, err)
+	return id
+}
+
+func createTestCourse(t *testing.T, r http.Handler, req CourseRequest) Course {
+	w := performRequest(r, "POST", "/api/courses", req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var course Course
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &course))
+	return course
+}
+
+func TestCourseCRUD(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	course := createTestCourse(t, r, testCourseRequest("NAGEUR 3", 8))
+	assert.Greater(t, course.ID, 0)
+	assert.Equal(t, "NAGEUR 3", course.Level)
+	assert.Equal(t, 0, course.Enrolled)
+
+	path := "/api/courses/" + strconv.Itoa(course.ID)
+
+	update := testCourseRequest("NAGEUR 3", 10)
+	update.Pool = "Centre aquatique"
+	w := performRequest(r, "PUT", path, update)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &course))
+	assert.Equal(t, "Centre aquatique", course.Pool)
+	assert.Equal(t, 10, course.Capacity)
+
+	w = performRequest(r, "GET", "/api/courses?session=Automne 2024", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Courses []Course `json:"courses"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Courses, 1)
+
+	w = performRequest(r, "DELETE", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateCourseInvalidSchedule(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	req := testCourseRequest("NAGEUR 3", 8)
+	req.EndTime = "08:30"
+	w := performRequest(r, "POST", "/api/courses", req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	req = testCourseRequest("NAGEUR 3", 8)
+	req.StartTime = "9h00"
+	w = performRequest(r, "POST", "/api/courses", req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	req = testCourseRequest("NAGEUR 3", 8)
+	req.Weekday = 8
+	w = performRequest(r, "POST", "/api/courses", req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestEnrollment(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	course := createTestCourse(t, r, testCourseRequest("NAGEUR 3", 2))
+	path := "/api/courses/" + strconv.Itoa(course.ID) + "/enrollments"
+
+	jean := insertTestUser(t, testDB, "jean@test.com", "NAGEUR 3")
+	marie := insertTestUser(t, testDB, "marie@test.com", "NAGEUR 3")
+	luc := insertTestUser(t, testDB, "luc@test.com", "NAGEUR 3")
+	debutant := insertTestUser(t, testDB, "debutant@test.com", "NAGEUR 1")
+
+	w := performRequest(r, "POST", path, EnrollmentRequest{UserID: jean})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Déjà inscrit
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: jean})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Niveau différent du cours
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: debutant})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Usager inexistant
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: 999})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: marie})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Cours complet : liste d'attente
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: luc})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var entry WaitlistEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, 1, entry.Position)
+
+	// La capacité ne peut pas descendre sous le nombre d'inscrits
+	w = performRequest(r, "PUT", "/api/courses/"+strconv.Itoa(course.ID), testCourseRequest("NAGEUR 3", 1))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = performRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Enrollments []Enrollment `json:"enrollments"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Enrollments, 2)
+	assert.Equal(t, jean, list.Enrollments[0].UserID)
+	assert.Equal(t, "jean@test.com", list.Enrollments[0].User.Email)
+
+	// Désinscription : la place libérée revient au premier de la liste d'attente
+	w = performRequest(r, "DELETE", path+"/"+strconv.Itoa(marie), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var unenrolled struct {
+		Promoted *Enrollment `json:"promoted"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &unenrolled))
+	require.NotNil(t, unenrolled.Promoted)
+	assert.Equal(t, luc, unenrolled.Promoted.UserID)
+	w = performRequest(r, "DELETE", path+"/"+strconv.Itoa(marie), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: luc})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// La suppression d'un usager supprime ses inscriptions
+	w = performRequest(r, "DELETE", "/api/users/"+strconv.Itoa(luc), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(r, "GET", "/api/courses/"+strconv.Itoa(course.ID), nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &course))
+	assert.Equal(t, 1, course.Enrolled)
+}
+
+// testConcurrentEnrollments inscrit 10 usagers en parallèle à un cours de 3 places
+func testConcurrentEnrollments(t *testing.T, store *SQLStore) {
+	r := setupRouterWithStore(store)
+	ctx := context.Background()
+
+	course := createTestCourse(t, r, testCourseRequest("NAGEUR 3", 3))
+	path := "/api/courses/" + strconv.Itoa(course.ID) + "/enrollments"
+
+	var userIDs []int
+	for i := 0; i < 10; i++ {
+		u, err := store.Create(ctx, UserRequest{FirstName: "Test", LastName: "Usager", Email: "user" + strconv.Itoa(i) + "@test.com",
+			DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"})
+		require.NoError(t, err)
+		userIDs = append(userIDs, u.ID)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	statuses := map[int]int{}
+	for _, id := range userIDs {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			w := performRequest(r, "POST", path, EnrollmentRequest{UserID: id})
+			mu.Lock()
+			statuses[w.Code]++
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, statuses[http.StatusCreated])
+	assert.Equal(t, 7, statuses[http.StatusAccepted])
+
+	enrollments, err := store.ListEnrollments(ctx, course.ID)
+	require.NoError(t, err)
+	assert.Len(t, enrollments, 3)
+	waitlist, err := store.ListWaitlist(ctx, course.ID)
+	require.NoError(t, err)
+	assert.Len(t, waitlist, 7)
+
+	// Annulations concurrentes : chaque place libérée est attribuée une seule fois
+	for _, e := range enrollments {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			w := performRequest(r, "DELETE", path+"/"+strconv.Itoa(userID), nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}(e.UserID)
+	}
+	wg.Wait()
+
+	enrollments, err = store.ListEnrollments(ctx, course.ID)
+	require.NoError(t, err)
+	assert.Len(t, enrollments, 3)
+	waitlist, err = store.ListWaitlist(ctx, course.ID)
+	require.NoError(t, err)
+	assert.Len(t, waitlist, 4)
+	for i, entry := range waitlist {
+		assert.Equal(t, i+1, entry.Position)
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	assert.Equal(t, "./data/users.db?_busy_timeout=5000&_foreign_keys=on&_txlock=immediate", sqliteDSN("./data/users.db"))
+
+	// Les options requises sont ajoutées aux paramètres du DSN et l'emportent sur une valeur contraire
+	assert.Equal(t, "./data/users.db?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL&_txlock=immediate",
+		sqliteDSN("./data/users.db?_journal_mode=WAL"))
+	assert.Equal(t, "users.db?_busy_timeout=100&_foreign_keys=on&_txlock=immediate",
+		sqliteDSN("users.db?_fk=off&_txlock=deferred&_busy_timeout=100"))
+
+	// Avec des paramètres, les clés étrangères restent actives
+	testDB, err := sql.Open("sqlite3", sqliteDSN(filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL"))
+	require.NoError(t, err)
+	defer testDB.Close()
+	var foreignKeys int
+	require.NoError(t, testDB.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys))
+	assert.Equal(t, 1, foreignKeys)
+}
+
+func TestConcurrentEnrollmentsRespectCapacity(t *testing.T) {
+	// Base sur disque pour avoir plusieurs connexions concurrentes; les paramètres du DSN
+	// ne retirent pas le verrou en écriture (BEGIN IMMEDIATE) qui sérialise les inscriptions
+	testDB, err := sql.Open("sqlite3", sqliteDSN(filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL"))
+	require.NoError(t, err)
+	defer testDB.Close()
+	migrateTestDB(t, testDB, sqliteDialect)
+
+	testConcurrentEnrollments(t, newSQLiteStore(testDB))
+}
+
+func TestPostgresConcurrentEnrollmentsRespectCapacity(t *testing.T) {
+	testDB := setupPostgresTestDB(t)
+	defer testDB.Close()
+
+	testConcurrentEnrollments(t, newSQLStore(testDB, postgresDialect))
+}
+
+func TestCourseRoutesRequireCourseStore(t *testing.T) {
+	r := setupRouterWithStore(newMemoryStore())
+
+	w := performRequest(r, "GET", "/api/courses", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWaitlist(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	course := createTestCourse(t, r, testCourseRequest("NAGEUR 3", 1))
+	coursePath := "/api/courses/" + strconv.Itoa(course.ID)
+
+	inscrit := insertTestUser(t, testDB, "inscrit@test.com", "NAGEUR 3")
+	premier := insertTestUser(t, testDB, "premier@test.com", "NAGEUR 3")
+	deuxieme := insertTestUser(t, testDB, "deuxieme@test.com", "NAGEUR 3")
+	troisieme := insertTestUser(t, testDB, "troisieme@test.com", "NAGEUR 3")
+
+	for _, id := range []int{inscrit, premier, deuxieme, troisieme} {
+		w := performRequest(r, "POST", coursePath+"/enrollments", EnrollmentRequest{UserID: id})
+		require.Contains(t, []int{http.StatusCreated, http.StatusAccepted}, w.Code)
+	}
+	w := performRequest(r, "POST", coursePath+"/enrollments", EnrollmentRequest{UserID: premier})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var list struct {
+		Waitlist []WaitlistEntry `json:"waitlist"`
+	}
+	waitlistUserIDs := func() []int {
+		w := performRequest(r, "GET", coursePath+"/waitlist", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		ids := []int{}
+		for _, entry := range list.Waitlist {
+			ids = append(ids, entry.UserID)
+		}
+		return ids
+	}
+	assert.Equal(t, []int{premier, deuxieme, troisieme}, waitlistUserIDs())
+
+	// Réordonner : l'ordre doit contenir exactement les usagers en attente
+	w = performRequest(r, "PUT", coursePath+"/waitlist", WaitlistOrderRequest{UserIDs: []int{troisieme, premier}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(r, "PUT", coursePath+"/waitlist", WaitlistOrderRequest{UserIDs: []int{troisieme, premier, premier}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = performRequest(r, "PUT", coursePath+"/waitlist", WaitlistOrderRequest{UserIDs: []int{troisieme, premier, deuxieme}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{troisieme, premier, deuxieme}, waitlistUserIDs())
+
+	// Retirer un usager de la liste d'attente
+	w = performRequest(r, "DELETE", coursePath+"/waitlist/"+strconv.Itoa(premier), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(r, "DELETE", coursePath+"/waitlist/"+strconv.Itoa(premier), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, []int{troisieme, deuxieme}, waitlistUserIDs())
+
+	// Un usager qui n'a plus le niveau du cours est ignoré lors de la promotion
+	_, err := testDB.Exec("UPDATE users SET niveau_natation = 'NAGEUR 4' WHERE id = ?", troisieme)
+	require.NoError(t, err)
+	w = performRequest(r, "DELETE", coursePath+"/enrollments/"+strconv.Itoa(inscrit), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []int{troisieme}, waitlistUserIDs())
+
+	// Une hausse de capacité promeut les usagers admissibles suivants
+	_, err = testDB.Exec("UPDATE users SET niveau_natation = 'NAGEUR 3' WHERE id = ?", troisieme)
+	require.NoError(t, err)
+	w = performRequest(r, "PUT", coursePath, testCourseRequest("NAGEUR 3", 3))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &course))
+	assert.Equal(t, 2, course.Enrolled)
+	assert.Equal(t, 0, course.Waitlisted)
+
+	// La suppression d'un usager inscrit libère sa place pour la liste d'attente
+	w = performRequest(r, "PUT", coursePath, testCourseRequest("NAGEUR 3", 2))
+	assert.Equal(t, http.StatusOK, w.Code)
+	autre := insertTestUser(t, testDB, "autre@test.com", "NAGEUR 3")
+	w = performRequest(r, "POST", coursePath+"/enrollments", EnrollmentRequest{UserID: autre})
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	w = performRequest(r, "DELETE", "/api/users/"+strconv.Itoa(deuxieme), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, waitlistUserIDs())
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var db *sql.DB
+// Chemin par défaut de la base SQLite (dans /app/data pour la persistance dans Docker)
+const defaultSQLitePath = "./data/users.db"
 
 // calculateAge calcule l'âge à partir d'une date de naissance (format YYYY-MM-DD)
 func calculateAge(dateNaissance string) int {
+	return calculateAgeAt(dateNaissance, time.Now())
+}
+
+// calculateAgeAt calcule l'âge à une date de référence (ex: le début d'une session)
+func calculateAgeAt(dateNaissance string, ref time.Time) int {
 	if dateNaissance == "" {
 		return 0
 	}
@@ -20,55 +32,96 @@ func calculateAge(dateNaissance string) int {
 	if err != nil {
 		return 0
 	}
-	now := time.Now()
-	age := now.Year() - birthDate.Year()
-	if now.YearDay() < birthDate.YearDay() {
+	age := ref.Year() - birthDate.Year()
+	if ref.Month() < birthDate.Month() || (ref.Month() == birthDate.Month() && ref.Day() < birthDate.Day()) {
 		age--
 	}
 	return age
 }
 
-// initDB initialise la connexion à la base de données et crée les tables si nécessaire
-func initDB() {
-	var err error
-	// Utiliser un chemin dans /app/data pour la persistance dans Docker
-	dbPath := "./data/users.db"
-	if _, err := os.Stat("./data"); os.IsNotExist(err) {
-		os.MkdirAll("./data", 0755)
-	}
-	db, err = sql.Open("sqlite3", dbPath)
+// sqliteDSN ajoute au chemin SQLite les options de connexion requises, y compris lorsque le DSN
+// a déjà des paramètres (ex: ./data/users.db?_journal_mode=WAL) : clés étrangères actives
+// (ON DELETE CASCADE) et transactions en écriture (BEGIN IMMEDIATE), qui sérialisent les
+// transactions concurrentes puisque SQLite n'a pas de SELECT ... FOR UPDATE. Ces deux options
+// remplacent une valeur contraire du DSN. L'attente en cas de verrou n'est ajoutée que si elle manque.
+func sqliteDSN(dsn string) string {
+	path, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
 	if err != nil {
-		log.Fatal("Erreur lors de l'ouverture de la base de données:", err)
+		// Paramètres illisibles : le driver refusera le DSN et signalera l'erreur à l'ouverture
+		return dsn + "&_foreign_keys=on&_txlock=immediate&_busy_timeout=5000"
 	}
+	query.Del("_fk") // Alias de _foreign_keys
+	query.Set("_foreign_keys", "on")
+	query.Set("_txlock", "immediate")
+	if query.Get("_busy_timeout") == "" && query.Get("_timeout") == "" {
+		query.Set("_busy_timeout", "5000")
+	}
+	return path + "?" + query.Encode()
+}
 
-	// Créer la table si elle n'existe pas
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		first_name TEXT NOT NULL,
-		last_name TEXT NOT NULL,
-		email TEXT NOT NULL UNIQUE,
-		date_naissance TEXT NOT NULL,
-		niveau_natation TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
+// sqlitePath retourne le chemin du fichier SQLite, sans les paramètres du DSN
+func sqlitePath(dsn string) string {
+	path, _, _ := strings.Cut(dsn, "?")
+	return path
+}
 
-	_, err = db.Exec(createTableSQL)
-	if err != nil {
-		log.Fatal("Erreur lors de la création de la table:", err)
+// openDB ouvre la connexion à la base de données correspondant au driver.
+// Si observe n'est pas nil, il reçoit la durée de chaque requête SQL.
+func openDB(driver, dsn string, observe queryObserver) (*sql.DB, dialect, error) {
+	d, ok := dialectFor(driver)
+	if !ok {
+		return nil, dialect{}, fmt.Errorf("driver de base de données non supporté: %q", driver)
+	}
+
+	if d == sqliteDialect {
+		if dsn == "" {
+			dsn = defaultSQLitePath
+		}
+		if dir := filepath.Dir(sqlitePath(dsn)); dir != "" {
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				os.MkdirAll(dir, 0755)
+			}
+		}
+		dsn = sqliteDSN(dsn)
+	} else if dsn == "" {
+		return nil, dialect{}, fmt.Errorf("DB_DSN est requis pour le driver %s", driver)
 	}
 
-	// Ajouter les nouvelles colonnes si elles n'existent pas (migration)
-	db.Exec("ALTER TABLE users ADD COLUMN date_naissance TEXT")
-	db.Exec("ALTER TABLE users ADD COLUMN niveau_natation TEXT")
-	
-	// Fonction pour calculer l'âge
-	db.Exec(`
-		CREATE TRIGGER IF NOT EXISTS calculate_age 
-		AFTER INSERT ON users
-		BEGIN
-			-- L'âge sera calculé côté application
-		END;
-	`)
+	db, err := sql.Open(d.name, dsn)
+	if err != nil {
+		return nil, dialect{}, err
+	}
+	if observe != nil {
+		// sql.Open ne se connecte pas : la connexion est remplacée par une connexion instrumentée
+		drv := db.Driver()
+		db.Close()
+		db = sql.OpenDB(instrumentedConnector{driver: drv, dsn: dsn, observe: observe})
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, dialect{}, err
+	}
+	return db, d, nil
 }
 
+// initDB initialise la connexion à la base de données et applique les migrations en attente
+func initDB(driver, dsn string, observe queryObserver) (*sql.DB, dialect) {
+	db, d, err := openDB(driver, dsn, observe)
+	if err != nil {
+		fatal("Erreur lors de l'ouverture de la base de données", err)
+	}
+
+	m, err := newMigrator(db, d)
+	if err != nil {
+		fatal("Erreur lors du chargement des migrations", err)
+	}
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		fatal("Erreur lors de l'application des migrations", err)
+	}
+	for _, mig := range applied {
+		slog.Info("Migration appliquée", "version", mig.Version, "name", mig.Name)
+	}
+	return db, d
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect regroupe les différences de syntaxe SQL entre les bases de données supportées
+type dialect struct {
+	name          string // Nom du driver database/sql
+	numberedParam bool   // Paramètres $1, $2... au lieu de ?
+	likeOp        string // Opérateur de recherche insensible à la casse
+	ageExpr       string // Expression SQL de l'âge en années révolues (comme calculateAge) à partir de date_naissance
+	migrationsDir string // Dossier des migrations dans migrations/
+	forUpdate     string // Clause de verrouillage des lignes lues dans une transaction
+	lockKey       string // Requête de verrou transactionnel sur une clé (vide si la transaction verrouille déjà la base)
+
+	// Désactivation des clés étrangères autour d'une migration (ex: reconstruction d'une table référencée)
+	foreignKeysOff  string
+	foreignKeysOn   string
+	foreignKeyCheck string // Requête retournant une ligne par violation de clé étrangère
+}
+
+var sqliteDialect = dialect{
+	name:   "sqlite3",
+	likeOp: "LIKE",
+	ageExpr: `(CAST(strftime('%Y', 'now', 'localtime') AS INTEGER) - CAST(strftime('%Y', date_naissance) AS INTEGER)
+		- (strftime('%m-%d', 'now', 'localtime') < strftime('%m-%d', date_naissance)))`,
+	migrationsDir: "sqlite",
+	// SQLite verrouille toute la base à l'ouverture de la transaction (_txlock=immediate)
+	forUpdate: "",
+	lockKey:   "",
+	// PRAGMA foreign_keys est sans effet dans une transaction : il est appliqué sur la connexion avant BEGIN
+	foreignKeysOff:  "PRAGMA foreign_keys = OFF",
+	foreignKeysOn:   "PRAGMA foreign_keys = ON",
+	foreignKeyCheck: "PRAGMA foreign_key_check",
+}
+
+var postgresDialect = dialect{
+	name:          "postgres",
+	numberedParam: true,
+	likeOp:        "ILIKE",
+	// Les dates mal formées donnent NULL au lieu de faire échouer la requête
+	ageExpr: `(CASE WHEN date_naissance ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}$'
+		THEN date_part('year', age(CURRENT_DATE, CAST(date_naissance AS DATE))) END)`,
+	migrationsDir: "postgres",
+	forUpdate:     " FOR UPDATE",
+	lockKey:       "SELECT pg_advisory_xact_lock(hashtext(?))",
+}
+
+// dialectFor retourne le dialecte correspondant au nom du driver
+func dialectFor(driver string) (dialect, bool) {
+	switch driver {
+	case "sqlite3", "sqlite":
+		return sqliteDialect, true
+	case "postgres", "postgresql":
+		return postgresDialect, true
+	}
+	return dialect{}, false
+}
+
+// rebind convertit les paramètres ? d'une requête dans la syntaxe du dialecte
+func (d dialect) rebind(query string) string {
+	if !d.numberedParam {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes de vérification de l'âge par rapport au niveau
+const (
+	ageCheckReject = "reject" // L'écriture est refusée (422)
+	ageCheckWarn   = "warn"   // L'écriture est acceptée avec un avertissement
+	ageCheckOff    = "off"    // Aucune vérification
+)
+
+// ageRange est la tranche d'âge admissible d'un niveau, bornes incluses
+type ageRange struct {
+	Min int
+	Max *int // nil: pas de limite
+}
+
+// contains indique si l'âge est dans la tranche
+func (r ageRange) contains(age int) bool {
+	return age >= r.Min && (r.Max == nil || age <= *r.Max)
+}
+
+// AgeMismatch décrit un âge incompatible avec un niveau de natation
+type AgeMismatch struct {
+	Level         string `json:"level"`
+	Age           int    `json:"age"`
+	MinAge        int    `json:"min_age"`
+	MaxAge        *int   `json:"max_age"`
+	ReferenceDate string `json:"reference_date"` // Date à laquelle l'âge est calculé
+}
+
+// agePolicy associe à chaque niveau (par libellé) sa tranche d'âge et définit le mode de vérification
+type agePolicy struct {
+	mode   string
+	ranges map[string]ageRange
+}
+
+// defaultAgePolicy reprend les tranches d'âge du catalogue et refuse les incompatibilités
+func defaultAgePolicy() agePolicy {
+	p := agePolicy{mode: ageCheckReject, ranges: map[string]ageRange{}}
+	for _, l := range levels {
+		p.ranges[l.Label] = ageRange{Min: l.MinAge, Max: l.MaxAge}
+	}
+	return p
+}
+
+// parseAgePolicy construit une politique à partir du mode et des tranches fournis.
+// Les tranches par programme sont appliquées avant celles par niveau.
+func parseAgePolicy(mode, ranges string) (agePolicy, error) {
+	p := defaultAgePolicy()
+	switch mode {
+	case "":
+	case ageCheckReject, ageCheckWarn, ageCheckOff:
+		p.mode = mode
+	default:
+		return agePolicy{}, fmt.Errorf("AGE_CHECK invalide: %q (reject, warn ou off)", mode)
+	}
+
+	var levelRanges [][2]string
+	for _, entry := range strings.Split(ranges, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return agePolicy{}, fmt.Errorf("tranche d'âge invalide: %q (format CODE=MIN-MAX)", entry)
+		}
+		key = strings.TrimSpace(key)
+		if _, ok := findProgram(key); ok {
+			r, err := parseAgeRange(value)
+			if err != nil {
+				return agePolicy{}, fmt.Errorf("tranche d'âge de %s: %w", key, err)
+			}
+			for _, l := range levels {
+				if l.Program == key {
+					p.ranges[l.Label] = r
+				}
+			}
+			continue
+		}
+		levelRanges = append(levelRanges, [2]string{key, value})
+	}
+
+	for _, lr := range levelRanges {
+		l, ok := findLevel(lr[0])
+		if !ok {
+			return agePolicy{}, fmt.Errorf("niveau ou programme inconnu dans LEVEL_AGE_RANGES: %q", lr[0])
+		}
+		r, err := parseAgeRange(lr[1])
+		if err != nil {
+			return agePolicy{}, fmt.Errorf("tranche d'âge de %s: %w", lr[0], err)
+		}
+		p.ranges[l.Label] = r
+	}
+	return p, nil
+}
+
+// parseAgeRange lit une tranche du type "3-5" ou "8-"
+func parseAgeRange(value string) (ageRange, error) {
+	minStr, maxStr, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return ageRange{}, fmt.Errorf("format MIN-MAX attendu: %q", value)
+	}
+	minAge, err := strconv.Atoi(minStr)
+	if err != nil || minAge < 0 {
+		return ageRange{}, fmt.Errorf("âge minimum invalide: %q", minStr)
+	}
+	r := ageRange{Min: minAge}
+	if maxStr != "" {
+		maxAge, err := strconv.Atoi(maxStr)
+		if err != nil || maxAge < minAge {
+			return ageRange{}, fmt.Errorf("âge maximum invalide: %q", maxStr)
+		}
+		r.Max = &maxAge
+	}
+	return r, nil
+}
+
+// check vérifie l'âge à la date de référence pour le niveau donné.
+// Retourne nil si l'âge convient, si la date de naissance est absente ou si le niveau n'a pas de tranche.
+func (p agePolicy) check(level, dateNaissance string, ref time.Time) *AgeMismatch {
+	r, ok := p.ranges[level]
+	if !ok || dateNaissance == "" {
+		return nil
+	}
+	if _, err := time.Parse("2006-01-02", dateNaissance); err != nil {
+		return nil
+	}
+	age := calculateAgeAt(dateNaissance, ref)
+	if r.contains(age) {
+		return nil
+	}
+	return &AgeMismatch{Level: level, Age: age, MinAge: r.Min, MaxAge: r.Max, ReferenceDate: ref.Format("2006-01-02")}
+}
+
+// levels retourne le catalogue des niveaux avec les tranches d'âge de la politique
+func (p agePolicy) levels() []Level {
+	result := make([]Level, len(levels))
+	for i, l := range levels {
+		if r, ok := p.ranges[l.Label]; ok {
+			l.MinAge, l.MaxAge = r.Min, r.Max
+		}
+		result[i] = l
+	}
+	return result
+}
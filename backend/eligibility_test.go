@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateAgeAt(t *testing.T) {
+	ref := time.Date(2024, 9, 7, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5, calculateAgeAt("2019-09-07", ref))
+	assert.Equal(t, 4, calculateAgeAt("2019-09-08", ref))
+	assert.Equal(t, 4, calculateAgeAt("2020-02-29", ref))
+	assert.Equal(t, 0, calculateAgeAt("invalide", ref))
+}
+
+func TestParseAgePolicy(t *testing.T) {
+	p, err := parseAgePolicy("", "")
+	require.NoError(t, err)
+	assert.Equal(t, ageCheckReject, p.mode)
+	assert.Equal(t, 5, *p.ranges["PRÉSCOLAIRE 1"].Max)
+
+	// Les tranches par niveau l'emportent sur celles du programme, quel que soit l'ordre
+	p, err = parseAgePolicy("warn", "NAGEUR_7=9-, NAGEUR=6-17")
+	require.NoError(t, err)
+	assert.Equal(t, ageCheckWarn, p.mode)
+	assert.Equal(t, 6, p.ranges["NAGEUR 1"].Min)
+	assert.Equal(t, 17, *p.ranges["NAGEUR 6"].Max)
+	assert.Equal(t, 9, p.ranges["NAGEUR 7"].Min)
+	assert.Nil(t, p.ranges["NAGEUR 7"].Max)
+
+	for _, tc := range []struct{ mode, ranges string }{
+		{"strict", ""},
+		{"", "NAGEUR_12=5-"},
+		{"", "NAGEUR=5"},
+		{"", "NAGEUR=8-5"},
+		{"", "NAGEUR=a-"},
+	} {
+		_, err := parseAgePolicy(tc.mode, tc.ranges)
+		assert.Error(t, err, "%q %q", tc.mode, tc.ranges)
+	}
+}
+
+func TestCreateUserAgeEligibility(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	s := newServer(newSQLiteStore(testDB))
+	r := setupRouterWithServer(s)
+
+	adult := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com",
+		DateNaissance: time.Now().AddDate(-40, 0, 0).Format("2006-01-02"), NiveauNatation: "PARENT ET ENFANT 1"}
+	w := performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var response Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, CodeAgeMismatch, response.Code)
+	require.NotNil(t, response.AgeMismatch)
+	assert.Equal(t, 40, response.AgeMismatch.Age)
+	assert.Equal(t, 0, response.AgeMismatch.MinAge)
+	assert.Equal(t, 3, *response.AgeMismatch.MaxAge)
+
+	// En mode warn, l'usager est créé avec un avertissement
+	s.ages.mode = ageCheckWarn
+	w = performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	require.Len(t, u.Warnings, 1)
+	assert.Equal(t, "PARENT ET ENFANT 1", u.Warnings[0].Level)
+
+	// Une modification qui ne touche ni au niveau ni à la date de naissance n'est pas vérifiée
+	s.ages.mode = ageCheckReject
+	adult.FirstName = "Jean-Pierre"
+	w = performRequest(r, "PUT", "/api/users/"+strconv.Itoa(u.ID), adult)
+	assert.Equal(t, http.StatusOK, w.Code)
+	adult.NiveauNatation = "PARENT ET ENFANT 2"
+	w = performRequest(r, "PUT", "/api/users/"+strconv.Itoa(u.ID), adult)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	adult.NiveauNatation = "NAGEUR 9"
+	w = performRequest(r, "PUT", "/api/users/"+strconv.Itoa(u.ID), adult)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	s.ages.mode = ageCheckOff
+	adult.Email = "autre@test.com"
+	adult.NiveauNatation = "PRÉSCOLAIRE 1"
+	w = performRequest(r, "POST", "/api/users", adult)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestEnrollmentAgeEligibility(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	s := newServer(newSQLiteStore(testDB))
+	r := setupRouterWithServer(s)
+
+	// Le cours commence le 2024-09-07 : l'âge est calculé à cette date
+	course := createTestCourse(t, r, testCourseRequest("PRÉSCOLAIRE 1", 8))
+	path := "/api/courses/" + strconv.Itoa(course.ID) + "/enrollments"
+
+	var tooYoung, eligible int
+	require.NoError(t, testDB.QueryRow(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation)
+		VALUES ('Léa', 'Roy', 'lea@test.com', '2021-09-08', 'PRÉSCOLAIRE 1') RETURNING id`).Scan(&tooYoung))
+	require.NoError(t, testDB.QueryRow(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation)
+		VALUES ('Tom', 'Roy', 'tom@test.com', '2021-09-07', 'PRÉSCOLAIRE 1') RETURNING id`).Scan(&eligible))
+
+	w := performRequest(r, "POST", path, EnrollmentRequest{UserID: tooYoung})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"reference_date":"2024-09-07"`)
+
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: eligible})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// La promotion depuis la liste d'attente vérifie aussi l'âge : un usager devenu non admissible
+	// (date de naissance corrigée) garde son rang, le suivant est inscrit
+	full := createTestCourse(t, r, testCourseRequest("PRÉSCOLAIRE 1", 1))
+	fullPath := "/api/courses/" + strconv.Itoa(full.ID) + "/enrollments"
+	var first, second int
+	require.NoError(t, testDB.QueryRow(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation)
+		VALUES ('Ana', 'Roy', 'ana@test.com', '2021-03-01', 'PRÉSCOLAIRE 1') RETURNING id`).Scan(&first))
+	require.NoError(t, testDB.QueryRow(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation)
+		VALUES ('Zoé', 'Roy', 'zoe@test.com', '2021-04-01', 'PRÉSCOLAIRE 1') RETURNING id`).Scan(&second))
+	for _, id := range []int{eligible, first, second} {
+		w = performRequest(r, "POST", fullPath, EnrollmentRequest{UserID: id})
+		require.Contains(t, []int{http.StatusCreated, http.StatusAccepted}, w.Code, w.Body.String())
+	}
+	_, err := testDB.Exec("UPDATE users SET date_naissance = '2022-03-01' WHERE id = ?", first)
+	require.NoError(t, err)
+	w = performRequest(r, "DELETE", fullPath+"/"+strconv.Itoa(eligible), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var unenrolled struct {
+		Promoted *Enrollment `json:"promoted"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &unenrolled))
+	require.NotNil(t, unenrolled.Promoted)
+	assert.Equal(t, second, unenrolled.Promoted.UserID)
+
+	// En mode warn, l'inscription est acceptée avec l'avertissement calculé dans la transaction
+	s.setAgePolicy(agePolicy{mode: ageCheckWarn, ranges: defaultAgePolicy().ranges})
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: tooYoung})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var enrollment Enrollment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+	require.Len(t, enrollment.Warnings, 1)
+	assert.Equal(t, "2024-09-07", enrollment.Warnings[0].ReferenceDate)
+
+	// Une date de début illisible n'est pas remplacée par la date du jour
+	_, err = testDB.Exec("UPDATE courses SET start_date = '07/09/2024' WHERE id = ?", course.ID)
+	require.NoError(t, err)
+	w = performRequest(r, "POST", path, EnrollmentRequest{UserID: first})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestGetAgeMismatches(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	_, err := testDB.Exec(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation) VALUES
+		('Léa', 'Roy', 'lea@test.com', '2019-03-01', 'PRÉSCOLAIRE 5'),
+		('Tom', 'Roy', 'tom@test.com', '2020-03-01', 'PRÉSCOLAIRE 2'),
+		('Ana', 'Roy', 'ana@test.com', '', 'NAGEUR 1')`)
+	require.NoError(t, err)
+
+	w := performRequest(r, "GET", "/api/users/age-mismatches?date=2024-09-01", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		ReferenceDate string            `json:"reference_date"`
+		Users         []UserAgeMismatch `json:"users"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "2024-09-01", response.ReferenceDate)
+	assert.Empty(t, response.Users)
+
+	// Un an plus tard, Léa (6 ans) n'a plus l'âge du préscolaire
+	w = performRequest(r, "GET", "/api/users/age-mismatches?date=2025-09-01", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Users, 1)
+	assert.Equal(t, "lea@test.com", response.Users[0].User.Email)
+	assert.Equal(t, 6, response.Users[0].Mismatch.Age)
+
+	w = performRequest(r, "GET", "/api/users/age-mismatches?date=01/09/2025", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
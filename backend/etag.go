@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userETag retourne l'ETag d'un usager : sa version, incrémentée à chaque modification.
+// L'âge, calculé à la lecture, n'en fait pas partie.
+func userETag(u User) string {
+	return `"` + strconv.Itoa(u.Version) + `"`
+}
+
+// etagMatches indique si etag figure dans la liste d'un en-tête If-Match ou If-None-Match ("*" : toute version).
+// La comparaison faible (If-None-Match) ignore le préfixe W/; la comparaison forte (If-Match) ne retient
+// aucun ETag faible (RFC 9110, section 8.8.3.2).
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = candidate[2:]
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPrecondition répond 428 et retourne false si la modification d'un usager ne porte pas If-Match
+// alors que le serveur l'exige (REQUIRE_IF_MATCH) : sans précondition, la dernière modification l'emporterait.
+func (s *Server) checkPrecondition(c *gin.Context) bool {
+	if !s.requireIfMatch || c.GetHeader("If-Match") != "" {
+		return true
+	}
+	abortError(c, http.StatusPreconditionRequired, CodePreconditionRequired, "La modification d'un usager doit porter l'en-tête If-Match")
+	return false
+}
+
+// checkIfMatch vérifie la précondition If-Match d'une modification. Sans If-Match, la modification est permise
+// (voir checkPrecondition).
+// Si l'ETag actuel n'est pas dans la liste, répond 412 avec l'ETag actuel et retourne false.
+func checkIfMatch(c *gin.Context, current User) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || etagMatches(header, userETag(current), false) {
+		return true
+	}
+	abortVersionConflict(c, current)
+	return false
+}
+
+// abortVersionConflict répond 412 : l'usager a été modifié depuis la version connue du client.
+// L'ETag actuel, s'il est connu, permet au client de relire l'usager avant de réessayer.
+func abortVersionConflict(c *gin.Context, current User) {
+	if current.Version != 0 {
+		c.Header("ETag", userETag(current))
+	}
+	abortError(c, http.StatusPreconditionFailed, CodePreconditionFailed, "L'usager a été modifié depuis sa lecture")
+}
+
+// notModified répond 304 sans corps et retourne true si If-None-Match contient l'ETag actuel
+func notModified(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" || !etagMatches(header, etag, true) {
+		return false
+	}
+	c.Status(http.StatusNotModified)
+	return true
+}
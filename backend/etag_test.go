@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"3"`, false, true},
+		{`"2", "3"`, false, true},
+		{`"2"`, false, false},
+		{`*`, false, true},
+		{`W/"3"`, false, false}, // If-Match : comparaison forte
+		{`W/"3"`, true, true},   // If-None-Match : comparaison faible
+		{`"2",W/"3"`, true, true},
+		{`3`, true, false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, etagMatches(tc.header, `"3"`, tc.weak), tc.header)
+	}
+}
+
+func TestUserETags(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	req := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"}
+	w := performRequest(r, "POST", "/api/users", req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, 1, u.Version)
+	assert.False(t, u.UpdatedAt.IsZero())
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	path := "/api/users/" + strconv.Itoa(u.ID)
+	withHeader := func(method, name, value string, body interface{}) *httptest.ResponseRecorder {
+		return performRequestWithHeaders(r, method, path, map[string]string{name: value}, body)
+	}
+
+	// Lecture : ETag, puis 304 sans corps tant que l'usager ne change pas
+	w = performRequest(r, "GET", path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+	w = withHeader("GET", "If-None-Match", etag, nil)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	w = withHeader("GET", "If-None-Match", `W/"1"`, nil)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// Modification avec l'ETag lu : nouvelle version
+	req.NiveauNatation = "NAGEUR 4"
+	w = withHeader("PUT", "If-Match", etag, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, 2, u.Version)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	w = withHeader("GET", "If-None-Match", etag, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Une deuxième modification à partir de la même lecture est refusée, sans rien enregistrer
+	req.LastName = "Martin"
+	w = withHeader("PUT", "If-Match", etag, req)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, CodePreconditionFailed, decodeProblem(t, w).Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	w = withHeader("PATCH", "If-Match", etag, json.RawMessage(`{"last_name": "Martin"}`))
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = withHeader("DELETE", "If-Match", etag, nil)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = performRequest(r, "GET", path, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "Dupont", u.LastName)
+	assert.Equal(t, 2, u.Version)
+
+	// Un patch avec l'ETag actuel ou "*" est accepté; sans If-Match, la modification reste permise
+	w = withHeader("PATCH", "If-Match", `"1", "2"`, json.RawMessage(`{"last_name": "Martin"}`))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	w = withHeader("PUT", "If-Match", "*", req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = performRequest(r, "PUT", path, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+
+	// Suppression conditionnelle
+	w = withHeader("DELETE", "If-Match", `"5"`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = withHeader("DELETE", "If-Match", `"5"`, nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// racingStore simule une modification concurrente : une autre requête enregistre l'usager
+// entre sa lecture et son enregistrement par le handler
+type racingStore struct {
+	*MemoryStore
+}
+
+func (s racingStore) Update(ctx context.Context, id int, req UserRequest, version int) (User, error) {
+	current, err := s.MemoryStore.Get(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	if _, err := s.MemoryStore.Update(ctx, id, UserRequest{FirstName: "Autre", LastName: current.LastName, Email: current.Email,
+		DateNaissance: current.DateNaissance, NiveauNatation: current.NiveauNatation}, 0); err != nil {
+		return User{}, err
+	}
+	return s.MemoryStore.Update(ctx, id, req, version)
+}
+
+func TestConcurrentUserUpdate(t *testing.T) {
+	store := racingStore{newMemoryStore()}
+	r := setupRouterWithStore(store)
+	req := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"}
+	u, err := store.Create(context.Background(), req)
+	require.NoError(t, err)
+	path := "/api/users/" + strconv.Itoa(u.ID)
+
+	// Avec If-Match : la précondition n'est plus vérifiée au moment de l'enregistrement (412)
+	w := performRequestWithHeaders(r, "PUT", path, map[string]string{"If-Match": userETag(u)}, req)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code, w.Body.String())
+	assert.Equal(t, CodePreconditionFailed, decodeProblem(t, w).Code)
+
+	// Sans If-Match, la requête n'avait pas de précondition : conflit (409), pas 412
+	w = performRequest(r, "PUT", path, req)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, CodeEditConflict, decodeProblem(t, w).Code)
+	w = performRequestWithHeaders(r, "PATCH", path, map[string]string{"Content-Type": mergePatchContentType}, json.RawMessage(`{"last_name": "Martin"}`))
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, CodeEditConflict, decodeProblem(t, w).Code)
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	store := newMemoryStore()
+	s := newServer(store)
+	s.requireIfMatch = true
+	r := setupRouterWithServer(s)
+	req := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"}
+	u, err := store.Create(context.Background(), req)
+	require.NoError(t, err)
+	path := "/api/users/" + strconv.Itoa(u.ID)
+
+	// Sans If-Match, PUT, PATCH et DELETE sont refusés (428) sans rien enregistrer
+	req.LastName = "Martin"
+	w := performRequest(r, "PUT", path, req)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code, w.Body.String())
+	assert.Equal(t, CodePreconditionRequired, decodeProblem(t, w).Code)
+	w = performRequestWithHeaders(r, "PATCH", path, map[string]string{"Content-Type": mergePatchContentType}, json.RawMessage(`{"last_name": "Martin"}`))
+	require.Equal(t, http.StatusPreconditionRequired, w.Code, w.Body.String())
+	w = performRequest(r, "DELETE", path, nil)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code, w.Body.String())
+	current, err := store.Get(context.Background(), u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Dupont", current.LastName)
+	assert.Equal(t, 1, current.Version)
+
+	// La création n'a pas de précondition; avec If-Match, la modification et la suppression sont permises
+	w = performRequest(r, "POST", "/api/users", UserRequest{FirstName: "Marie", LastName: "Dupont", DateNaissance: "2016-03-01", NiveauNatation: "NAGEUR 2"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = performRequestWithHeaders(r, "PUT", path, map[string]string{"If-Match": userETag(u)}, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = performRequestWithHeaders(r, "DELETE", path, map[string]string{"If-Match": "*"}, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestNextLevel(t *testing.T) {
+	next, ok := nextLevel("NAGEUR 3")
+	assert.True(t, ok)
+	assert.Equal(t, "NAGEUR 4", next)
+
+	next, ok = nextLevel("PRÉSCOLAIRE 5")
+	assert.True(t, ok)
+	assert.Equal(t, "NAGEUR 1", next)
+
+	_, ok = nextLevel("NAGEUR 9")
+	assert.False(t, ok)
+	_, ok = nextLevel("inconnu")
+	assert.False(t, ok)
+}
+
+func TestEvaluations(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	userID := insertTestUser(t, testDB, "jean@test.com", "NAGEUR 3")
+	path := "/api/users/" + strconv.Itoa(userID) + "/evaluations"
+
+	// Échec : l'usager reste au même niveau
+	w := performRequest(r, "POST", path, EvaluationRequest{
+		Criteria: []CriterionResult{
+			{Name: "Nage sur le ventre 15 m", Passed: true},
+			{Name: "Nage sur le dos 15 m", Passed: false, Comment: "Battements de jambes à travailler"},
+		},
+		Passed:      boolPtr(false),
+		Evaluator:   "Sophie Tremblay",
+		EvaluatedOn: "2024-06-15",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var evaluation Evaluation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &evaluation))
+	assert.Equal(t, "NAGEUR 3", evaluation.Level)
+	assert.Equal(t, "NAGEUR 3", evaluation.NewLevel)
+	assert.Len(t, evaluation.Criteria, 2)
+
+	// Réussite : l'usager passe au niveau suivant; le niveau évalué peut être donné par son code
+	w = performRequest(r, "POST", path, EvaluationRequest{
+		Level:       "NAGEUR_3",
+		Passed:      boolPtr(true),
+		Evaluator:   "Sophie Tremblay",
+		EvaluatedOn: "2024-11-30",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &evaluation))
+	assert.Equal(t, "NAGEUR 3", evaluation.PreviousLevel)
+	assert.Equal(t, "NAGEUR 4", evaluation.NewLevel)
+
+	w = performRequest(r, "GET", "/api/users/"+strconv.Itoa(userID), nil)
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "NAGEUR 4", u.NiveauNatation)
+
+	// L'évaluation doit porter sur le niveau actuel
+	w = performRequest(r, "POST", path, EvaluationRequest{Level: "NAGEUR 3", Passed: boolPtr(true), Evaluator: "Sophie Tremblay"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Champs requis et format de date
+	w = performRequest(r, "POST", path, EvaluationRequest{Evaluator: "Sophie Tremblay"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequest(r, "POST", path, EvaluationRequest{Passed: boolPtr(true), Evaluator: "Sophie Tremblay", EvaluatedOn: "30/11/2024"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Une évaluation datée dans le futur est refusée : elle ferait passer l'usager au niveau suivant
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	w = performRequest(r, "POST", path, EvaluationRequest{Passed: boolPtr(true), Evaluator: "Sophie Tremblay", EvaluatedOn: tomorrow})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p := decodeProblem(t, w)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "evaluated_on", p.Errors[0].Field)
+	assert.Equal(t, "past", p.Errors[0].Code)
+
+	w = performRequest(r, "GET", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var history struct {
+		Evaluations []Evaluation `json:"evaluations"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	require.Len(t, history.Evaluations, 2)
+	assert.False(t, history.Evaluations[0].Passed)
+	assert.True(t, history.Evaluations[1].Passed)
+	assert.Equal(t, "Battements de jambes à travailler", history.Evaluations[0].Criteria[1].Comment)
+
+	w = performRequest(r, "GET", "/api/users/999/evaluations", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(r, "POST", "/api/users/999/evaluations", EvaluationRequest{Passed: boolPtr(true), Evaluator: "Sophie Tremblay"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// La suppression de l'usager supprime son historique
+	var count int
+	w = performRequest(r, "DELETE", "/api/users/"+strconv.Itoa(userID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM evaluations").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestEvaluationAgePolicy(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	s := newServer(newSQLiteStore(testDB))
+	r := setupRouterWithServer(s)
+
+	// À 7 ans, la réussite de NAGEUR 6 mènerait à NAGEUR 7 (Jeune sauveteur, 8 ans et plus)
+	var userID int
+	err := testDB.QueryRow(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation)
+		VALUES ('Léa', 'Roy', '', ?, 'NAGEUR 6') RETURNING id`, time.Now().AddDate(-7, 0, 0).Format("2006-01-02")).Scan(&userID)
+	require.NoError(t, err)
+	path := "/api/users/" + strconv.Itoa(userID) + "/evaluations"
+	passed := EvaluationRequest{Level: "NAGEUR_6", Passed: boolPtr(true), Evaluator: "Sophie Tremblay"}
+
+	// Mode reject : l'évaluation est refusée et l'usager reste à son niveau
+	w := performRequest(r, "POST", path, passed)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	p := decodeProblem(t, w)
+	assert.Equal(t, CodeAgeMismatch, p.Code)
+	require.NotNil(t, p.AgeMismatch)
+	assert.Equal(t, "NAGEUR 7", p.AgeMismatch.Level)
+	assert.Equal(t, 7, p.AgeMismatch.Age)
+	var level string
+	var count int
+	require.NoError(t, testDB.QueryRow("SELECT niveau_natation FROM users WHERE id = ?", userID).Scan(&level))
+	assert.Equal(t, "NAGEUR 6", level)
+	require.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM evaluations").Scan(&count))
+	assert.Equal(t, 0, count)
+
+	// Un échec ne change pas de niveau : rien à vérifier
+	w = performRequest(r, "POST", path, EvaluationRequest{Passed: boolPtr(false), Evaluator: "Sophie Tremblay"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Mode warn : l'usager progresse et la réponse contient l'avertissement
+	s.setAgePolicy(agePolicy{mode: ageCheckWarn, ranges: defaultAgePolicy().ranges})
+	w = performRequest(r, "POST", path, passed)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var evaluation Evaluation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &evaluation))
+	assert.Equal(t, "NAGEUR 7", evaluation.NewLevel)
+	require.Len(t, evaluation.Warnings, 1)
+	assert.Equal(t, 8, evaluation.Warnings[0].MinAge)
+}
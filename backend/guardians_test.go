@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuardians(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	req := GuardianRequest{FirstName: "Julie", LastName: "Roy", Email: "julie.roy@test.com", Phone: "514-555-0123"}
+	w := performRequest(r, "POST", "/api/guardians", req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var guardian Guardian
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &guardian))
+	assert.Equal(t, "514-555-0123", guardian.Phone)
+	path := "/api/guardians/" + strconv.Itoa(guardian.ID)
+
+	// L'email d'un tuteur est unique
+	w = performRequest(r, "POST", "/api/guardians", req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = performRequest(r, "POST", "/api/guardians", GuardianRequest{FirstName: "Julie", LastName: "Roy", Email: "pas-un-email"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Des enfants d'une même famille partagent l'email du parent, ou n'en ont pas
+	var childIDs []int
+	for _, child := range []UserRequest{
+		{FirstName: "Léa", LastName: "Roy", Email: "julie.roy@test.com", DateNaissance: "2016-03-01", NiveauNatation: "NAGEUR 4"},
+		{FirstName: "Tom", LastName: "Roy", DateNaissance: "2019-06-01", NiveauNatation: "NAGEUR 1"},
+	} {
+		w = performRequest(r, "POST", "/api/users", child)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var u User
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+		childIDs = append(childIDs, u.ID)
+
+		w = performRequest(r, "POST", path+"/children", GuardianLinkRequest{UserID: u.ID, Relationship: "mère"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	w = performRequest(r, "POST", path+"/children", GuardianLinkRequest{UserID: childIDs[0]})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = performRequest(r, "POST", path+"/children", GuardianLinkRequest{UserID: 999})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(r, "POST", "/api/guardians/999/children", GuardianLinkRequest{UserID: childIDs[0]})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = performRequest(r, "GET", path+"/children", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var household struct {
+		Children []Child `json:"children"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &household))
+	require.Len(t, household.Children, 2)
+	assert.Equal(t, "Léa", household.Children[0].FirstName)
+	assert.Equal(t, "mère", household.Children[0].Relationship)
+	assert.Greater(t, household.Children[0].Age, 0)
+
+	w = performRequest(r, "GET", "/api/users/"+strconv.Itoa(childIDs[1])+"/guardians", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var guardians struct {
+		Guardians []UserGuardian `json:"guardians"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &guardians))
+	require.Len(t, guardians.Guardians, 1)
+	assert.Equal(t, "julie.roy@test.com", guardians.Guardians[0].Email)
+
+	w = performRequest(r, "DELETE", path+"/children/"+strconv.Itoa(childIDs[1]), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(r, "DELETE", path+"/children/"+strconv.Itoa(childIDs[1]), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req.Phone = "438-555-0199"
+	w = performRequest(r, "PUT", path, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(r, "GET", "/api/guardians?search=roy", nil)
+	var list struct {
+		Guardians []Guardian `json:"guardians"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Guardians, 1)
+	assert.Equal(t, "438-555-0199", list.Guardians[0].Phone)
+
+	// La suppression du tuteur conserve les usagers
+	w = performRequest(r, "DELETE", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequest(r, "GET", path+"/children", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(r, "GET", "/api/users/"+strconv.Itoa(childIDs[0]), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUserEmailUniqueness(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	adult := UserRequest{FirstName: "Julie", LastName: "Roy", Email: "julie.roy@test.com", DateNaissance: "1985-03-01", NiveauNatation: "NAGEUR 9"}
+	w := performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	adult.FirstName = "Marc"
+	w = performRequest(r, "POST", "/api/users", adult)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	child := UserRequest{FirstName: "Léa", LastName: "Roy", Email: "julie.roy@test.com", DateNaissance: "2016-03-01", NiveauNatation: "NAGEUR 4"}
+	w = performRequest(r, "POST", "/api/users", child)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	child.FirstName, child.Email = "Tom", ""
+	w = performRequest(r, "POST", "/api/users", child)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Des frères et sœurs devenus majeurs avec l'email d'un parent restent modifiables
+	// tant que leur email ne change pas
+	res, err := testDB.Exec(`INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation) VALUES
+		('Tom', 'Roy', 'famille.roy@test.com', '2000-01-01', 'NAGEUR 9'),
+		('Zoé', 'Roy', 'famille.roy@test.com', '2001-01-01', 'NAGEUR 9')`)
+	require.NoError(t, err)
+	lastID, err := res.LastInsertId()
+	require.NoError(t, err)
+	sibling := UserRequest{FirstName: "Zoé", LastName: "Roy", Email: "famille.roy@test.com", DateNaissance: "2001-01-01", NiveauNatation: "NAGEUR 8"}
+	w = performRequest(r, "PUT", "/api/users/"+strconv.FormatInt(lastID, 10), sibling)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	sibling.Email = "julie.roy@test.com"
+	w = performRequest(r, "PUT", "/api/users/"+strconv.FormatInt(lastID, 10), sibling)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -1,21 +1,128 @@
 package main
 
 import (
-	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// Server regroupe les dépendances partagées par les handlers HTTP
+type Server struct {
+	store       UserStore
+	courses     CourseStore     // nil si le store ne gère pas les cours
+	evaluations EvaluationStore // nil si le store ne gère pas les évaluations
+	guardians   GuardianStore   // nil si le store ne gère pas les tuteurs
+	attendance  AttendanceStore // nil si le store ne gère pas les présences
+	ages        agePolicy       // Tranches d'âge par niveau et mode de vérification
+	auth        *authenticator  // nil si l'authentification est désactivée (API ouverte)
+	health      HealthStore     // nil si le store n'est pas vérifié par /readyz
+	dataDir     string          // Répertoire des données (SQLite) dont /readyz vérifie l'écriture; vide si aucun
+	metrics     *metrics        // nil si /metrics n'est pas exposé
+
+	requireIfMatch bool // Les modifications d'usagers sans If-Match sont refusées (428)
+}
+
+// newServer crée un Server utilisant le UserStore fourni.
+// Les fonctionnalités optionnelles (cours, évaluations, tuteurs, présences, ...) sont activées si le store les implémente.
+func newServer(store UserStore) *Server {
+	s := &Server{store: store, ages: defaultAgePolicy()}
+	if courses, ok := store.(CourseStore); ok {
+		s.courses = courses
+	}
+	if evaluations, ok := store.(EvaluationStore); ok {
+		s.evaluations = evaluations
+	}
+	if guardians, ok := store.(GuardianStore); ok {
+		s.guardians = guardians
+	}
+	if attendance, ok := store.(AttendanceStore); ok {
+		s.attendance = attendance
+	}
+	if health, ok := store.(HealthStore); ok {
+		s.health = health
+	}
+	return s
+}
+
+// validateUserRequest normalise les noms et l'email (facultatif), vérifie la date de naissance et que le niveau
+// de natation existe dans le catalogue, et remplace un code de niveau par son libellé.
+// Retourne les champs invalides.
+func validateUserRequest(req *UserRequest) []FieldError {
+	fields := validateUserFields(&req.FirstName, &req.LastName, &req.Email)
+	fields = append(fields, validateBirthDate("date_naissance", req.DateNaissance, time.Now())...)
+	if level, ok := findLevel(req.NiveauNatation); ok {
+		req.NiveauNatation = level.Label
+	} else {
+		fields = append(fields, fieldError("niveau_natation", "unknown_level", "Niveau de natation inconnu: %s", req.NiveauNatation))
+	}
+	return fields
+}
+
+// checkAge applique la politique d'âge au niveau et à la date de naissance, à la date de référence.
+// En mode reject, répond 422 avec l'incompatibilité et retourne false; en mode warn, retourne l'avertissement.
+func (s *Server) checkAge(c *gin.Context, level, dateNaissance string, ref time.Time) ([]AgeMismatch, bool) {
+	if s.ages.mode == ageCheckOff {
+		return nil, true
+	}
+	mismatch := s.ages.check(level, dateNaissance, ref)
+	if mismatch == nil {
+		return nil, true
+	}
+	if s.ages.mode == ageCheckWarn {
+		return []AgeMismatch{*mismatch}, true
+	}
+	abortAgeMismatch(c, *mismatch)
+	return nil, false
+}
+
+// abortAgeMismatch répond 422 avec l'incompatibilité entre l'âge de l'usager et le niveau
+func abortAgeMismatch(c *gin.Context, mismatch AgeMismatch) {
+	abortProblem(c, Problem{
+		Status:      http.StatusUnprocessableEntity,
+		Code:        CodeAgeMismatch,
+		Detail:      "L'âge de l'usager ne correspond pas au niveau de natation",
+		AgeMismatch: &mismatch,
+	})
+}
+
+// setAgePolicy remplace la politique d'âge, appliquée aussi par le store des cours
+// aux inscriptions et aux promotions depuis la liste d'attente
+func (s *Server) setAgePolicy(p agePolicy) {
+	s.ages = p
+	if st, ok := s.courses.(agePolicyStore); ok {
+		st.setAgePolicy(p)
+	}
+}
+
+// userFilterFromQuery lit la recherche globale et les filtres par colonne de la requête
+// (filter_niveau accepte un code ou libellé de niveau, ou un code de programme)
+func userFilterFromQuery(c *gin.Context) UserFilter {
+	filter := UserFilter{
+		Search:  c.Query("search"),
+		Niveaux: resolveLevelFilter(c.Query("filter_niveau")),
+	}
+	if minAge, err := strconv.Atoi(c.Query("filter_age_min")); err == nil {
+		filter.AgeMin = &minAge
+	}
+	if maxAge, err := strconv.Atoi(c.Query("filter_age_max")); err == nil {
+		filter.AgeMax = &maxAge
+	}
+	return filter
+}
+
 // getUsers liste tous les usagers avec pagination, recherche et filtres
 // GET /api/users
-func getUsers(c *gin.Context) {
+func (s *Server) getUsers(c *gin.Context) {
 	// Paramètres de pagination
 	page := 1
 	limit := 10
-	search := c.Query("search")
 
 	if p := c.Query("page"); p != "" {
 		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
@@ -29,103 +136,16 @@ func getUsers(c *gin.Context) {
 		}
 	}
 
-	offset := (page - 1) * limit
-
-	// Filtres par colonne
-	filterNiveau := c.Query("filter_niveau")
-	filterAgeMin := c.Query("filter_age_min")
-	filterAgeMax := c.Query("filter_age_max")
-
-	// Construire la requête avec recherche et filtres
-	var query string
-	var args []interface{}
-	var whereConditions []string
-	var whereArgs []interface{}
-
-	// Recherche globale
-	if search != "" {
-		whereConditions = append(whereConditions, "(first_name LIKE ? OR last_name LIKE ? OR email LIKE ?)")
-		searchPattern := "%" + search + "%"
-		whereArgs = append(whereArgs, searchPattern, searchPattern, searchPattern)
-	}
-
-	// Filtre par niveau
-	if filterNiveau != "" {
-		whereConditions = append(whereConditions, "niveau_natation = ?")
-		whereArgs = append(whereArgs, filterNiveau)
-	}
-
-	// Filtre par âge (calculé à partir de date_naissance)
-	if filterAgeMin != "" || filterAgeMax != "" {
-		// Pour SQLite, on calcule l'âge approximativement
-		// On utilise une sous-requête pour calculer l'âge
-		ageCondition := `(
-			(julianday('now') - julianday(date_naissance)) / 365.25
-		)`
-		if filterAgeMin != "" {
-			if minAge, err := strconv.Atoi(filterAgeMin); err == nil {
-				whereConditions = append(whereConditions, ageCondition+" >= ?")
-				whereArgs = append(whereArgs, float64(minAge))
-			}
-		}
-		if filterAgeMax != "" {
-			if maxAge, err := strconv.Atoi(filterAgeMax); err == nil {
-				whereConditions = append(whereConditions, ageCondition+" <= ?")
-				whereArgs = append(whereArgs, float64(maxAge))
-			}
-		}
-	}
-
-	whereClause := ""
-	if len(whereConditions) > 0 {
-		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
-	}
-
-	query = `SELECT id, first_name, last_name, email, date_naissance, niveau_natation, created_at 
-		FROM users 
-		` + whereClause + `
-		ORDER BY id DESC 
-		LIMIT ? OFFSET ?`
-	
-	args = append(whereArgs, limit, offset)
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	defer rows.Close()
-
-	var users []User
-	for rows.Next() {
-		var u User
-		var dateNaissance sql.NullString
-		var niveauNatation sql.NullString
-		err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &dateNaissance, &niveauNatation, &u.CreatedAt)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		u.DateNaissance = dateNaissance.String
-		u.NiveauNatation = niveauNatation.String
-		u.Age = calculateAge(u.DateNaissance)
-		users = append(users, u)
-	}
+	filter := userFilterFromQuery(c)
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
 
-	// Compter le total (avec ou sans recherche/filtres)
-	var total int
-	countQuery := `SELECT COUNT(*) FROM users ` + whereClause
-	err = db.QueryRow(countQuery, whereArgs...).Scan(&total)
+	users, total, err := s.store.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	// S'assurer qu'on retourne toujours un tableau, même vide
-	if users == nil {
-		users = []User{}
-	}
-
 	totalPages := (total + limit - 1) / limit
 	if totalPages == 0 {
 		totalPages = 1
@@ -142,133 +162,308 @@ func getUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// getUserByID récupère un usager par son ID
+// exportPageSize est le nombre d'usagers lus à la fois pendant un export
+const exportPageSize = 500
+
+// exportUsers exporte en CSV tous les usagers correspondant aux filtres de getUsers, sans pagination
+// GET /api/users/export
+func (s *Server) exportUsers(c *gin.Context) {
+	filter := userFilterFromQuery(c)
+	filter.Limit = exportPageSize
+
+	// Lire tous les usagers avant d'écrire : une erreur peut encore être retournée en JSON
+	var users []User
+	for {
+		page, total, err := s.store.List(c.Request.Context(), filter)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		users = append(users, page...)
+		filter.Offset += exportPageSize
+		if len(page) == 0 || filter.Offset >= total {
+			break
+		}
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="usagers.csv"`)
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"id", "first_name", "last_name", "email", "date_naissance", "age", "niveau_natation", "created_at"})
+	for _, u := range users {
+		w.Write([]string{
+			strconv.Itoa(u.ID), u.FirstName, u.LastName, u.Email, u.DateNaissance,
+			strconv.Itoa(u.Age), u.NiveauNatation, u.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+}
+
+// getUserByID récupère un usager par son ID. La réponse porte l'ETag de l'usager;
+// avec If-None-Match, répond 304 si l'usager n'a pas changé.
 // GET /api/users/:id
-func getUserByID(c *gin.Context) {
+func (s *Server) getUserByID(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		abortInvalidID(c, "ID invalide")
 		return
 	}
 
-	var u User
-	var dateNaissance sql.NullString
-	var niveauNatation sql.NullString
-	err = db.QueryRow("SELECT id, first_name, last_name, email, date_naissance, niveau_natation, created_at FROM users WHERE id = ?", id).
-		Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &dateNaissance, &niveauNatation, &u.CreatedAt)
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usager non trouvé"})
+	u, err := s.store.Get(c.Request.Context(), id)
+	if errors.Is(err, ErrUserNotFound) {
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	u.DateNaissance = dateNaissance.String
-	u.NiveauNatation = niveauNatation.String
-	u.Age = calculateAge(u.DateNaissance)
 
+	c.Header("ETag", userETag(u))
+	if notModified(c, userETag(u)) {
+		return
+	}
 	c.JSON(http.StatusOK, u)
 }
 
 // createUser crée un nouvel usager
 // POST /api/users
-func createUser(c *gin.Context) {
+func (s *Server) createUser(c *gin.Context) {
 	var req UserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validateUserRequest(&req); len(fields) > 0 {
+		abortValidation(c, fields...)
 		return
 	}
 
-	result, err := db.Exec("INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation) VALUES (?, ?, ?, ?, ?)",
-		req.FirstName, req.LastName, req.Email, req.DateNaissance, req.NiveauNatation)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	warnings, ok := s.checkAge(c, req.NiveauNatation, req.DateNaissance, time.Now())
+	if !ok {
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	var u User
-	var dateNaissance sql.NullString
-	var niveauNatation sql.NullString
-	err = db.QueryRow("SELECT id, first_name, last_name, email, date_naissance, niveau_natation, created_at FROM users WHERE id = ?", id).
-		Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &dateNaissance, &niveauNatation, &u.CreatedAt)
+	u, err := s.store.Create(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	u.DateNaissance = dateNaissance.String
-	u.NiveauNatation = niveauNatation.String
-	u.Age = calculateAge(u.DateNaissance)
 
+	u.Warnings = warnings
+	c.Header("ETag", userETag(u))
 	c.JSON(http.StatusCreated, u)
 }
 
 // updateUser modifie un usager existant
 // PUT /api/users/:id
-func updateUser(c *gin.Context) {
+func (s *Server) updateUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		abortInvalidID(c, "ID invalide")
 		return
 	}
 
 	var req UserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validateUserRequest(&req); len(fields) > 0 {
+		abortValidation(c, fields...)
 		return
 	}
 
-	result, err := db.Exec("UPDATE users SET first_name = ?, last_name = ?, email = ?, date_naissance = ?, niveau_natation = ? WHERE id = ?",
-		req.FirstName, req.LastName, req.Email, req.DateNaissance, req.NiveauNatation, id)
+	s.saveUser(c, id, func(User) (UserRequest, bool) { return req, true })
+}
+
+// patchUser modifie une partie des champs d'un usager : JSON Merge Patch (RFC 7396, application/merge-patch+json
+// ou application/json) ou JSON Patch (RFC 6902, application/json-patch+json). Le patch s'applique
+// aux champs de UserRequest et l'usager obtenu est validé comme par createUser.
+// PATCH /api/users/:id
+func (s *Server) patchUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortInvalidID(c, "ID invalide")
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usager non trouvé"})
+	body, err := c.GetRawData()
+	if err != nil {
+		bindError(c, err)
+		return
+	}
+	patch, err := parsePatch(c.ContentType(), body)
+	if err != nil {
+		var e apiError
+		if errors.As(err, &e) && e.Status == http.StatusUnsupportedMediaType {
+			c.Header("Accept-Patch", acceptPatch)
+		}
+		respondError(c, err)
 		return
 	}
 
-	var u User
-	var dateNaissance sql.NullString
-	var niveauNatation sql.NullString
-	err = db.QueryRow("SELECT id, first_name, last_name, email, date_naissance, niveau_natation, created_at FROM users WHERE id = ?", id).
-		Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &dateNaissance, &niveauNatation, &u.CreatedAt)
+	s.saveUser(c, id, func(current User) (UserRequest, bool) {
+		doc, err := patch.apply(userDocument(current))
+		if err != nil {
+			respondError(c, err)
+			return UserRequest{}, false
+		}
+		return patchedUserRequest(c, doc)
+	})
+}
+
+// userDocument retourne les champs modifiables d'un usager sous la forme du document JSON auquel s'applique un patch
+func userDocument(u User) map[string]any {
+	data, _ := json.Marshal(UserRequest{
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Email:          u.Email,
+		DateNaissance:  u.DateNaissance,
+		NiveauNatation: u.NiveauNatation,
+	})
+	var doc map[string]any
+	json.Unmarshal(data, &doc)
+	return doc
+}
+
+// patchedUserRequest convertit le document obtenu par un patch en requête et la valide comme createUser.
+// Un champ qui n'est pas dans UserRequest (ex: id, age) ne peut pas être modifié.
+// En cas d'erreur, répond 422 et retourne false.
+func patchedUserRequest(c *gin.Context, doc any) (UserRequest, bool) {
+	var req UserRequest
+	fields, ok := doc.(map[string]any)
+	if !ok {
+		abortError(c, http.StatusUnprocessableEntity, CodeInvalidPatch, "Le patch doit produire un objet")
+		return req, false
+	}
+	editable := userDocument(User{})
+	var unknown []FieldError
+	for name := range fields {
+		if _, ok := editable[name]; !ok {
+			unknown = append(unknown, fieldError(name, "read_only", "Champ non modifiable"))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i].Field < unknown[j].Field })
+		abortValidation(c, unknown...)
+		return req, false
+	}
+
+	data, _ := json.Marshal(fields)
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, bindError(c, err)
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return req, bindError(c, err)
+	}
+	if invalid := validateUserRequest(&req); len(invalid) > 0 {
+		abortValidation(c, invalid...)
+		return req, false
+	}
+	return req, true
+}
+
+// saveUser enregistre les modifications d'un usager existant. build construit la requête
+// à partir de l'usager actuel, ce qui permet de ne modifier qu'une partie de ses champs;
+// il retourne false s'il a déjà répondu (requête invalide).
+// La précondition If-Match est vérifiée sur l'usager actuel. L'enregistrement échoue si l'usager est
+// modifié par une autre requête entre sa lecture et son enregistrement : 412 si la requête porte If-Match,
+// 409 sinon (la requête n'avait pas de précondition, mais la modification a été construite sur l'usager lu).
+func (s *Server) saveUser(c *gin.Context, id int, build func(current User) (UserRequest, bool)) {
+	// L'âge n'est vérifié que si le niveau ou la date de naissance changent,
+	// pour ne pas bloquer la modification d'un usager devenu trop âgé pour son niveau
+	if !s.checkPrecondition(c) {
+		return
+	}
+	current, err := s.store.Get(c.Request.Context(), id)
+	if errors.Is(err, ErrUserNotFound) {
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+	if !checkIfMatch(c, current) {
+		return
+	}
+	req, ok := build(current)
+	if !ok {
+		return
+	}
+	var warnings []AgeMismatch
+	if current.NiveauNatation != req.NiveauNatation || current.DateNaissance != req.DateNaissance {
+		var ok bool
+		if warnings, ok = s.checkAge(c, req.NiveauNatation, req.DateNaissance, time.Now()); !ok {
+			return
+		}
+	}
+
+	u, err := s.store.Update(c.Request.Context(), id, req, current.Version)
+	if errors.Is(err, ErrUserNotFound) {
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+		return
+	}
+	if errors.Is(err, ErrVersionConflict) && c.GetHeader("If-Match") != "" {
+		abortVersionConflict(c, User{})
+		return
+	}
+	if errors.Is(err, ErrVersionConflict) {
+		abortError(c, http.StatusConflict, CodeEditConflict, "L'usager a été modifié pendant l'enregistrement")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
 		return
 	}
-	u.DateNaissance = dateNaissance.String
-	u.NiveauNatation = niveauNatation.String
-	u.Age = calculateAge(u.DateNaissance)
 
+	u.Warnings = warnings
+	c.Header("ETag", userETag(u))
 	c.JSON(http.StatusOK, u)
 }
 
-// deleteUser supprime un usager
+// deleteUser supprime un usager. Avec If-Match, seule la version indiquée est supprimée (sinon 412);
+// sans If-Match, 428 si le serveur l'exige.
 // DELETE /api/users/:id
-func deleteUser(c *gin.Context) {
+func (s *Server) deleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID invalide"})
+		abortInvalidID(c, "ID invalide")
 		return
 	}
-
-	result, err := db.Exec("DELETE FROM users WHERE id = ?", id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !s.checkPrecondition(c) {
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Usager non trouvé"})
+	version := 0
+	if c.GetHeader("If-Match") != "" {
+		current, err := s.store.Get(c.Request.Context(), id)
+		if errors.Is(err, ErrUserNotFound) {
+			abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+			return
+		}
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if !checkIfMatch(c, current) {
+			return
+		}
+		version = current.Version
+	}
+
+	err = s.store.Delete(c.Request.Context(), id, version)
+	if errors.Is(err, ErrUserNotFound) {
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+		return
+	}
+	if errors.Is(err, ErrVersionConflict) {
+		abortVersionConflict(c, User{})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Usager supprimé avec succès"})
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Usager supprimé avec succès")})
 }
-
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getAPIKeys liste les clés d'API, y compris les clés révoquées (sans les clés elles-mêmes)
+// GET /api/api-keys
+func (s *Server) getAPIKeys(c *gin.Context) {
+	keys, err := s.auth.keys.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// createAPIKey crée une clé d'API. La clé n'est retournée qu'une fois, dans cette réponse.
+// POST /api/api-keys
+func (s *Server) createAPIKey(c *gin.Context) {
+	var req APIKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var createdBy *int
+	if staff, ok := c.Get(staffContextKey); ok {
+		id := staff.(Staff).ID
+		createdBy = &id
+	}
+
+	k, err := s.auth.keys.CreateAPIKey(c.Request.Context(), req.Name, req.Scopes, prefix, hash, createdBy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIKeyResponse{APIKey: k, Key: key})
+}
+
+// rotateAPIKey remplace une clé d'API par une nouvelle clé, avec le même nom et les mêmes portées.
+// L'ancienne clé est refusée immédiatement.
+// POST /api/api-keys/:id/rotate
+func (s *Server) rotateAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	key, prefix, hash, err := generateAPIKey()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	k, err := s.auth.keys.RotateAPIKey(c.Request.Context(), id, prefix, hash)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		abortError(c, http.StatusNotFound, CodeAPIKeyNotFound, "Clé d'API non trouvée ou révoquée")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, APIKeyResponse{APIKey: k, Key: key})
+}
+
+// revokeAPIKey révoque une clé d'API
+// DELETE /api/api-keys/:id
+func (s *Server) revokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	err = s.auth.keys.RevokeAPIKey(c.Request.Context(), id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		abortError(c, http.StatusNotFound, CodeAPIKeyNotFound, "Clé d'API non trouvée ou révoquée")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Clé d'API révoquée")})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMeetings liste les séances d'un cours avec le décompte des présences
+// GET /api/courses/:id/meetings
+func (s *Server) getMeetings(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	meetings, err := s.attendance.ListMeetings(c.Request.Context(), id)
+	if e, ok := attendanceError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"meetings": meetings})
+}
+
+// generateMeetings génère le calendrier des séances d'un cours (corps optionnel : dates exclues)
+// POST /api/courses/:id/meetings
+func (s *Server) generateMeetings(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req MeetingsRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	meetings, err := s.attendance.GenerateMeetings(c.Request.Context(), id, req.ExcludeDates)
+	if e, ok := attendanceError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"meetings": meetings})
+}
+
+// getMeetingAttendance liste les présences enregistrées pour une séance
+// GET /api/courses/:id/meetings/:meetingId/attendance
+func (s *Server) getMeetingAttendance(c *gin.Context) {
+	id, meetingID, ok := meetingParams(c)
+	if !ok {
+		return
+	}
+
+	records, err := s.attendance.ListAttendance(c.Request.Context(), id, meetingID)
+	if e, ok := attendanceError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attendance": records})
+}
+
+// markMeetingAttendance enregistre en une fois les présences d'une séance
+// PUT /api/courses/:id/meetings/:meetingId/attendance
+func (s *Server) markMeetingAttendance(c *gin.Context) {
+	id, meetingID, ok := meetingParams(c)
+	if !ok {
+		return
+	}
+
+	var req AttendanceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	records, err := s.attendance.MarkAttendance(c.Request.Context(), id, meetingID, req.Records)
+	if e, ok := attendanceError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"attendance": records})
+}
+
+// getCourseAttendance retourne le taux de présence d'un cours, au total et par usager
+// GET /api/courses/:id/attendance
+func (s *Server) getCourseAttendance(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	report, err := s.attendance.CourseAttendance(c.Request.Context(), id)
+	if e, ok := attendanceError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// getUserAttendance retourne le taux de présence d'un usager, au total et par cours
+// GET /api/users/:id/attendance
+func (s *Server) getUserAttendance(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	report, err := s.attendance.UserAttendance(c.Request.Context(), id)
+	if e, ok := attendanceError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// meetingParams lit les paramètres :id et :meetingId; répond 400 s'ils sont invalides
+func meetingParams(c *gin.Context) (int, int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return 0, 0, false
+	}
+	meetingID, err := strconv.Atoi(c.Param("meetingId"))
+	if err != nil {
+		abortInvalidID(c, "ID de séance invalide")
+		return 0, 0, false
+	}
+	return id, meetingID, true
+}
+
+// attendanceError associe les erreurs métier des présences à une réponse
+func attendanceError(err error) (apiError, bool) {
+	var notEnrolled NotEnrolledError
+	switch {
+	case errors.Is(err, ErrCourseNotFound):
+		return newAPIError(http.StatusNotFound, CodeCourseNotFound, "Cours non trouvé"), true
+	case errors.Is(err, ErrUserNotFound):
+		return newAPIError(http.StatusNotFound, CodeUserNotFound, "Usager non trouvé"), true
+	case errors.Is(err, ErrMeetingNotFound):
+		return newAPIError(http.StatusNotFound, CodeMeetingNotFound, "Séance non trouvée"), true
+	case errors.As(err, &notEnrolled):
+		return newAPIError(http.StatusUnprocessableEntity, CodeNotEnrolled, "Seuls les usagers inscrits au cours peuvent être marqués (usager %d non inscrit)", notEnrolled.UserID), true
+	case errors.Is(err, ErrNotEnrolled):
+		return newAPIError(http.StatusUnprocessableEntity, CodeNotEnrolled, "Seuls les usagers inscrits au cours peuvent être marqués"), true
+	}
+	return apiError{}, false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// login authentifie un membre du personnel et retourne ses jetons d'accès et de rafraîchissement
+// POST /api/auth/login
+func (s *Server) login(c *gin.Context) {
+	var req LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := s.auth.Login(c.Request.Context(), req.Email, req.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		abortError(c, http.StatusUnauthorized, CodeInvalidCredentials, "Email ou mot de passe invalide")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// refreshTokens échange un jeton de rafraîchissement contre une nouvelle paire de jetons
+// POST /api/auth/refresh
+func (s *Server) refreshTokens(c *gin.Context) {
+	var req RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := s.auth.Refresh(c.Request.Context(), req.RefreshToken)
+	if errors.Is(err, ErrInvalidToken) {
+		abortError(c, http.StatusUnauthorized, CodeInvalidToken, "Jeton de rafraîchissement invalide ou expiré")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// logout révoque le jeton d'accès courant et, s'il est fourni, le jeton de rafraîchissement
+// POST /api/auth/logout
+func (s *Server) logout(c *gin.Context) {
+	var req LogoutRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	staff := c.MustGet(staffContextKey).(Staff)
+
+	if req.RefreshToken != "" {
+		owner, claims, err := s.auth.Authenticate(ctx, req.RefreshToken, refreshToken)
+		if err != nil && !errors.Is(err, ErrInvalidToken) {
+			respondError(c, err)
+			return
+		}
+		// Un jeton déjà invalide ou appartenant à un autre compte est ignoré
+		if err == nil && owner.ID == staff.ID {
+			if err := s.auth.Revoke(ctx, claims); err != nil {
+				respondError(c, err)
+				return
+			}
+		}
+	}
+
+	if err := s.auth.Revoke(ctx, c.MustGet(claimsContextKey).(*tokenClaims)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Déconnexion réussie")})
+}
+
+// getCurrentStaff retourne le compte du personnel authentifié, avec les permissions de son rôle
+// GET /api/auth/me
+func (s *Server) getCurrentStaff(c *gin.Context) {
+	c.JSON(http.StatusOK, c.MustGet(staffContextKey).(Staff).withPermissions())
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateCourseRequest vérifie le niveau ainsi que la cohérence des horaires et des dates d'un cours.
+// Un code de niveau est remplacé par son libellé. Retourne les champs invalides.
+func validateCourseRequest(req *CourseRequest) []FieldError {
+	var fields []FieldError
+	if level, ok := findLevel(req.Level); ok {
+		req.Level = level.Label
+	} else {
+		fields = append(fields, fieldError("level", "unknown_level", "Niveau de natation inconnu: %s", req.Level))
+	}
+
+	// Les formats HH:MM et YYYY-MM-DD sont validés par le binding, la comparaison de chaînes suffit
+	if req.EndTime <= req.StartTime {
+		fields = append(fields, fieldError("end_time", "after_start", "L'heure de fin doit être après l'heure de début"))
+	}
+	if req.EndDate < req.StartDate {
+		fields = append(fields, fieldError("end_date", "after_start", "La date de fin doit être après la date de début"))
+	}
+	return fields
+}
+
+// getCourses liste les cours, filtrables par session, par niveau et par usager inscrit
+// GET /api/courses
+func (s *Server) getCourses(c *gin.Context) {
+	filter := CourseFilter{
+		Session: c.Query("session"),
+		Level:   c.Query("level"),
+	}
+	if userID, err := strconv.Atoi(c.Query("user_id")); err == nil {
+		filter.UserID = userID
+	}
+
+	courses, err := s.courses.ListCourses(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"courses": courses})
+}
+
+// getCourseByID récupère un cours par son ID
+// GET /api/courses/:id
+func (s *Server) getCourseByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	course, err := s.courses.GetCourse(c.Request.Context(), id)
+	if errors.Is(err, ErrCourseNotFound) {
+		abortError(c, http.StatusNotFound, CodeCourseNotFound, "Cours non trouvé")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, course)
+}
+
+// createCourse crée un nouveau cours
+// POST /api/courses
+func (s *Server) createCourse(c *gin.Context) {
+	var req CourseRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validateCourseRequest(&req); len(fields) > 0 {
+		abortValidation(c, fields...)
+		return
+	}
+
+	course, err := s.courses.CreateCourse(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, course)
+}
+
+// updateCourse modifie un cours existant
+// PUT /api/courses/:id
+func (s *Server) updateCourse(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req CourseRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validateCourseRequest(&req); len(fields) > 0 {
+		abortValidation(c, fields...)
+		return
+	}
+
+	course, err := s.courses.UpdateCourse(c.Request.Context(), id, req)
+	switch {
+	case errors.Is(err, ErrCourseNotFound):
+		abortError(c, http.StatusNotFound, CodeCourseNotFound, "Cours non trouvé")
+		return
+	case errors.Is(err, ErrCapacityTooLow):
+		abortError(c, http.StatusConflict, CodeCapacityBelowEnrollment, "La capacité ne peut pas être inférieure au nombre d'inscrits")
+		return
+	case err != nil:
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, course)
+}
+
+// deleteCourse supprime un cours et ses inscriptions
+// DELETE /api/courses/:id
+func (s *Server) deleteCourse(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	err = s.courses.DeleteCourse(c.Request.Context(), id)
+	if errors.Is(err, ErrCourseNotFound) {
+		abortError(c, http.StatusNotFound, CodeCourseNotFound, "Cours non trouvé")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Cours supprimé avec succès")})
+}
+
+// getEnrollments liste les usagers inscrits à un cours
+// GET /api/courses/:id/enrollments
+func (s *Server) getEnrollments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	enrollments, err := s.courses.ListEnrollments(c.Request.Context(), id)
+	if errors.Is(err, ErrCourseNotFound) {
+		abortError(c, http.StatusNotFound, CodeCourseNotFound, "Cours non trouvé")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enrollments": enrollments})
+}
+
+// createEnrollment inscrit un usager à un cours
+// POST /api/courses/:id/enrollments
+func (s *Server) createEnrollment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req EnrollmentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Le niveau et l'âge au début du cours sont vérifiés par Enroll, dans la transaction de l'inscription
+	result, err := s.courses.Enroll(c.Request.Context(), id, req.UserID)
+	var ageErr *AgeMismatchError
+	if errors.As(err, &ageErr) {
+		abortAgeMismatch(c, ageErr.Mismatch)
+		return
+	}
+	if e, ok := enrollmentError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Cours complet : l'usager est placé en liste d'attente
+	if result.Waitlist != nil {
+		c.JSON(http.StatusAccepted, result.Waitlist)
+		return
+	}
+	c.JSON(http.StatusCreated, result.Enrollment)
+}
+
+// deleteEnrollment désinscrit un usager d'un cours
+// DELETE /api/courses/:id/enrollments/:userId
+func (s *Server) deleteEnrollment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		abortInvalidID(c, "ID d'usager invalide")
+		return
+	}
+
+	promoted, err := s.courses.Unenroll(c.Request.Context(), id, userID)
+	if e, ok := enrollmentError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response := gin.H{"message": tr(c, "Inscription annulée avec succès")}
+	if promoted != nil {
+		response["promoted"] = promoted
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// getWaitlist liste les usagers en attente d'une place dans un cours
+// GET /api/courses/:id/waitlist
+func (s *Server) getWaitlist(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	entries, err := s.courses.ListWaitlist(c.Request.Context(), id)
+	if e, ok := enrollmentError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"waitlist": entries})
+}
+
+// reorderWaitlist change l'ordre de promotion de la liste d'attente
+// PUT /api/courses/:id/waitlist
+func (s *Server) reorderWaitlist(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req WaitlistOrderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	entries, err := s.courses.ReorderWaitlist(c.Request.Context(), id, req.UserIDs)
+	if e, ok := enrollmentError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"waitlist": entries})
+}
+
+// deleteWaitlistEntry retire un usager de la liste d'attente
+// DELETE /api/courses/:id/waitlist/:userId
+func (s *Server) deleteWaitlistEntry(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		abortInvalidID(c, "ID d'usager invalide")
+		return
+	}
+
+	err = s.courses.RemoveFromWaitlist(c.Request.Context(), id, userID)
+	if e, ok := enrollmentError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Usager retiré de la liste d'attente")})
+}
+
+// enrollmentError associe les erreurs métier des inscriptions à une réponse
+func enrollmentError(err error) (apiError, bool) {
+	switch {
+	case errors.Is(err, ErrCourseNotFound):
+		return newAPIError(http.StatusNotFound, CodeCourseNotFound, "Cours non trouvé"), true
+	case errors.Is(err, ErrUserNotFound):
+		return newAPIError(http.StatusNotFound, CodeUserNotFound, "Usager non trouvé"), true
+	case errors.Is(err, ErrEnrollmentNotFound):
+		return newAPIError(http.StatusNotFound, CodeEnrollmentNotFound, "Inscription non trouvée"), true
+	case errors.Is(err, ErrAlreadyEnrolled):
+		return newAPIError(http.StatusConflict, CodeAlreadyEnrolled, "L'usager est déjà inscrit à ce cours"), true
+	case errors.Is(err, ErrAlreadyWaitlisted):
+		return newAPIError(http.StatusConflict, CodeAlreadyWaitlisted, "L'usager est déjà en liste d'attente pour ce cours"), true
+	case errors.Is(err, ErrWaitlistEntryNotFound):
+		return newAPIError(http.StatusNotFound, CodeWaitlistEntryNotFound, "Usager absent de la liste d'attente"), true
+	case errors.Is(err, ErrInvalidWaitlistOrder):
+		return newAPIError(http.StatusBadRequest, CodeInvalidWaitlistOrder, "Le nouvel ordre doit contenir exactement les usagers en liste d'attente"), true
+	case errors.Is(err, ErrLevelMismatch):
+		return newAPIError(http.StatusUnprocessableEntity, CodeLevelMismatch, "Le niveau de natation de l'usager ne correspond pas au niveau du cours"), true
+	}
+	return apiError{}, false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getEvaluations retourne l'historique des évaluations et de la progression d'un usager
+// GET /api/users/:id/evaluations
+func (s *Server) getEvaluations(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	evaluations, err := s.evaluations.ListEvaluations(c.Request.Context(), id)
+	if errors.Is(err, ErrUserNotFound) {
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"evaluations": evaluations})
+}
+
+// createEvaluation enregistre une évaluation; si elle est réussie, l'usager passe au niveau suivant
+// si son âge convient à ce niveau (politique d'âge)
+// POST /api/users/:id/evaluations
+func (s *Server) createEvaluation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req EvaluationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validateEvaluationDate("evaluated_on", req.EvaluatedOn, time.Now()); len(fields) > 0 {
+		abortValidation(c, fields...)
+		return
+	}
+
+	// L'âge pour le niveau suivant est vérifié par CreateEvaluation, dans la transaction de la progression
+	evaluation, err := s.evaluations.CreateEvaluation(c.Request.Context(), id, req)
+	var ageErr *AgeMismatchError
+	switch {
+	case errors.As(err, &ageErr):
+		abortAgeMismatch(c, ageErr.Mismatch)
+		return
+	case errors.Is(err, ErrUserNotFound):
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+		return
+	case errors.Is(err, ErrEvaluationLevelMismatch):
+		abortError(c, http.StatusUnprocessableEntity, CodeLevelMismatch, "L'évaluation doit porter sur le niveau actuel de l'usager")
+		return
+	case err != nil:
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, evaluation)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getGuardians liste les tuteurs, avec recherche optionnelle sur le nom ou l'email
+// GET /api/guardians
+func (s *Server) getGuardians(c *gin.Context) {
+	guardians, err := s.guardians.ListGuardians(c.Request.Context(), c.Query("search"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"guardians": guardians})
+}
+
+// getGuardianByID récupère un tuteur par son ID
+// GET /api/guardians/:id
+func (s *Server) getGuardianByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	g, err := s.guardians.GetGuardian(c.Request.Context(), id)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, g)
+}
+
+// createGuardian crée un nouveau tuteur
+// POST /api/guardians
+func (s *Server) createGuardian(c *gin.Context) {
+	var req GuardianRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validatePersonFields(&req.FirstName, &req.LastName, &req.Email); len(fields) > 0 {
+		abortValidation(c, fields...)
+		return
+	}
+
+	g, err := s.guardians.CreateGuardian(c.Request.Context(), req)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, g)
+}
+
+// updateGuardian modifie un tuteur existant
+// PUT /api/guardians/:id
+func (s *Server) updateGuardian(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req GuardianRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validatePersonFields(&req.FirstName, &req.LastName, &req.Email); len(fields) > 0 {
+		abortValidation(c, fields...)
+		return
+	}
+
+	g, err := s.guardians.UpdateGuardian(c.Request.Context(), id, req)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, g)
+}
+
+// deleteGuardian supprime un tuteur; les usagers rattachés sont conservés
+// DELETE /api/guardians/:id
+func (s *Server) deleteGuardian(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	err = s.guardians.DeleteGuardian(c.Request.Context(), id)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Tuteur supprimé avec succès")})
+}
+
+// getGuardianChildren liste les usagers rattachés à un tuteur (le foyer)
+// GET /api/guardians/:id/children
+func (s *Server) getGuardianChildren(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	children, err := s.guardians.ListChildren(c.Request.Context(), id)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"children": children})
+}
+
+// linkGuardianChild rattache un usager à un tuteur
+// POST /api/guardians/:id/children
+func (s *Server) linkGuardianChild(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req GuardianLinkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	child, err := s.guardians.LinkChild(c.Request.Context(), id, req)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, child)
+}
+
+// unlinkGuardianChild détache un usager d'un tuteur
+// DELETE /api/guardians/:id/children/:userId
+func (s *Server) unlinkGuardianChild(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		abortInvalidID(c, "ID d'usager invalide")
+		return
+	}
+
+	err = s.guardians.UnlinkChild(c.Request.Context(), id, userID)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Usager détaché du tuteur")})
+}
+
+// getUserGuardians liste les tuteurs d'un usager
+// GET /api/users/:id/guardians
+func (s *Server) getUserGuardians(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	guardians, err := s.guardians.ListUserGuardians(c.Request.Context(), id)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"guardians": guardians})
+}
+
+// guardianError associe les erreurs métier des tuteurs à une réponse
+func guardianError(err error) (apiError, bool) {
+	switch {
+	case errors.Is(err, ErrGuardianNotFound):
+		return newAPIError(http.StatusNotFound, CodeGuardianNotFound, "Tuteur non trouvé"), true
+	case errors.Is(err, ErrUserNotFound):
+		return newAPIError(http.StatusNotFound, CodeUserNotFound, "Usager non trouvé"), true
+	case errors.Is(err, ErrLinkNotFound):
+		return newAPIError(http.StatusNotFound, CodeLinkNotFound, "L'usager n'est pas rattaché à ce tuteur"), true
+	case errors.Is(err, ErrAlreadyLinked):
+		return newAPIError(http.StatusConflict, CodeAlreadyLinked, "L'usager est déjà rattaché à ce tuteur"), true
+	case errors.Is(err, ErrDuplicateEmail):
+		return newAPIError(http.StatusConflict, CodeDuplicateEmail, "Un autre tuteur utilise déjà cet email"), true
+	}
+	return apiError{}, false
+}
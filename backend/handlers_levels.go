@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserAgeMismatch associe un usager à l'incompatibilité entre son âge et son niveau
+type UserAgeMismatch struct {
+	User     User        `json:"user"`
+	Mismatch AgeMismatch `json:"mismatch"`
+}
+
+// getLevels retourne le catalogue des programmes et niveaux de natation, avec les tranches d'âge en vigueur
+// et les libellés dans la langue de la requête (name)
+// GET /api/levels
+func (s *Server) getLevels(c *gin.Context) {
+	lang := languageOf(c)
+	c.JSON(http.StatusOK, LevelsResponse{Programs: localizePrograms(lang), Levels: localizeLevels(lang, s.ages.levels())})
+}
+
+// getAgeMismatches liste les usagers dont l'âge ne correspond plus à leur niveau
+// GET /api/users/age-mismatches?date=YYYY-MM-DD (défaut: aujourd'hui)
+func (s *Server) getAgeMismatches(c *gin.Context) {
+	ref := time.Now()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			abortError(c, http.StatusBadRequest, CodeInvalidDate, "Date invalide (format YYYY-MM-DD)")
+			return
+		}
+		ref = parsed
+	}
+
+	// Parcourir tous les usagers par pages
+	mismatches := []UserAgeMismatch{}
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		users, total, err := s.store.List(c.Request.Context(), UserFilter{Limit: pageSize, Offset: offset})
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		for _, u := range users {
+			if m := s.ages.check(u.NiveauNatation, u.DateNaissance, ref); m != nil {
+				mismatches = append(mismatches, UserAgeMismatch{User: u, Mismatch: *m})
+			}
+		}
+		if offset+pageSize >= total {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reference_date": ref.Format("2006-01-02"),
+		"users":          mismatches,
+		"total":          len(mismatches),
+	})
+}
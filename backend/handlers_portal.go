@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// portalLogin authentifie un tuteur et retourne ses jetons du portail parents
+// POST /api/portal/login
+func (s *Server) portalLogin(c *gin.Context) {
+	var req LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := s.auth.PortalLogin(c.Request.Context(), normalizeEmail(req.Email), req.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		abortError(c, http.StatusUnauthorized, CodeInvalidCredentials, "Email ou mot de passe invalide")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// portalRefresh échange un jeton de rafraîchissement du portail contre une nouvelle paire de jetons
+// POST /api/portal/refresh
+func (s *Server) portalRefresh(c *gin.Context) {
+	var req RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := s.auth.PortalRefresh(c.Request.Context(), req.RefreshToken)
+	if errors.Is(err, ErrInvalidToken) {
+		abortError(c, http.StatusUnauthorized, CodeInvalidToken, "Jeton de rafraîchissement invalide ou expiré")
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// portalLogout révoque le jeton d'accès courant du tuteur et, s'il est fourni, son jeton de rafraîchissement
+// POST /api/portal/logout
+func (s *Server) portalLogout(c *gin.Context) {
+	var req LogoutRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	guardian := c.MustGet(guardianContextKey).(Guardian)
+
+	if req.RefreshToken != "" {
+		owner, claims, err := s.auth.AuthenticateGuardian(ctx, req.RefreshToken, portalRefreshToken)
+		if err != nil && !errors.Is(err, ErrInvalidToken) {
+			respondError(c, err)
+			return
+		}
+		// Un jeton déjà invalide ou appartenant à un autre tuteur est ignoré
+		if err == nil && owner.ID == guardian.ID {
+			if err := s.auth.Revoke(ctx, claims); err != nil {
+				respondError(c, err)
+				return
+			}
+		}
+	}
+
+	if err := s.auth.Revoke(ctx, c.MustGet(claimsContextKey).(*tokenClaims)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Déconnexion réussie")})
+}
+
+// getPortalGuardian retourne le tuteur authentifié
+// GET /api/portal/me
+func (s *Server) getPortalGuardian(c *gin.Context) {
+	c.JSON(http.StatusOK, c.MustGet(guardianContextKey).(Guardian))
+}
+
+// changePortalPassword remplace le mot de passe du tuteur authentifié.
+// Tous ses jetons sont révoqués : le tuteur doit se reconnecter.
+// PUT /api/portal/password
+func (s *Server) changePortalPassword(c *gin.Context) {
+	var req PortalPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	ctx := c.Request.Context()
+	guardian := c.MustGet(guardianContextKey).(Guardian)
+
+	acc, err := s.auth.portal.GetGuardianAccountByID(ctx, guardian.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !s.auth.checkPassword(acc.PasswordHash, req.CurrentPassword) {
+		abortValidation(c, fieldError("current_password", "invalid_password", "Mot de passe actuel invalide"))
+		return
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		abortValidation(c, passwordPolicyError("new_password"))
+		return
+	}
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := s.auth.portal.SetGuardianPassword(ctx, guardian.ID, hash); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Mot de passe modifié, veuillez vous reconnecter")})
+}
+
+// getPortalChildren liste les usagers rattachés au tuteur authentifié
+// GET /api/portal/children
+func (s *Server) getPortalChildren(c *gin.Context) {
+	guardian := c.MustGet(guardianContextKey).(Guardian)
+
+	children, err := s.guardians.ListChildren(c.Request.Context(), guardian.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"children": children})
+}
+
+// updatePortalChild modifie les coordonnées d'un enfant du tuteur authentifié.
+// La date de naissance et le niveau de natation sont conservés.
+// PUT /api/portal/children/:id
+func (s *Server) updatePortalChild(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req PortalContactRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if fields := validateUserFields(&req.FirstName, &req.LastName, &req.Email); len(fields) > 0 {
+		abortValidation(c, fields...)
+		return
+	}
+
+	s.saveUser(c, id, func(current User) (UserRequest, bool) {
+		return UserRequest{
+			FirstName:      req.FirstName,
+			LastName:       req.LastName,
+			Email:          req.Email,
+			DateNaissance:  current.DateNaissance,
+			NiveauNatation: current.NiveauNatation,
+		}, true
+	})
+}
+
+// getPortalChildCourses liste les cours auxquels est inscrit un enfant du tuteur authentifié
+// GET /api/portal/children/:id/courses
+func (s *Server) getPortalChildCourses(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	courses, err := s.courses.ListCourses(c.Request.Context(), CourseFilter{UserID: id})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"courses": courses})
+}
+
+// setPortalAccess donne (ou remplace) le mot de passe du portail parents d'un tuteur.
+// Les jetons déjà émis pour ce tuteur sont révoqués.
+// PUT /api/guardians/:id/portal-access
+func (s *Server) setPortalAccess(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	var req PortalAccessRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := validatePassword(req.Password); err != nil {
+		abortValidation(c, passwordPolicyError("password"))
+		return
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	err = s.auth.portal.SetGuardianPassword(c.Request.Context(), id, hash)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Accès au portail parents accordé")})
+}
+
+// revokePortalAccess retire l'accès au portail parents d'un tuteur et révoque ses jetons
+// DELETE /api/guardians/:id/portal-access
+func (s *Server) revokePortalAccess(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		abortInvalidID(c, "ID invalide")
+		return
+	}
+
+	err = s.auth.portal.RevokeGuardianAccess(c.Request.Context(), id)
+	if e, ok := guardianError(err); ok {
+		abortAPIError(c, e)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": tr(c, "Accès au portail parents retiré")})
+}
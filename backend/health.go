@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout borne la durée totale des vérifications de /readyz
+const readinessTimeout = 2 * time.Second
+
+// readinessCheck est une vérification de /readyz. En cas d'échec, message est renvoyé au client
+// et l'erreur détaillée (qui peut contenir des adresses internes) est journalisée.
+type readinessCheck struct {
+	name    string
+	message string
+	check   func(ctx context.Context) error
+}
+
+// readinessChecks retourne les vérifications disponibles selon le store et la configuration
+func (s *Server) readinessChecks() []readinessCheck {
+	var checks []readinessCheck
+	if s.health != nil {
+		checks = append(checks,
+			readinessCheck{"database", "base de données injoignable", s.health.Ping},
+			readinessCheck{"migrations", "migrations en attente ou modifiées (voir ./main migrate status)", s.health.CheckMigrations},
+		)
+	}
+	if s.dataDir != "" {
+		checks = append(checks, readinessCheck{"disk", "répertoire des données non accessible en écriture", func(context.Context) error {
+			return checkWritable(s.dataDir)
+		}})
+	}
+	return checks
+}
+
+// checkWritable vérifie qu'un fichier peut être créé et écrit dans le répertoire
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("ok"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// getHealth indique que le processus répond (liveness), sans vérifier ses dépendances
+// GET /healthz
+func (s *Server) getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// getReadiness vérifie que le serveur peut traiter des requêtes : base de données joignable,
+// migrations appliquées et répertoire des données accessible en écriture. Répond 503 si une vérification échoue.
+// GET /readyz
+func (s *Server) getReadiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	resp := HealthResponse{Status: "ok", Components: map[string]ComponentHealth{}}
+	status := http.StatusOK
+	for _, rc := range s.readinessChecks() {
+		start := time.Now()
+		err := rc.check(ctx)
+		component := ComponentHealth{Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+		if err != nil {
+			loggerFrom(ctx).Warn("Vérification de disponibilité échouée", "check", rc.name, "error", err)
+			component.Status, component.Error = "error", tr(c, rc.message)
+			resp.Status, status = "error", http.StatusServiceUnavailable
+		}
+		resp.Components[rc.name] = component
+	}
+	c.JSON(status, resp)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getReadiness interroge /readyz et décode la réponse
+func getReadiness(t *testing.T, r http.Handler) (int, HealthResponse) {
+	w := performRequest(r, "GET", "/readyz", nil)
+	var resp HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	return w.Code, resp
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	r, store, _ := setupAuthRouter(t)
+
+	// Les sondes ne demandent pas de jeton
+	w := performRequest(r, "GET", "/healthz", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+
+	code, resp := getReadiness(t, r)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", resp.Status)
+	assert.Equal(t, "ok", resp.Components["database"].Status)
+	assert.Equal(t, "ok", resp.Components["migrations"].Status)
+	assert.NotContains(t, resp.Components, "disk")
+
+	s := newServer(store)
+	s.dataDir = t.TempDir()
+	code, resp = getReadiness(t, setupRouterWithServer(s))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", resp.Components["disk"].Status)
+}
+
+func TestReadinessFailures(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	s := newServer(newSQLiteStore(testDB))
+	s.dataDir = filepath.Join(t.TempDir(), "absent")
+	r := setupRouterWithServer(s)
+
+	code, resp := getReadiness(t, r)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "error", resp.Status)
+	assert.Equal(t, "ok", resp.Components["database"].Status)
+	assert.Equal(t, "error", resp.Components["disk"].Status)
+	assert.NotEmpty(t, resp.Components["disk"].Error)
+
+	// Une migration annulée est signalée
+	s.dataDir = t.TempDir()
+	m, err := newMigrator(testDB, sqliteDialect)
+	require.NoError(t, err)
+	_, err = m.Down(context.Background(), 1)
+	require.NoError(t, err)
+	code, resp = getReadiness(t, r)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "error", resp.Components["migrations"].Status)
+	assert.Equal(t, "ok", resp.Components["disk"].Status)
+
+	// Base de données fermée : le détail de l'erreur n'est pas renvoyé
+	testDB.Close()
+	code, resp = getReadiness(t, r)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "error", resp.Components["database"].Status)
+	assert.Equal(t, "base de données injoignable", resp.Components["database"].Error)
+
+	// /healthz ne dépend pas de la base
+	w := performRequest(r, "GET", "/healthz", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHealthcheckCommand(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	ts := httptest.NewServer(setupRouter(testDB))
+	defer ts.Close()
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	require.NoError(t, err)
+
+	// Une adresse d'écoute sans hôte interroge 127.0.0.1
+	var out bytes.Buffer
+	require.NoError(t, runHealthcheckCommand(":"+port, &out))
+	assert.Contains(t, out.String(), `"status":"ok"`)
+
+	testDB.Close()
+	assert.Error(t, runHealthcheckCommand("0.0.0.0:"+port, &out))
+}
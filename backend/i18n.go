@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Langues des messages de l'API. Les messages sont écrits en français dans le code
+// et traduits à l'aide des catalogues (voir messages_en.go).
+const (
+	langFrench  = "fr"
+	langEnglish = "en"
+)
+
+// languageContextKey est la clé de la langue négociée dans le contexte gin
+const languageContextKey = "language"
+
+// languageMatcher choisit la langue d'après Accept-Language; le français est la langue par défaut
+var languageMatcher = language.NewMatcher([]language.Tag{language.French, language.English})
+
+// catalogs associe à chaque langue autre que le français la traduction des messages français
+var catalogs = map[string]map[string]string{
+	langEnglish: englishMessages,
+}
+
+// negotiateLanguage retourne la langue des messages d'après l'en-tête Accept-Language
+// (ex: "en-CA,en;q=0.9,fr;q=0.8" -> en). Une langue non prise en charge donne le français.
+func negotiateLanguage(acceptLanguage string) string {
+	tag, _ := language.MatchStrings(languageMatcher, acceptLanguage)
+	if base, _ := tag.Base(); base.String() == langEnglish {
+		return langEnglish
+	}
+	return langFrench
+}
+
+// negotiatedLanguage négocie la langue de la requête et l'indique dans la réponse (Content-Language).
+// Vary: Accept-Language évite qu'un cache serve une réponse dans une autre langue.
+func negotiatedLanguage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := negotiateLanguage(c.GetHeader("Accept-Language"))
+		c.Set(languageContextKey, lang)
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// languageOf retourne la langue de la requête, négociée par le middleware ou à défaut d'après Accept-Language
+func languageOf(c *gin.Context) string {
+	if lang := c.GetString(languageContextKey); lang != "" {
+		return lang
+	}
+	return negotiateLanguage(c.GetHeader("Accept-Language"))
+}
+
+// translate traduit un message écrit en français dans la langue demandée, puis le formate avec args
+// (fmt.Sprintf). Un message absent du catalogue est retourné en français.
+func translate(lang, msg string, args ...any) string {
+	if translated, ok := catalogs[lang][msg]; ok {
+		msg = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// tr traduit un message dans la langue de la requête
+func tr(c *gin.Context, msg string, args ...any) string {
+	return translate(languageOf(c), msg, args...)
+}
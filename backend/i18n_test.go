@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// translatedArgs indique, pour chaque fonction qui traduit un message, la position du message
+var translatedArgs = map[string]int{
+	"tr":          1,
+	"translate":   1,
+	"abortError":  3,
+	"newAPIError": 2,
+	"fieldError":  2,
+}
+
+// sourceMessages retourne les messages français du code : arguments traduits des fonctions ci-dessus,
+// Detail des Problem, messages des vérifications de /readyz et chaînes retournées par les fonctions de validation
+func sourceMessages(t *testing.T) []string {
+	files, err := filepath.Glob("*.go")
+	require.NoError(t, err)
+	seen := map[string]bool{}
+	add := func(e ast.Expr) {
+		if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			msg, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err)
+			seen[msg] = true
+		}
+	}
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, 0)
+		require.NoError(t, err)
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if id, ok := n.Fun.(*ast.Ident); ok {
+					if i, ok := translatedArgs[id.Name]; ok && i < len(n.Args) {
+						add(n.Args[i])
+					}
+				}
+			case *ast.CompositeLit:
+				switch typ, _ := n.Type.(*ast.Ident); {
+				case typ != nil && typ.Name == "Problem":
+					for _, elt := range n.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok && kv.Key.(*ast.Ident).Name == "Detail" {
+							add(kv.Value)
+						}
+					}
+				case typ != nil && typ.Name == "readinessCheck" && len(n.Elts) > 1:
+					add(n.Elts[1])
+				}
+			case *ast.FuncDecl:
+				if n.Name.Name == "validationMessage" || n.Name.Name == "jsonTypeName" {
+					ast.Inspect(n.Body, func(n ast.Node) bool {
+						if ret, ok := n.(*ast.ReturnStmt); ok && len(ret.Results) > 0 {
+							add(ret.Results[0])
+						}
+						return true
+					})
+				}
+			}
+			return true
+		})
+	}
+
+	for _, p := range programs {
+		seen[p.Label] = true
+	}
+	for _, l := range levels {
+		seen[l.series] = true
+	}
+
+	var messages []string
+	for msg := range seen {
+		messages = append(messages, msg)
+	}
+	sort.Strings(messages)
+	return messages
+}
+
+var formatVerb = regexp.MustCompile(`%[-+# 0]*[0-9]*[a-zA-Z%]`)
+
+func TestEnglishCatalogComplete(t *testing.T) {
+	messages := sourceMessages(t)
+	require.NotEmpty(t, messages)
+	used := map[string]bool{}
+	for _, msg := range messages {
+		used[msg] = true
+		translated, ok := englishMessages[msg]
+		if !assert.True(t, ok, "traduction anglaise manquante : %q", msg) {
+			continue
+		}
+		// La traduction attend les mêmes arguments, dans le même ordre
+		assert.Equal(t, formatVerb.FindAllString(msg, -1), formatVerb.FindAllString(translated, -1), msg)
+	}
+	for msg := range englishMessages {
+		assert.True(t, used[msg], "traduction inutilisée : %q", msg)
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                              langFrench,
+		"en":                            langEnglish,
+		"en-CA,en;q=0.9,fr-CA;q=0.8":    langEnglish,
+		"fr-CA,fr;q=0.9,en;q=0.8":       langFrench,
+		"de-DE,en;q=0.5":                langEnglish,
+		"de-DE":                         langFrench,
+		"es, fr;q=0.3, en;q=0.7":        langEnglish,
+		"en;q=0, fr":                    langFrench,
+		"*":                             langFrench,
+		"n'importe quoi;q=pas un poids": langFrench,
+	}
+	for header, want := range tests {
+		assert.Equal(t, want, negotiateLanguage(header), header)
+	}
+	assert.Equal(t, "Swimmer 3 and Preschool", translate(langEnglish, "Nageur")+" 3 and "+translate(langEnglish, "Préscolaire"))
+	assert.Equal(t, "Message inconnu 3", translate(langEnglish, "Message inconnu %d", 3))
+}
+
+func TestLocalizedResponses(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	request := func(method, path, lang string, body interface{}) (int, http.Header, Problem) {
+		w := performRequestWithHeaders(r, method, path, map[string]string{"Accept-Language": lang}, body)
+		var p Problem
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return w.Code, w.Header(), p
+	}
+
+	// Messages des handlers
+	status, header, p := request("GET", "/api/users/999", "en-US,en;q=0.9", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "User not found", p.Detail)
+	assert.Equal(t, "en", header.Get("Content-Language"))
+	assert.Contains(t, header.Values("Vary"), "Accept-Language")
+	_, header, p = request("GET", "/api/users/999", "fr-CA", nil)
+	assert.Equal(t, "Usager non trouvé", p.Detail)
+	assert.Equal(t, "fr", header.Get("Content-Language"))
+
+	// Erreurs de validation, y compris celles du validateur et celles de l'API
+	invalid := UserRequest{LastName: "Dupont", Email: "pas-un-email", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 12"}
+	status, _, p = request("POST", "/api/users", "en", invalid)
+	require.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "One or more fields are invalid", p.Detail)
+	messages := map[string]string{}
+	for _, fe := range p.Errors {
+		messages[fe.Field] = fe.Message
+	}
+	assert.Equal(t, "This field is required", messages["first_name"])
+	assert.Equal(t, "Invalid email address", messages["email"])
+	invalid.FirstName = "Jean"
+	invalid.Email = "jean@test.com"
+	_, _, p = request("POST", "/api/users", "en", invalid)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "Unknown swimming level: NAGEUR 12", p.Errors[0].Message)
+
+	// Messages de succès
+	invalid.NiveauNatation = "NAGEUR 3"
+	w := performRequest(r, "POST", "/api/users", invalid)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	w = performRequestWithHeaders(r, "DELETE", "/api/users/"+strconv.Itoa(u.ID), map[string]string{"Accept-Language": "en"}, nil)
+	assert.JSONEq(t, `{"message": "User deleted successfully"}`, w.Body.String())
+
+	// Libellés du catalogue des niveaux; le libellé enregistré (label) ne change pas
+	var catalog LevelsResponse
+	w = performRequestWithHeaders(r, "GET", "/api/levels", map[string]string{"Accept-Language": "en"}, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &catalog))
+	level, _ := findLevel("NAGEUR_3")
+	assert.Equal(t, "NAGEUR 3", catalog.Levels[level.Order-1].Label)
+	assert.Equal(t, "Swimmer 3", catalog.Levels[level.Order-1].Name)
+	assert.Equal(t, "Preschool", catalog.Programs[1].Name)
+	w = performRequest(r, "GET", "/api/levels", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &catalog))
+	assert.Equal(t, "Nageur 3", catalog.Levels[level.Order-1].Name)
+	assert.Equal(t, "Préscolaire", catalog.Programs[1].Name)
+}
+
+// performRequestWithHeaders exécute une requête avec les en-têtes fournis
+func performRequestWithHeaders(r http.Handler, method, path string, headers map[string]string, body interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		jsonData, _ := json.Marshal(body)
+		reader = bytes.NewReader(jsonData)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,167 @@
+package main
+
+import "strconv"
+
+// Program regroupe des niveaux de natation destinés à une même tranche d'âge
+type Program struct {
+	Code   string `json:"code"`    // ex: "PRESCOLAIRE"
+	Label  string `json:"label"`   // ex: "Préscolaire"
+	Name   string `json:"name"`    // Libellé dans la langue de la requête, ex: "Preschool"
+	MinAge int    `json:"min_age"` // Âge minimum en années
+	MaxAge *int   `json:"max_age"` // Âge maximum en années (nil: pas de limite)
+}
+
+// Level représente un niveau de natation du catalogue
+type Level struct {
+	Code    string `json:"code"`    // ex: "NAGEUR_3"
+	Label   string `json:"label"`   // Valeur enregistrée dans niveau_natation, ex: "NAGEUR 3"
+	Name    string `json:"name"`    // Libellé dans la langue de la requête, ex: "Swimmer 3"
+	Program string `json:"program"` // Code du programme
+	Order   int    `json:"order"`   // Rang dans la progression, à partir de 1
+	MinAge  int    `json:"min_age"`
+	MaxAge  *int   `json:"max_age"`
+
+	series string // Nom de la série, ex: "Nageur"
+	number int    // Numéro dans la série
+}
+
+// LevelsResponse représente la réponse de GET /api/levels
+type LevelsResponse struct {
+	Programs []Program `json:"programs"`
+	Levels   []Level   `json:"levels"`
+}
+
+// programs liste les programmes dans l'ordre de progression
+var programs = []Program{
+	{Code: "PARENT_ET_ENFANT", Label: "Parent et Enfant", MinAge: 0, MaxAge: intPtr(3)},
+	{Code: "PRESCOLAIRE", Label: "Préscolaire", MinAge: 3, MaxAge: intPtr(5)},
+	{Code: "NAGEUR", Label: "Nageur", MinAge: 5},
+	{Code: "JEUNE_SAUVETEUR", Label: "Jeune sauveteur", MinAge: 8},
+}
+
+// levelRange décrit une série de niveaux numérotés d'un programme (ex: NAGEUR 1 à 6)
+type levelRange struct {
+	program  string
+	prefix   string // Préfixe du libellé enregistré
+	series   string // Nom de la série, traduit dans le libellé affiché
+	from, to int
+}
+
+// levels est le catalogue des niveaux, dans l'ordre de progression
+var levels = buildLevels([]levelRange{
+	{"PARENT_ET_ENFANT", "PARENT ET ENFANT", "Parent et Enfant", 1, 3},
+	{"PRESCOLAIRE", "PRÉSCOLAIRE", "Préscolaire", 1, 5},
+	{"NAGEUR", "NAGEUR", "Nageur", 1, 6},
+	{"JEUNE_SAUVETEUR", "NAGEUR", "Nageur", 7, 9},
+})
+
+// buildLevels génère les niveaux de chaque série avec les âges de leur programme
+func buildLevels(ranges []levelRange) []Level {
+	var result []Level
+	for _, r := range ranges {
+		p, _ := findProgram(r.program)
+		for n := r.from; n <= r.to; n++ {
+			label := r.prefix + " " + strconv.Itoa(n)
+			result = append(result, Level{
+				Code:    levelCode(label),
+				Label:   label,
+				Program: p.Code,
+				Order:   len(result) + 1,
+				MinAge:  p.MinAge,
+				MaxAge:  p.MaxAge,
+				series:  r.series,
+				number:  n,
+			})
+		}
+	}
+	return result
+}
+
+// levelCode dérive le code d'un niveau de son libellé (ex: "PRÉSCOLAIRE 2" -> "PRESCOLAIRE_2")
+func levelCode(label string) string {
+	code := make([]rune, 0, len(label))
+	for _, r := range label {
+		switch r {
+		case ' ':
+			r = '_'
+		case 'É':
+			r = 'E'
+		}
+		code = append(code, r)
+	}
+	return string(code)
+}
+
+// localizePrograms retourne les programmes avec leur libellé dans la langue
+func localizePrograms(lang string) []Program {
+	result := make([]Program, len(programs))
+	for i, p := range programs {
+		p.Name = translate(lang, p.Label)
+		result[i] = p
+	}
+	return result
+}
+
+// localizeLevels complète le libellé des niveaux dans la langue (ex: "Nageur 3", "Swimmer 3")
+func localizeLevels(lang string, levels []Level) []Level {
+	for i, l := range levels {
+		levels[i].Name = translate(lang, l.series) + " " + strconv.Itoa(l.number)
+	}
+	return levels
+}
+
+// intPtr retourne un pointeur vers n
+func intPtr(n int) *int {
+	return &n
+}
+
+// findProgram retourne le programme correspondant au code
+func findProgram(code string) (Program, bool) {
+	for _, p := range programs {
+		if p.Code == code {
+			return p, true
+		}
+	}
+	return Program{}, false
+}
+
+// findLevel retourne le niveau correspondant au code ou au libellé
+func findLevel(value string) (Level, bool) {
+	for _, l := range levels {
+		if l.Code == value || l.Label == value {
+			return l, true
+		}
+	}
+	return Level{}, false
+}
+
+// nextLevel retourne le libellé du niveau qui suit level dans la progression.
+// Retourne false si level est inconnu ou s'il s'agit du dernier niveau.
+func nextLevel(level string) (string, bool) {
+	l, ok := findLevel(level)
+	if !ok || l.Order >= len(levels) {
+		return "", false
+	}
+	return levels[l.Order].Label, true
+}
+
+// resolveLevelFilter convertit un code de niveau, un libellé ou un code de programme
+// en liste de libellés. Une valeur inconnue est conservée telle quelle.
+func resolveLevelFilter(value string) []string {
+	if value == "" {
+		return nil
+	}
+	if l, ok := findLevel(value); ok {
+		return []string{l.Label}
+	}
+	if _, ok := findProgram(value); ok {
+		var labels []string
+		for _, l := range levels {
+			if l.Program == value {
+				labels = append(labels, l.Label)
+			}
+		}
+		return labels
+	}
+	return []string{value}
+}
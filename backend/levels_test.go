@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelCatalogue(t *testing.T) {
+	require.Len(t, levels, 17)
+	codes := map[string]bool{}
+	for i, l := range levels {
+		assert.Equal(t, i+1, l.Order)
+		assert.False(t, codes[l.Code], "code en double: %s", l.Code)
+		codes[l.Code] = true
+		_, ok := findProgram(l.Program)
+		assert.True(t, ok, "programme inconnu: %s", l.Program)
+	}
+
+	l, ok := findLevel("PRESCOLAIRE_2")
+	require.True(t, ok)
+	assert.Equal(t, "PRÉSCOLAIRE 2", l.Label)
+	l, ok = findLevel("NAGEUR 8")
+	require.True(t, ok)
+	assert.Equal(t, "JEUNE_SAUVETEUR", l.Program)
+	assert.Equal(t, 8, l.MinAge)
+	_, ok = findLevel("NAGEUR 10")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"NAGEUR 3"}, resolveLevelFilter("NAGEUR_3"))
+	assert.Equal(t, []string{"NAGEUR 7", "NAGEUR 8", "NAGEUR 9"}, resolveLevelFilter("JEUNE_SAUVETEUR"))
+	assert.Equal(t, []string{"inconnu"}, resolveLevelFilter("inconnu"))
+	assert.Nil(t, resolveLevelFilter(""))
+}
+
+func TestGetLevels(t *testing.T) {
+	r := setupRouterWithStore(newMemoryStore())
+
+	w := performRequest(r, "GET", "/api/levels", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response LevelsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Programs, 4)
+	assert.Len(t, response.Levels, 17)
+	assert.Equal(t, "PARENT_ET_ENFANT_1", response.Levels[0].Code)
+	require.NotNil(t, response.Programs[1].MaxAge)
+	assert.Equal(t, 5, *response.Programs[1].MaxAge)
+}
+
+func TestCreateUserLevelValidation(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	req := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 12"}
+	w := performRequest(r, "POST", "/api/users", req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// Un code de niveau est accepté et enregistré sous son libellé
+	req.NiveauNatation = "NAGEUR_3"
+	w = performRequest(r, "POST", "/api/users", req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "NAGEUR 3", u.NiveauNatation)
+
+	req.NiveauNatation = "Nageur 4"
+	w = performRequest(r, "PUT", "/api/users/"+strconv.Itoa(u.ID), req)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = performRequest(r, "POST", "/api/courses", testCourseRequest("NAGEUR 0", 8))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestGetUsersWithProgramFilter(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	insertTestUser(t, testDB, "n3@test.com", "NAGEUR 3")
+	insertTestUser(t, testDB, "n8@test.com", "NAGEUR 8")
+	insertTestUser(t, testDB, "n9@test.com", "NAGEUR 9")
+
+	var response UsersResponse
+	w := performRequest(r, "GET", "/api/users?filter_niveau=JEUNE_SAUVETEUR", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Total)
+
+	w = performRequest(r, "GET", "/api/users?filter_niveau=NAGEUR_3", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Total)
+}
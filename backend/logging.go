@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader transporte l'identifiant de la requête, reçu du client ou du proxy, ou généré
+const requestIDHeader = "X-Request-ID"
+
+// requestIDContextKey est la clé de l'identifiant de la requête dans le contexte gin
+const requestIDContextKey = "request_id"
+
+// requestIDKey est la clé de l'identifiant de la requête dans le contexte de la requête (context.Context)
+type requestIDKey struct{}
+
+// requestIDRe limite les identifiants acceptés du client, pour ne pas polluer les logs
+var requestIDRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// newLogger crée le logger du serveur au niveau (debug, info, warn, error) et au format (json, text) demandés
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("niveau de log invalide: %q (debug, info, warn ou error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("format de log invalide: %q (json ou text)", format)
+}
+
+// fatal journalise l'erreur et arrête le processus (remplace log.Fatal)
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// requestID attribue un identifiant à chaque requête : l'en-tête X-Request-ID s'il est valide, sinon un
+// identifiant aléatoire. L'identifiant est renvoyé dans la réponse et ajouté à chaque log de la requête.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !requestIDRe.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDContextKey, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// newRequestID génère un identifiant aléatoire de 128 bits
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// loggerFrom retourne le logger du serveur, avec l'identifiant de la requête du contexte s'il y en a un
+func loggerFrom(ctx context.Context) *slog.Logger {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// accessLog journalise chaque requête une fois traitée : erreur pour les 5xx, avertissement pour les 4xx.
+// La requête (query string) n'est pas journalisée : elle peut contenir des noms ou des emails.
+func accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		loggerFrom(c.Request.Context()).LogAttrs(c.Request.Context(), level, "requête",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// recovery transforme une panique d'un handler en réponse 500, journalisée avec l'identifiant de la requête
+func recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		internalError(c, fmt.Errorf("panique: %v", recovered))
+	})
+}
+
+// internalError journalise l'erreur (souvent une erreur de la base de données) et répond 500 avec un
+// message générique : le détail reste côté serveur, l'identifiant de la requête permet de le retrouver.
+func internalError(c *gin.Context, err error) {
+	loggerFrom(c.Request.Context()).Error("erreur interne",
+		"error", err,
+		"method", c.Request.Method,
+		"route", c.FullPath(),
+	)
+	abortError(c, http.StatusInternalServerError, CodeInternal, "Erreur interne du serveur")
+}
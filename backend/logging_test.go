@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs remplace le logger par défaut par un logger JSON écrivant dans le buffer retourné
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "debug", "json")
+	require.NoError(t, err)
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logEntries décode les lignes JSON des logs capturés
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry), scanner.Text())
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// setupLoggingRouter crée un routeur avec les middlewares de logs de main
+func setupLoggingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(requestID(), accessLog(), recovery())
+	r.GET("/api/levels", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	r.GET("/api/users/:id", func(c *gin.Context) {
+		internalError(c, errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+	})
+	r.GET("/api/panique", func(c *gin.Context) { panic("index hors limites") })
+	return r
+}
+
+func TestNewLogger(t *testing.T) {
+	for _, tc := range []struct{ level, format string }{{"debug", "json"}, {"info", "text"}, {"WARN", "json"}, {"error", "text"}} {
+		_, err := newLogger(&bytes.Buffer{}, tc.level, tc.format)
+		assert.NoError(t, err, tc)
+	}
+	_, err := newLogger(&bytes.Buffer{}, "verbeux", "json")
+	assert.Error(t, err)
+	_, err = newLogger(&bytes.Buffer{}, "info", "xml")
+	assert.Error(t, err)
+}
+
+func TestRequestID(t *testing.T) {
+	captureLogs(t)
+	r := setupLoggingRouter()
+	request := func(id string) string {
+		req := httptest.NewRequest("GET", "/api/levels", nil)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Header().Get(requestIDHeader)
+	}
+
+	// L'identifiant du client (ou du proxy) est conservé s'il est valide
+	assert.Equal(t, "proxy-1234.abcd", request("proxy-1234.abcd"))
+
+	// Sinon, un identifiant est généré
+	generated := request("")
+	assert.Regexp(t, `^[0-9a-f]{32}$`, generated)
+	assert.NotEqual(t, generated, request(""))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, request("id avec espaces"))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, request(strings.Repeat("a", 129)))
+}
+
+func TestInternalErrorLogging(t *testing.T) {
+	logs := captureLogs(t)
+	r := setupLoggingRouter()
+
+	req := httptest.NewRequest("GET", "/api/users/42?search=jean@test.com", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Le client reçoit un message générique et l'identifiant de la requête, pas le détail de l'erreur
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Internal Server Error",
+		"status": 500,
+		"detail": "Erreur interne du serveur",
+		"instance": "/api/users/42",
+		"code": "internal_error",
+		"request_id": "req-42"
+	}`, w.Body.String())
+
+	// Le détail est journalisé, avec l'identifiant de la requête sur chaque ligne
+	entries := logEntries(t, logs)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "ERROR", entries[0]["level"])
+	assert.Equal(t, "req-42", entries[0]["request_id"])
+	assert.Contains(t, entries[0]["error"], "connection refused")
+	assert.Equal(t, "/api/users/:id", entries[0]["route"])
+
+	access := entries[1]
+	assert.Equal(t, "ERROR", access["level"])
+	assert.Equal(t, "req-42", access["request_id"])
+	assert.Equal(t, "/api/users/42", access["path"])
+	assert.EqualValues(t, 500, access["status"])
+	assert.NotContains(t, logs.String(), "jean@test.com")
+
+	// Une panique donne aussi une réponse 500 journalisée
+	logs.Reset()
+	w = performRequest(r, "GET", "/api/panique", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), w.Header().Get(requestIDHeader))
+	assert.Contains(t, logs.String(), "index hors limites")
+}
+
+func TestDatabaseErrorNotExposed(t *testing.T) {
+	logs := captureLogs(t)
+	testDB := setupTestDB(t)
+	r := setupRouter(testDB)
+	testDB.Close()
+
+	w := performRequest(r, "GET", "/api/users", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Erreur interne du serveur")
+	assert.NotContains(t, w.Body.String(), "database is closed")
+	assert.Contains(t, logs.String(), "database is closed")
+}
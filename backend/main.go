@@ -1,38 +1,222 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Initialiser la base de données
-	initDB()
-	defer db.Close()
+	// Configuration : drapeaux, puis environnement, puis fichier (-config ou CONFIG_FILE)
+	cfg, args, err := loadConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		fatal("Configuration invalide", err)
+	}
+
+	// Logs structurés (LOG_LEVEL, LOG_FORMAT); les messages du paquet log passent aussi par ce logger
+	logger, _ := newLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat) // Déjà validés par loadConfig
+	slog.SetDefault(logger)
 
-	// Créer le routeur Gin
-	r := gin.Default()
-	r.Use(setupCORS())
+	// Sous-commandes (ex: ./main migrate status)
+	if runCommand(cfg, args) {
+		return
+	}
+	if len(args) > 0 {
+		fatal("Sous-commande inconnue", fmt.Errorf("%s (migrate, staff, healthcheck)", args[0]))
+	}
+	if err := cfg.checkFrontend(); err != nil {
+		fatal("Configuration invalide", err)
+	}
+	if err := cfg.checkAuth(); err != nil {
+		fatal("Configuration de l'authentification invalide", err)
+	}
+
+	// Initialiser la base de données (SQLite par défaut, PostgreSQL via DB_DRIVER) et appliquer les migrations.
+	// La durée des requêtes SQL et l'état du pool de connexions sont exposés sur /metrics (METRICS_ADDR).
+	m := newMetrics()
+	db, d := initDB(cfg.DB.Driver, cfg.DB.DSN, m.observeQuery)
+	m.registerDB(db)
+
+	// Créer le routeur Gin : identifiant de requête, log de chaque requête et reprise après panique
+	gin.SetMode(cfg.Mode)
+	r := gin.New()
+	r.Use(requestID(), accessLog(), recovery())
+	r.Use(setupCORS(cfg.CORS)) // Origines autorisées à appeler l'API depuis un navigateur (CORS_*)
 
 	// Routes API
+	store := newSQLStore(db, d)
+	server := newServer(store)
+	ages, _ := cfg.agePolicy() // Politique d'âge par niveau (AGE_CHECK, LEVEL_AGE_RANGES), déjà validée par loadConfig
+	server.setAgePolicy(ages)
+	server.dataDir = cfg.DB.dataDir()
+	server.requireIfMatch = cfg.RequireIfMatch // REQUIRE_IF_MATCH : If-Match obligatoire pour modifier un usager
+	server.metrics = m
+	m.registerUsersByLevel(store)
+	// Authentification du personnel (AUTH_JWT_*); AUTH_DISABLED=true laisse l'API ouverte
+	if cfg.Auth.Disabled {
+		slog.Warn("Authentification désactivée (AUTH_DISABLED), l'API est ouverte à tous")
+	} else {
+		if server.auth, err = newAuthenticator(cfg.Auth, store); err != nil {
+			fatal("Configuration de l'authentification invalide", err)
+		}
+	}
+	server.registerRoutes(r)
+
+	// Servir les fichiers statiques du frontend
+	r.Static("/static", cfg.StaticDir)
+	r.StaticFile("/", cfg.IndexFile)
+
+	// Démarrer le serveur; SIGTERM (docker-compose down) ou Ctrl+C déclenche un arrêt propre
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		fatal("Erreur lors du démarrage du serveur", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if cfg.MetricsAddr != "" {
+		metricsLn, err := net.Listen("tcp", cfg.MetricsAddr)
+		if err != nil {
+			fatal("Erreur lors du démarrage du serveur de métriques", err)
+		}
+		go func() {
+			if err := serve(ctx, newHTTPServer(cfg.MetricsAddr, m.handler(), cfg.Timeouts), metricsLn, cfg.Timeouts.Shutdown); err != nil {
+				slog.Error("Erreur du serveur de métriques", "error", err)
+			}
+		}()
+		slog.Info("Métriques exposées", "addr", cfg.MetricsAddr)
+	}
+	slog.Info("Serveur démarré", "addr", cfg.Addr, "mode", cfg.Mode)
+	serveErr := serve(ctx, newHTTPServer(cfg.Addr, r, cfg.Timeouts), ln, cfg.Timeouts.Shutdown)
+
+	// La base n'est fermée qu'une fois les requêtes en cours terminées
+	if err := db.Close(); err != nil {
+		slog.Error("Erreur lors de la fermeture de la base de données", "error", err)
+	}
+	if serveErr != nil {
+		fatal("Erreur du serveur", serveErr)
+	}
+	slog.Info("Serveur arrêté")
+}
+
+// registerRoutes enregistre les routes de l'API sur le routeur.
+// Les routes d'écriture exigent une permission du rôle du compte connecté (voir rbac.go).
+func (s *Server) registerRoutes(r *gin.Engine) {
+	r.Use(negotiatedLanguage())
+
+	// Métriques des requêtes, déclarées avant toute route pour les compter toutes.
+	// /metrics est servi sur sa propre adresse (METRICS_ADDR), pas sur le routeur de l'API.
+	if s.metrics != nil {
+		r.Use(s.metrics.middleware())
+	}
+
+	// Sondes des conteneurs, sans authentification
+	r.GET("/healthz", s.getHealth)
+	r.GET("/readyz", s.getReadiness)
+
 	api := r.Group("/api")
+	keyed := api // Routes accessibles aussi avec une clé d'API, selon sa portée
+	if s.auth != nil {
+		// Seules la connexion et le renouvellement des jetons sont accessibles sans jeton
+		api.POST("/auth/login", s.login)
+		api.POST("/auth/refresh", s.refreshTokens)
+		keyed = api.Group("", requireAuthOrKey(s.auth))
+		api = api.Group("", requireAuth(s.auth))
+		api.POST("/auth/logout", s.logout)
+		api.GET("/auth/me", s.getCurrentStaff)
+	}
 	{
-		api.GET("/users", getUsers)
-		api.GET("/users/:id", getUserByID)
-		api.POST("/users", createUser)
-		api.PUT("/users/:id", updateUser)
-		api.DELETE("/users/:id", deleteUser)
+		keyed.GET("/levels", s.getLevels)
+		keyed.GET("/users", requireScope(ScopeReadUsers), s.getUsers)
+		keyed.GET("/users/export", requireScope(ScopeExport), s.exportUsers)
+		keyed.GET("/users/age-mismatches", requireScope(ScopeReadUsers), s.getAgeMismatches)
+		keyed.GET("/users/:id", requireScope(ScopeReadUsers), s.getUserByID)
+		keyed.POST("/users", requireScope(ScopeWriteUsers), requirePermission(PermWriteUsers), s.createUser)
+		keyed.PUT("/users/:id", requireScope(ScopeWriteUsers), requirePermission(PermWriteUsers), s.updateUser)
+		keyed.PATCH("/users/:id", requireScope(ScopeWriteUsers), requirePermission(PermWriteUsers), s.patchUser)
+		api.DELETE("/users/:id", requirePermission(PermDeleteUsers), s.deleteUser)
 	}
 
-	// Servir les fichiers statiques du frontend
-	r.Static("/static", "./frontend/static")
-	r.StaticFile("/", "./frontend/index.html")
+	if s.auth != nil && s.auth.keys != nil {
+		api.GET("/api-keys", requirePermission(PermManageAPIKeys), s.getAPIKeys)
+		api.POST("/api-keys", requirePermission(PermManageAPIKeys), s.createAPIKey)
+		api.POST("/api-keys/:id/rotate", requirePermission(PermManageAPIKeys), s.rotateAPIKey)
+		api.DELETE("/api-keys/:id", requirePermission(PermManageAPIKeys), s.revokeAPIKey)
+	}
+
+	if s.courses != nil {
+		api.GET("/courses", s.getCourses)
+		api.GET("/courses/:id", s.getCourseByID)
+		api.POST("/courses", requirePermission(PermManageCourses), s.createCourse)
+		api.PUT("/courses/:id", requirePermission(PermManageCourses), s.updateCourse)
+		api.DELETE("/courses/:id", requirePermission(PermManageCourses), s.deleteCourse)
+		api.GET("/courses/:id/enrollments", s.getEnrollments)
+		api.POST("/courses/:id/enrollments", requirePermission(PermManageEnrollments), s.createEnrollment)
+		api.DELETE("/courses/:id/enrollments/:userId", requirePermission(PermManageEnrollments), s.deleteEnrollment)
+		api.GET("/courses/:id/waitlist", s.getWaitlist)
+		api.PUT("/courses/:id/waitlist", requirePermission(PermManageEnrollments), s.reorderWaitlist)
+		api.DELETE("/courses/:id/waitlist/:userId", requirePermission(PermManageEnrollments), s.deleteWaitlistEntry)
+	}
+
+	if s.evaluations != nil {
+		api.GET("/users/:id/evaluations", s.getEvaluations)
+		api.POST("/users/:id/evaluations", requirePermission(PermRecordEvaluations), s.createEvaluation)
+	}
+
+	if s.guardians != nil {
+		api.GET("/guardians", s.getGuardians)
+		api.GET("/guardians/:id", s.getGuardianByID)
+		api.POST("/guardians", requirePermission(PermManageGuardians), s.createGuardian)
+		api.PUT("/guardians/:id", requirePermission(PermManageGuardians), s.updateGuardian)
+		api.DELETE("/guardians/:id", requirePermission(PermManageGuardians), s.deleteGuardian)
+		api.GET("/guardians/:id/children", s.getGuardianChildren)
+		api.POST("/guardians/:id/children", requirePermission(PermManageGuardians), s.linkGuardianChild)
+		api.DELETE("/guardians/:id/children/:userId", requirePermission(PermManageGuardians), s.unlinkGuardianChild)
+		api.GET("/users/:id/guardians", s.getUserGuardians)
+	}
+
+	if s.attendance != nil {
+		api.GET("/courses/:id/meetings", s.getMeetings)
+		api.POST("/courses/:id/meetings", requirePermission(PermManageCourses), s.generateMeetings)
+		api.GET("/courses/:id/meetings/:meetingId/attendance", s.getMeetingAttendance)
+		api.PUT("/courses/:id/meetings/:meetingId/attendance", requirePermission(PermRecordAttendance), s.markMeetingAttendance)
+		api.GET("/courses/:id/attendance", s.getCourseAttendance)
+		api.GET("/users/:id/attendance", s.getUserAttendance)
+	}
+
+	if s.auth != nil && s.auth.portal != nil && s.guardians != nil {
+		api.PUT("/guardians/:id/portal-access", requirePermission(PermManageGuardians), s.setPortalAccess)
+		api.DELETE("/guardians/:id/portal-access", requirePermission(PermManageGuardians), s.revokePortalAccess)
+		s.registerPortalRoutes(r.Group("/api/portal"))
+	}
+}
+
+// registerPortalRoutes enregistre le portail parents : un tuteur n'accède qu'aux usagers qui lui sont rattachés
+func (s *Server) registerPortalRoutes(portal *gin.RouterGroup) {
+	portal.POST("/login", s.portalLogin)
+	portal.POST("/refresh", s.portalRefresh)
+
+	portal = portal.Group("", requireGuardian(s.auth))
+	portal.POST("/logout", s.portalLogout)
+	portal.GET("/me", s.getPortalGuardian)
+	portal.PUT("/password", s.changePortalPassword)
+	portal.GET("/children", s.getPortalChildren)
 
-	// Démarrer le serveur
-	log.Println("Serveur démarré sur le port 8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatal("Erreur lors du démarrage du serveur:", err)
+	child := portal.Group("/children/:id", requireLinkedChild(s.guardians))
+	child.GET("", s.getUserByID)
+	child.PUT("", s.updatePortalChild)
+	if s.courses != nil {
+		child.GET("/courses", s.getPortalChildCourses)
+	}
+	if s.evaluations != nil {
+		child.GET("/evaluations", s.getEvaluations)
+	}
+	if s.attendance != nil {
+		child.GET("/attendance", s.getUserAttendance)
 	}
 }
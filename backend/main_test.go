@@ -2,11 +2,13 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -18,69 +20,44 @@ import (
 func setupTestDB(t *testing.T) *sql.DB {
 	// Créer une base de données temporaire pour les tests
 	dbPath := ":memory:" // Base de données en mémoire
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath))
 	if err != nil {
 		t.Fatalf("Erreur lors de l'ouverture de la base de données de test: %v", err)
 	}
+	// Chaque connexion :memory: est une base distincte : n'en garder qu'une
+	db.SetMaxOpenConns(1)
 
-	// Créer la table
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		first_name TEXT NOT NULL,
-		last_name TEXT NOT NULL,
-		email TEXT NOT NULL UNIQUE,
-		date_naissance TEXT NOT NULL,
-		niveau_natation TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	_, err = db.Exec(createTableSQL)
-	if err != nil {
-		t.Fatalf("Erreur lors de la création de la table de test: %v", err)
-	}
+	// Créer les tables avec les migrations embarquées
+	migrateTestDB(t, db, sqliteDialect)
 
 	return db
 }
 
+// migrateTestDB applique toutes les migrations embarquées sur la base de test
+func migrateTestDB(t *testing.T, db *sql.DB, d dialect) {
+	m, err := newMigrator(db, d)
+	if err != nil {
+		t.Fatalf("Erreur lors du chargement des migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Erreur lors de l'application des migrations de test: %v", err)
+	}
+}
+
 func setupRouter(testDB *sql.DB) *gin.Engine {
+	return setupRouterWithStore(newSQLiteStore(testDB))
+}
+
+func setupRouterWithStore(store UserStore) *gin.Engine {
+	return setupRouterWithServer(newServer(store))
+}
+
+func setupRouterWithServer(s *Server) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	r.Use(setupCORS())
-
-	api := r.Group("/api")
-	{
-		api.GET("/users", func(c *gin.Context) {
-			originalDB := db
-			db = testDB
-			getUsers(c)
-			db = originalDB
-		})
-		api.GET("/users/:id", func(c *gin.Context) {
-			originalDB := db
-			db = testDB
-			getUserByID(c)
-			db = originalDB
-		})
-		api.POST("/users", func(c *gin.Context) {
-			originalDB := db
-			db = testDB
-			createUser(c)
-			db = originalDB
-		})
-		api.PUT("/users/:id", func(c *gin.Context) {
-			originalDB := db
-			db = testDB
-			updateUser(c)
-			db = originalDB
-		})
-		api.DELETE("/users/:id", func(c *gin.Context) {
-			originalDB := db
-			db = testDB
-			deleteUser(c)
-			db = originalDB
-		})
-	}
+	r.Use(setupCORS(corsConfig{}))
+
+	s.registerRoutes(r)
 
 	return r
 }
@@ -178,7 +155,7 @@ func TestCreateUserInvalidData(t *testing.T) {
 	userData := UserRequest{
 		FirstName:     "Jean",
 		LastName:      "Dupont",
-		Email:         "", // Email manquant
+		Email:         "pas-un-email", // Email invalide
 		DateNaissance: "2010-05-15",
 		NiveauNatation: "NAGEUR 3",
 	}
@@ -190,7 +167,7 @@ func TestCreateUserInvalidData(t *testing.T) {
 
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 }
 
 func TestGetUsers(t *testing.T) {
@@ -437,3 +414,113 @@ func TestGetUsersWithAgeFilter(t *testing.T) {
 	assert.GreaterOrEqual(t, response.Total, 1)
 }
 
+
+func TestMemoryStoreHandlers(t *testing.T) {
+	r := setupRouterWithStore(newMemoryStore())
+
+	userData := UserRequest{
+		FirstName:      "Jean",
+		LastName:       "Dupont",
+		Email:          "jean@test.com",
+		DateNaissance:  "1990-05-15", // Majeur : l'email doit être unique
+		NiveauNatation: "NAGEUR 3",
+	}
+	jsonData, _ := json.Marshal(userData)
+	req, _ := http.NewRequest("POST", "/api/users", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created User
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, 1, created.ID)
+	assert.Greater(t, created.Age, 0)
+
+	// Email dupliqué refusé entre usagers majeurs
+	req, _ = http.NewRequest("POST", "/api/users", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/users?filter_niveau=NAGEUR 3&search=dup", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response UsersResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Total)
+
+	req, _ = http.NewRequest("DELETE", "/api/users/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/users/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestConcurrentCreateUsers(t *testing.T) {
+	r := setupRouterWithStore(newMemoryStore())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userData := UserRequest{
+				FirstName:      "User",
+				LastName:       "Test",
+				Email:          "user" + strconv.Itoa(i) + "@test.com",
+				DateNaissance:  "2010-05-15",
+				NiveauNatation: "NAGEUR 3",
+			}
+			jsonData, _ := json.Marshal(userData)
+			req, _ := http.NewRequest("POST", "/api/users", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusCreated, w.Code)
+		}(i)
+	}
+	wg.Wait()
+
+	req, _ := http.NewRequest("GET", "/api/users?limit=100", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response UsersResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 20, response.Total)
+	assert.Equal(t, 20, len(response.Users))
+}
+
+// performRequest exécute une requête sur le routeur, avec un corps JSON si body n'est pas nil
+func performRequest(r http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	return performRequestWithToken(r, method, path, "", body)
+}
+
+// performRequestWithToken exécute une requête authentifiée par le jeton fourni (Authorization: Bearer)
+func performRequestWithToken(r http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Buffer
+	if body != nil {
+		jsonData, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(jsonData)
+	} else {
+		reader = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
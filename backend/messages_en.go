@@ -0,0 +1,108 @@
+package main
+
+// englishMessages traduit en anglais les messages de l'API, écrits en français dans le code.
+// Un message ajouté au code sans traduction est signalé par TestEnglishCatalogComplete.
+var englishMessages = map[string]string{
+	// Erreurs génériques et validation
+	"Erreur interne du serveur":                  "Internal server error",
+	"Corps de la requête manquant":               "Missing request body",
+	"JSON invalide : %s":                         "Invalid JSON: %s",
+	"Un ou plusieurs champs sont invalides":      "One or more fields are invalid",
+	"Champ requis":                               "This field is required",
+	"Adresse email invalide":                     "Invalid email address",
+	"Valeur non permise (valeurs permises : %s)": "Value not allowed (allowed values: %s)",
+	"Format invalide (attendu : %s)":             "Invalid format (expected: %s)",
+	"Au moins %s élément(s) requis":              "At least %s item(s) required",
+	"Au plus %s élément(s)":                      "At most %s item(s)",
+	"Valeur minimale : %s":                       "Minimum value: %s",
+	"Valeur maximale : %s":                       "Maximum value: %s",
+	"Valeur invalide (règle %s)":                 "Invalid value (rule %s)",
+	"Type invalide : %s attendu":                 "Invalid type: %s expected",
+	"chaîne":                                     "string",
+	"booléen":                                    "boolean",
+	"entier":                                     "integer",
+	"nombre":                                     "number",
+	"tableau":                                    "array",
+	"objet":                                      "object",
+	"Date invalide (format YYYY-MM-DD)":          "Invalid date (format YYYY-MM-DD)",
+	"Cet email est déjà utilisé":                 "This email is already in use",
+	"Email déjà utilisé":                         "Email already in use",
+	"Au plus %d caractères":                      "At most %d characters",
+	"Caractères non permis":                      "Characters not allowed",
+	"La date de naissance ne peut pas être dans le futur": "The date of birth cannot be in the future",
+	"La date de naissance doit dater de moins de %d ans":  "The date of birth must be less than %d years ago",
+	"La date d'évaluation ne peut pas être dans le futur": "The evaluation date cannot be in the future",
+
+	// Modifications partielles (PATCH)
+	"Type de contenu non pris en charge : %s (types acceptés : %s)":   "Unsupported content type: %s (accepted types: %s)",
+	"JSON Patch : un tableau d'opérations est attendu":                "JSON Patch: an array of operations is expected",
+	"Opération %d : champ value requis":                               "Operation %d: value is required",
+	"Opération %d : chemin invalide : %s":                             "Operation %d: invalid path: %s",
+	"Opération %d : opération inconnue : %s":                          "Operation %d: unknown operation: %s",
+	"Opération %d : chemin introuvable : %s":                          "Operation %d: path not found: %s",
+	"Opération %d : impossible de déplacer une valeur dans elle-même": "Operation %d: cannot move a value into itself",
+	"Opération %d : la valeur de %s n'est pas celle attendue":         "Operation %d: the value of %s is not the expected one",
+	"Le patch doit produire un objet":                                 "The patch must produce an object",
+	"Champ non modifiable":                                            "This field cannot be modified",
+
+	// Authentification et autorisations
+	"Authentification requise":                                     "Authentication required",
+	"Jeton invalide ou expiré":                                     "Invalid or expired token",
+	"Jeton de rafraîchissement invalide ou expiré":                 "Invalid or expired refresh token",
+	"Email ou mot de passe invalide":                               "Invalid email or password",
+	"Accès refusé : la clé d'API n'a pas la portée %s":             "Access denied: the API key does not have the %s scope",
+	"Accès refusé : le rôle %s ne permet pas cette opération (%s)": "Access denied: the %s role does not allow this operation (%s)",
+	"Déconnexion réussie":                                          "Logged out successfully",
+	"Clé d'API non trouvée ou révoquée":                            "API key not found or revoked",
+	"Clé d'API révoquée":                                           "API key revoked",
+
+	// Usagers et niveaux
+	"Usager non trouvé":                                          "User not found",
+	"Usager supprimé avec succès":                                "User deleted successfully",
+	"L'usager a été modifié depuis sa lecture":                   "The user has been modified since it was read",
+	"L'usager a été modifié pendant l'enregistrement":            "The user was modified while being saved",
+	"La modification d'un usager doit porter l'en-tête If-Match": "Modifying a user requires an If-Match header",
+	"Niveau de natation inconnu: %s":                             "Unknown swimming level: %s",
+	"L'âge de l'usager ne correspond pas au niveau de natation":  "The user's age does not match the swimming level",
+	"L'évaluation doit porter sur le niveau actuel de l'usager":  "The evaluation must be for the user's current level",
+	"Parent et Enfant":                                           "Parent and Child",
+	"Préscolaire":                                                "Preschool",
+	"Nageur":                                                     "Swimmer",
+	"Jeune sauveteur":                                            "Junior Lifeguard",
+
+	// Cours, inscriptions et présences
+	"Cours non trouvé":                                                                 "Course not found",
+	"Cours supprimé avec succès":                                                       "Course deleted successfully",
+	"L'heure de fin doit être après l'heure de début":                                  "The end time must be after the start time",
+	"La date de fin doit être après la date de début":                                  "The end date must be after the start date",
+	"La capacité ne peut pas être inférieure au nombre d'inscrits":                     "The capacity cannot be lower than the number of enrolled users",
+	"Inscription non trouvée":                                                          "Enrollment not found",
+	"Inscription annulée avec succès":                                                  "Enrollment cancelled successfully",
+	"L'usager est déjà inscrit à ce cours":                                             "The user is already enrolled in this course",
+	"L'usager est déjà en liste d'attente pour ce cours":                               "The user is already on the waitlist for this course",
+	"Usager absent de la liste d'attente":                                              "User not on the waitlist",
+	"Usager retiré de la liste d'attente":                                              "User removed from the waitlist",
+	"Le nouvel ordre doit contenir exactement les usagers en liste d'attente":          "The new order must contain exactly the users on the waitlist",
+	"Le niveau de natation de l'usager ne correspond pas au niveau du cours":           "The user's swimming level does not match the course level",
+	"Séance non trouvée":                                                               "Session not found",
+	"Seuls les usagers inscrits au cours peuvent être marqués":                         "Only users enrolled in the course can be marked",
+	"Seuls les usagers inscrits au cours peuvent être marqués (usager %d non inscrit)": "Only users enrolled in the course can be marked (user %d is not enrolled)",
+
+	// Tuteurs et portail parents
+	"Tuteur non trouvé":                                       "Guardian not found",
+	"Tuteur supprimé avec succès":                             "Guardian deleted successfully",
+	"Un autre tuteur utilise déjà cet email":                  "Another guardian already uses this email",
+	"L'usager est déjà rattaché à ce tuteur":                  "The user is already linked to this guardian",
+	"L'usager n'est pas rattaché à ce tuteur":                 "The user is not linked to this guardian",
+	"Usager détaché du tuteur":                                "User unlinked from the guardian",
+	"Accès au portail parents accordé":                        "Parent portal access granted",
+	"Accès au portail parents retiré":                         "Parent portal access removed",
+	"Mot de passe actuel invalide":                            "Invalid current password",
+	"Mot de passe modifié, veuillez vous reconnecter":         "Password changed, please log in again",
+	"Le mot de passe doit contenir entre %d et %d caractères": "The password must contain between %d and %d characters",
+
+	// Sondes de santé
+	"base de données injoignable":                                     "database unreachable",
+	"migrations en attente ou modifiées (voir ./main migrate status)": "pending or modified migrations (see ./main migrate status)",
+	"répertoire des données non accessible en écriture":               "data directory not writable",
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics regroupe les métriques Prometheus exposées sur /metrics
+type metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	queries  *prometheus.HistogramVec
+}
+
+// newMetrics crée les métriques des requêtes HTTP et SQL, avec celles du runtime Go et du processus
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Nombre de requêtes HTTP traitées, par méthode, route et code de statut.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Durée de traitement des requêtes HTTP, par méthode, route et code de statut.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Durée des requêtes SQL, par opération (select, insert, update, delete, other).",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.queries,
+	)
+	return m
+}
+
+// observeQuery enregistre la durée d'une requête SQL (queryObserver de openDB)
+func (m *metrics) observeQuery(operation string, d time.Duration) {
+	m.queries.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// registerDB ajoute l'état du pool de connexions (db.Stats) : connexions ouvertes, en attente, ...
+func (m *metrics) registerDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "usagers"))
+}
+
+// registerUsersByLevel ajoute le nombre d'usagers par niveau, calculé à chaque collecte
+func (m *metrics) registerUsersByLevel(store LevelStatsStore) {
+	m.registry.MustRegister(&usersByLevelCollector{
+		store: store,
+		desc: prometheus.NewDesc("usagers_users", "Nombre d'usagers inscrits par niveau de natation.",
+			[]string{"niveau_natation"}, nil),
+	})
+}
+
+// middleware compte les requêtes et mesure leur durée. La route est le chemin déclaré
+// (ex: /api/users/:id) pour garder un nombre de séries borné; "unmatched" pour une route inconnue.
+func (m *metrics) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.duration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// handler expose les métriques au format Prometheus, servi sur l'adresse dédiée (METRICS_ADDR)
+// et non sur le routeur de l'API : les métriques ne sont pas protégées par l'authentification
+// GET /metrics
+func (m *metrics) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// usersByLevelCollector interroge le store à chaque collecte. Les niveaux du catalogue
+// sans usager sont exposés à 0, pour que les graphiques ne présentent pas de trous.
+type usersByLevelCollector struct {
+	store LevelStatsStore
+	desc  *prometheus.Desc
+}
+
+func (c *usersByLevelCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *usersByLevelCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	counts, err := c.store.CountUsersByLevel(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, level := range levels {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[level.Label]), level.Label)
+		delete(counts, level.Label)
+	}
+	// Valeurs hors catalogue (anciens niveaux)
+	for label, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), label)
+	}
+}
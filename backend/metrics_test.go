@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryOperation(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT id FROM users":               "select",
+		"\n\t\tinsert INTO users VALUES (?)": "insert",
+		"UPDATE users SET email = ?":         "update",
+		"DELETE FROM users":                  "delete",
+		"PRAGMA foreign_keys = OFF":          "other",
+		"":                                   "other",
+	} {
+		assert.Equal(t, want, queryOperation(query), query)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	m := newMetrics()
+	db, d, err := openDB("sqlite3", filepath.Join(t.TempDir(), "metrics.db"), m.observeQuery)
+	require.NoError(t, err)
+	defer db.Close()
+	migrateTestDB(t, db, d)
+	m.registerDB(db)
+
+	store := newSQLStore(db, d)
+	m.registerUsersByLevel(store)
+	s := newServer(store)
+	s.metrics = m
+	r := setupRouterWithServer(s)
+
+	insertTestUser(t, db, "jean@test.com", "NAGEUR 3")
+	performRequest(r, "GET", "/api/users", nil)
+	performRequest(r, "GET", "/api/users/1", nil)
+	performRequest(r, "GET", "/api/users/999", nil)
+	performRequest(r, "GET", "/api/inconnue/42", nil)
+
+	// /metrics n'est pas servi par le routeur de l'API, mais sur sa propre adresse
+	w := performRequest(r, "GET", "/metrics", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequest(m.handler(), "GET", "/metrics", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+
+	// Requêtes par route déclarée (et non par chemin) et par statut
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/users",status="200"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/users/:id",status="200"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/api/users/:id",status="404"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 2`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/api/users",status="200"} 1`)
+	assert.NotContains(t, body, "/api/users/999")
+
+	// Requêtes SQL (y compris les migrations, exécutées dans des transactions), pool de connexions
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="select"}`)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="insert"}`)
+	assert.Contains(t, body, `db_query_duration_seconds_count{operation="other"}`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="usagers"}`)
+
+	// Usagers par niveau, y compris les niveaux sans usager
+	assert.Contains(t, body, `usagers_users{niveau_natation="NAGEUR 3"} 1`)
+	assert.Contains(t, body, `usagers_users{niveau_natation="NAGEUR 1"} 0`)
+}
+
+func TestInstrumentedDriverErrors(t *testing.T) {
+	var operations []string
+	db, _, err := openDB("sqlite3", filepath.Join(t.TempDir(), "errors.db"), func(op string, _ time.Duration) {
+		operations = append(operations, op)
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Les erreurs du driver traversent la connexion instrumentée sans être modifiées
+	_, err = db.Exec("CREATE TABLE t (v TEXT UNIQUE)")
+	require.NoError(t, err)
+	stmt, err := db.Prepare("INSERT INTO t (v) VALUES (?)")
+	require.NoError(t, err)
+	defer stmt.Close()
+	_, err = stmt.Exec("a")
+	require.NoError(t, err)
+	_, err = stmt.Exec("a")
+	assert.True(t, isUniqueViolation(err), err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("DELETE FROM t")
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM t").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	assert.Equal(t, []string{"other", "insert", "insert", "delete", "select"}, operations)
+}
@@ -1,21 +1,205 @@
 package main
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// En-têtes et méthodes acceptés dans les requêtes cross-origin
+const (
+	corsAllowedHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, If-None-Match"
+	corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+)
+
+// setupCORS applique la politique CORS : l'origine de la requête est renvoyée si elle est autorisée.
+// Une requête preflight (OPTIONS) d'une origine non autorisée est refusée (403).
+// La configuration doit avoir été validée par loadConfig.
+func setupCORS(cfg corsConfig) gin.HandlerFunc {
+	origins, _ := newOriginMatcher(cfg.AllowedOrigins)
+	// ETag (pour If-Match) et l'identifiant de la requête sont toujours lisibles
+	exposed := strings.Join(append([]string{"ETag", requestIDHeader}, cfg.ExposedHeaders...), ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
 
-// setupCORS configure le middleware CORS pour permettre les requêtes cross-origin
-func setupCORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		h := c.Writer.Header()
+		// La réponse dépend de l'origine : les caches ne doivent pas la servir à une autre origine
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" {
+			c.Next()
+			return
+		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		switch {
+		case origins.match(origin):
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Credentials", "true")
+		case origins.any:
+			h.Set("Access-Control-Allow-Origin", "*")
+		default:
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
 			return
 		}
 
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", exposed)
+			c.Next()
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		h.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+		h.Set("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// Clés du contexte Gin renseignées par requireAuth, requireAuthOrKey et requireGuardian
+const (
+	staffContextKey    = "staff"
+	guardianContextKey = "guardian"
+	apiKeyContextKey   = "api_key"
+	claimsContextKey   = "token_claims"
+)
+
+// requireAuth rejette (401) les requêtes sans jeton d'accès valide dans l'en-tête
+// Authorization: Bearer <jeton>. Le compte authentifié est placé dans le contexte.
+func requireAuth(a *authenticator) gin.HandlerFunc {
+	return bearerAuth("api", func(c *gin.Context, token string) error {
+		return authenticateStaff(c, a, token)
+	})
+}
+
+// requireAuthOrKey accepte, en plus d'un jeton d'accès du personnel, une clé d'API
+// (Authorization: Bearer usk_...). La clé est placée dans le contexte; les routes
+// acceptant les clés doivent vérifier leur portée avec requireScope.
+func requireAuthOrKey(a *authenticator) gin.HandlerFunc {
+	return bearerAuth("api", func(c *gin.Context, token string) error {
+		if !isAPIKey(token) {
+			return authenticateStaff(c, a, token)
+		}
+		key, err := a.AuthenticateKey(c.Request.Context(), token)
+		if err == nil {
+			c.Set(apiKeyContextKey, key)
+		}
+		return err
+	})
+}
+
+// authenticateStaff valide un jeton d'accès du personnel et place le compte et le jeton dans le contexte
+func authenticateStaff(c *gin.Context, a *authenticator, token string) error {
+	staff, claims, err := a.Authenticate(c.Request.Context(), token, accessToken)
+	if err == nil {
+		c.Set(staffContextKey, staff)
+		c.Set(claimsContextKey, claims)
+	}
+	return err
+}
+
+// requireGuardian rejette (401) les requêtes sans jeton d'accès valide du portail parents.
+// Le tuteur authentifié est placé dans le contexte.
+func requireGuardian(a *authenticator) gin.HandlerFunc {
+	return bearerAuth("portal", func(c *gin.Context, token string) error {
+		guardian, claims, err := a.AuthenticateGuardian(c.Request.Context(), token, portalAccessToken)
+		if err == nil {
+			c.Set(guardianContextKey, guardian)
+			c.Set(claimsContextKey, claims)
+		}
+		return err
+	})
+}
+
+// bearerAuth extrait le jeton de l'en-tête Authorization: Bearer <jeton> et le valide avec authenticate
+func bearerAuth(realm string, authenticate func(c *gin.Context, token string) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+			abortError(c, http.StatusUnauthorized, CodeAuthenticationRequired, "Authentification requise")
+			return
+		}
+
+		err := authenticate(c, token)
+		if errors.Is(err, ErrInvalidToken) {
+			c.Header("WWW-Authenticate", `Bearer realm="`+realm+`", error="invalid_token"`)
+			abortError(c, http.StatusUnauthorized, CodeInvalidToken, "Jeton invalide ou expiré")
+			return
+		}
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireScope rejette (403) les requêtes authentifiées par une clé d'API qui n'a pas la portée.
+// Les requêtes du personnel sont acceptées (leurs permissions sont vérifiées par requirePermission).
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(apiKeyContextKey)
+		if ok && !value.(APIKey).hasScope(scope) {
+			abortError(c, http.StatusForbidden, CodeInsufficientScope, "Accès refusé : la clé d'API n'a pas la portée %s", scope)
+			return
+		}
 		c.Next()
 	}
 }
 
+// requireLinkedChild rejette les requêtes du portail sur un usager (:id) qui n'est pas rattaché
+// au tuteur authentifié. La réponse est la même que pour un usager inexistant (404).
+func requireLinkedChild(guardians GuardianStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			abortInvalidID(c, "ID invalide")
+			return
+		}
+
+		guardian := c.MustGet(guardianContextKey).(Guardian)
+		children, err := guardians.ListChildren(c.Request.Context(), guardian.ID)
+		if err != nil {
+			internalError(c, err)
+			return
+		}
+		for _, child := range children {
+			if child.ID == id {
+				c.Next()
+				return
+			}
+		}
+		abortError(c, http.StatusNotFound, CodeUserNotFound, "Usager non trouvé")
+	}
+}
+
+// requirePermission rejette (403) les requêtes dont le compte authentifié n'a pas la permission.
+// Sans authentification (AUTH_DISABLED), aucun compte n'est dans le contexte et la requête est acceptée.
+// Les requêtes authentifiées par une clé d'API sont contrôlées par requireScope.
+func requirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(staffContextKey)
+		if !ok {
+			c.Next()
+			return
+		}
+		if staff := value.(Staff); !hasPermission(staff.Role, permission) {
+			abortError(c, http.StatusForbidden, CodePermissionDenied,
+				"Accès refusé : le rôle %s ne permet pas cette opération (%s)", staff.Role, permission)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Les migrations sont embarquées dans le binaire, un dossier par dialecte
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+// migrationFileRe reconnaît les fichiers du type 0001_create_users.up.sql
+// (et le script de vérification facultatif 0001_create_users.check.sql)
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down|check)\.sql$`)
+
+// migration représente une migration numérotée avec ses scripts up et down
+type migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Check    string // Requête facultative listant les lignes qui empêchent d'appliquer le script up
+	Checksum string // SHA-256 du script up
+}
+
+// migrationStatus décrit l'état d'une migration dans la base
+type migrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // Le script up a changé depuis son application
+}
+
+// migrator applique les migrations et tient à jour la table schema_migrations
+type migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []migration
+}
+
+// newMigrator charge les migrations embarquées pour le dialecte de la base
+func newMigrator(db *sql.DB, d dialect) (*migrator, error) {
+	fsys, err := fs.Sub(migrationsFS, "migrations/"+d.migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+	return newMigratorFS(db, d, fsys)
+}
+
+// newMigratorFS charge les migrations depuis un système de fichiers quelconque
+func newMigratorFS(db *sql.DB, d dialect, fsys fs.FS) (*migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{db: db, dialect: d, migrations: migrations}, nil
+}
+
+// loadMigrations lit et valide les fichiers de migration, triés par version
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("nom de fichier de migration invalide: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %04d: noms incohérents %q et %q", version, m.Name, match[2])
+		}
+		switch match[3] {
+		case "up":
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		case "down":
+			m.Down = string(content)
+		case "check":
+			m.Check = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: les scripts up et down sont requis", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// ensureTable crée la table schema_migrations si nécessaire
+func (m *migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// appliedMigration est une ligne de la table schema_migrations
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// applied retourne les migrations déjà appliquées, indexées par version
+func (m *migrator) applied(ctx context.Context) (map[int]appliedMigration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// verify s'assure que les migrations appliquées correspondent à celles du binaire
+func (m *migrator) verify(applied map[int]appliedMigration) error {
+	known := map[int]migration{}
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	for _, version := range versions {
+		mig, ok := known[version]
+		if !ok {
+			return fmt.Errorf("la migration %04d est appliquée mais inconnue de ce binaire", version)
+		}
+		if applied[version].checksum != mig.Checksum {
+			return fmt.Errorf("la migration %04d_%s a été modifiée après son application (checksum différent)", version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// Up applique toutes les migrations en attente et retourne celles qui ont été appliquées
+func (m *migrator) Up(ctx context.Context) ([]migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var done []migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.inTx(ctx, mig.Check, mig.Up, "INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)", mig.Version, mig.Name, mig.Checksum)
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down annule les `steps` dernières migrations appliquées et retourne celles qui ont été annulées
+func (m *migrator) Down(ctx context.Context, steps int) ([]migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var done []migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		err := m.inTx(ctx, "", mig.Down, "DELETE FROM schema_migrations WHERE version = ?", mig.Version)
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s (down): %w", mig.Version, mig.Name, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Status retourne l'état de chaque migration connue
+func (m *migrator) Status(ctx context.Context) ([]migrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]migrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := migrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = a.appliedAt
+			st.Modified = a.checksum != mig.Checksum
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// inTx exécute un script de migration et la mise à jour de schema_migrations dans une même transaction,
+// après la requête de vérification check si elle est fournie (voir checkMigration).
+// Si le dialecte le demande, les clés étrangères sont désactivées sur la connexion pendant la migration
+// (pour pouvoir reconstruire une table référencée) et leur intégrité est vérifiée avant la validation.
+func (m *migrator) inTx(ctx context.Context, check, script, bookkeeping string, args ...interface{}) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect.foreignKeysOff != "" {
+		if _, err := conn.ExecContext(ctx, m.dialect.foreignKeysOff); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), m.dialect.foreignKeysOn)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if check != "" {
+		if err := checkMigration(ctx, tx, check); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if m.dialect.foreignKeyCheck != "" {
+		rows, err := tx.QueryContext(ctx, m.dialect.foreignKeyCheck)
+		if err != nil {
+			return err
+		}
+		violated := rows.Next()
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if violated {
+			return fmt.Errorf("la migration laisse des clés étrangères invalides")
+		}
+	}
+	if _, err := tx.ExecContext(ctx, m.dialect.rebind(bookkeeping), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkMigration exécute la requête de vérification d'une migration : chaque ligne retournée est
+// une donnée à corriger à la main avant d'appliquer la migration (ex: doublons qu'elle créerait).
+// Les lignes sont listées dans l'erreur, une par ligne, colonnes séparées par des espaces.
+func checkMigration(ctx context.Context, tx *sql.Tx, query string) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		fields := make([]string, len(values))
+		for i, v := range values {
+			fields[i] = v.String
+		}
+		conflicts = append(conflicts, strings.Join(fields, " "))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d ligne(s) à corriger avant d'appliquer la migration (%s) :\n  %s",
+			len(conflicts), strings.Join(columns, " "), strings.Join(conflicts, "\n  "))
+	}
+	return nil
+}
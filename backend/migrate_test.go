@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_pools.up.sql":   {Data: []byte("CREATE TABLE pools (id INTEGER PRIMARY KEY, name TEXT NOT NULL);")},
+		"0001_create_pools.down.sql": {Data: []byte("DROP TABLE pools;")},
+		"0002_add_pool_city.up.sql":  {Data: []byte("ALTER TABLE pools ADD COLUMN city TEXT;")},
+		"0002_add_pool_city.down.sql": {Data: []byte(`
+			CREATE TABLE pools_old (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+			INSERT INTO pools_old SELECT id, name FROM pools;
+			DROP TABLE pools;
+			ALTER TABLE pools_old RENAME TO pools;`)},
+	}
+}
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS) *migrator {
+	db, err := sql.Open("sqlite3", sqliteDSN(":memory:"))
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	m, err := newMigratorFS(db, sqliteDialect, fsys)
+	require.NoError(t, err)
+	return m
+}
+
+func TestMigrateUpIsIdempotent(t *testing.T) {
+	m := newTestMigrator(t, testMigrations())
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+
+	_, err = m.db.Exec("INSERT INTO pools (name, city) VALUES ('Piscine du parc', 'Montréal')")
+	assert.NoError(t, err)
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	var count int
+	require.NoError(t, m.db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestMigrateDownAndStatus(t *testing.T) {
+	m := newTestMigrator(t, testMigrations())
+	ctx := context.Background()
+
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, 2, reverted[0].Version)
+
+	_, err = m.db.Exec("INSERT INTO pools (name, city) VALUES ('Piscine du parc', 'Montréal')")
+	assert.Error(t, err, "la colonne city devrait avoir été retirée")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	// Réappliquer la migration annulée
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+}
+
+func TestMigrateDetectsModifiedMigration(t *testing.T) {
+	fsys := testMigrations()
+	m := newTestMigrator(t, fsys)
+	ctx := context.Background()
+
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	fsys["0001_create_pools.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE pools (id INTEGER PRIMARY KEY);")}
+	modified, err := newMigratorFS(m.db, sqliteDialect, fsys)
+	require.NoError(t, err)
+
+	_, err = modified.Up(ctx)
+	assert.ErrorContains(t, err, "checksum")
+
+	statuses, err := modified.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Modified)
+}
+
+func TestMigrateRejectsFailedMigration(t *testing.T) {
+	fsys := testMigrations()
+	fsys["0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE pools ADD COLUMN depth REAL; SELECT * FROM missing_table;")}
+	fsys["0003_broken.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	m := newTestMigrator(t, fsys)
+
+	applied, err := m.Up(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, applied, 2)
+
+	// La transaction a été annulée : la colonne depth n'existe pas
+	_, err = m.db.Exec("INSERT INTO pools (name, depth) VALUES ('Piscine', 2.5)")
+	assert.Error(t, err)
+}
+
+func TestMigrateCheckListsConflicts(t *testing.T) {
+	fsys := testMigrations()
+	fsys["0003_unique_pool_name.check.sql"] = &fstest.MapFile{Data: []byte(`
+		SELECT id, name FROM pools WHERE name IN (SELECT name FROM pools GROUP BY name HAVING COUNT(*) > 1) ORDER BY id`)}
+	fsys["0003_unique_pool_name.up.sql"] = &fstest.MapFile{Data: []byte("CREATE UNIQUE INDEX idx_pools_name ON pools (name);")}
+	fsys["0003_unique_pool_name.down.sql"] = &fstest.MapFile{Data: []byte("DROP INDEX idx_pools_name;")}
+	ctx := context.Background()
+
+	// Données appliquées avant l'ajout de la migration 0003
+	m := newTestMigrator(t, testMigrations())
+	_, err := m.Up(ctx)
+	require.NoError(t, err)
+	_, err = m.db.Exec("INSERT INTO pools (name) VALUES ('Piscine A'), ('Piscine B'), ('Piscine A')")
+	require.NoError(t, err)
+
+	// Les lignes retournées par la vérification empêchent la migration et sont listées
+	m, err = newMigratorFS(m.db, sqliteDialect, fsys)
+	require.NoError(t, err)
+	applied, err := m.Up(ctx)
+	require.Error(t, err)
+	assert.Empty(t, applied)
+	assert.Contains(t, err.Error(), "2 ligne(s)")
+	assert.Contains(t, err.Error(), "1 Piscine A\n  3 Piscine A")
+
+	// Une fois les données corrigées, la migration est appliquée
+	_, err = m.db.Exec("UPDATE pools SET name = 'Piscine C' WHERE id = 3")
+	require.NoError(t, err)
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 1)
+}
+
+func TestNormalizeEmailsMigration(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	m, err := newMigrator(db, sqliteDialect)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Revenir avant 0012_normalize_emails pour y insérer des emails qui ne diffèrent que par la casse
+	_, err = m.Down(ctx, len(m.migrations)-11)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation) VALUES
+			('Jean', 'Dupont', 'Jean@Test.com', '1980-05-15', 'NAGEUR 9'),
+			('Paul', 'Dupont', 'jean@test.com ', '1982-05-15', 'NAGEUR 9'),
+			('Léa', 'Dupont', 'JEAN@test.com', '2015-05-15', 'NAGEUR 3');
+		INSERT INTO guardians (first_name, last_name, email) VALUES ('Marie', 'Martin', 'Marie@Test.com'), ('Marie', 'Martin', 'marie@test.com');
+		INSERT INTO staff (email, name, password_hash) VALUES ('Admin@Test.com', 'Sophie', 'x');`)
+	require.NoError(t, err)
+
+	// Les doublons entre majeurs et entre tuteurs sont listés; rien n'est modifié
+	_, err = m.Up(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "0012_normalize_emails")
+	assert.Contains(t, err.Error(), "guardians 1 Marie@Test.com\n  guardians 2 marie@test.com\n  users 1 Jean@Test.com\n  users 2 jean@test.com")
+	assert.NotContains(t, err.Error(), "JEAN@test.com") // Mineure : email partagé permis
+
+	// Une fois les doublons corrigés, tous les emails sont normalisés, y compris ceux du personnel
+	_, err = db.Exec("UPDATE users SET email = 'paul@test.com' WHERE id = 2; DELETE FROM guardians WHERE id = 2")
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	for query, want := range map[string]string{
+		"SELECT email FROM users WHERE id = 3":     "jean@test.com",
+		"SELECT email FROM guardians WHERE id = 1": "marie@test.com",
+		"SELECT email FROM staff":                  "admin@test.com",
+	} {
+		var email string
+		require.NoError(t, db.QueryRow(query).Scan(&email))
+		assert.Equal(t, want, email, query)
+	}
+}
+
+func TestLoadMigrationsRequiresDownScript(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{
+		"0001_create_pools.up.sql": {Data: []byte("CREATE TABLE pools (id INTEGER PRIMARY KEY);")},
+	})
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrationsAreValid(t *testing.T) {
+	sqliteMigrator, err := newMigrator(nil, sqliteDialect)
+	require.NoError(t, err)
+	postgresMigrator, err := newMigrator(nil, postgresDialect)
+	require.NoError(t, err)
+
+	// Les deux dialectes doivent avoir les mêmes migrations
+	require.Equal(t, len(sqliteMigrator.migrations), len(postgresMigrator.migrations))
+	for i, mig := range sqliteMigrator.migrations {
+		assert.Equal(t, mig.Version, postgresMigrator.migrations[i].Version)
+		assert.Equal(t, mig.Name, postgresMigrator.migrations[i].Name)
+	}
+
+	// Aller-retour complet sur SQLite
+	db := setupTestDB(t)
+	defer db.Close()
+	m, err := newMigrator(db, sqliteDialect)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	reverted, err := m.Down(ctx, len(m.migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(m.migrations))
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(m.migrations))
+}
+
+func TestMigrateRebuildKeepsForeignKeys(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	m, err := newMigrator(db, sqliteDialect)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Revenir avant la reconstruction de la table users (0005) pour y insérer des données référencées
+	_, err = m.Down(ctx, len(m.migrations)-4)
+	require.NoError(t, err)
+	userID := insertTestUser(t, db, "jean@test.com", "NAGEUR 3")
+	_, err = db.Exec(`INSERT INTO evaluations (user_id, level, passed, evaluator, evaluated_on, previous_level, new_level)
+		VALUES (?, 'NAGEUR 3', 0, 'Sophie', '2024-06-15', 'NAGEUR 3', 'NAGEUR 3')`, userID)
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	// Les données référencées sont conservées et les clés étrangères sont de nouveau actives
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM evaluations WHERE user_id = ?", userID).Scan(&count))
+	assert.Equal(t, 1, count)
+	_, err = db.Exec("DELETE FROM users WHERE id = ?", userID)
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM evaluations").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestMigrateRejectsForeignKeyViolations(t *testing.T) {
+	fsys := testMigrations()
+	fsys["0003_create_lanes.up.sql"] = &fstest.MapFile{Data: []byte(`
+		CREATE TABLE lanes (id INTEGER PRIMARY KEY, pool_id INTEGER NOT NULL REFERENCES pools (id));
+		INSERT INTO lanes (pool_id) VALUES (42);`)}
+	fsys["0003_create_lanes.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE lanes;")}
+	m := newTestMigrator(t, fsys)
+
+	applied, err := m.Up(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, applied, 2)
+}
@@ -0,0 +1,3 @@
+-- Échoue si plusieurs usagers partagent un email
+DROP INDEX idx_users_email;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
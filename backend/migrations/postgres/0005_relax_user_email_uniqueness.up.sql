@@ -0,0 +1,5 @@
+-- L'email n'est plus unique au niveau de la base : des enfants d'une même famille peuvent partager
+-- l'email d'un parent. L'unicité entre usagers majeurs est vérifiée par l'application, lorsque
+-- l'email est renseigné ou modifié (voir checkEmail).
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE INDEX idx_users_email ON users (email);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_guardians;
+DROP TABLE IF EXISTS guardians;
@@ -0,0 +1,19 @@
+CREATE TABLE guardians (
+	id SERIAL PRIMARY KEY,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL,
+	email TEXT NOT NULL UNIQUE,
+	phone TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lien entre un usager (enfant) et ses tuteurs
+CREATE TABLE user_guardians (
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	guardian_id INTEGER NOT NULL REFERENCES guardians (id) ON DELETE CASCADE,
+	relationship TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, guardian_id)
+);
+
+CREATE INDEX idx_user_guardians_guardian ON user_guardians (guardian_id);
@@ -0,0 +1,20 @@
+-- Séances d'un cours, générées à partir du jour et des dates du cours
+CREATE TABLE course_meetings (
+	id SERIAL PRIMARY KEY,
+	course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+	meeting_date TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (course_id, meeting_date)
+);
+
+-- Présence d'un usager à une séance
+CREATE TABLE attendance (
+	meeting_id INTEGER NOT NULL REFERENCES course_meetings (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	status TEXT NOT NULL CHECK (status IN ('present', 'absent', 'excused')),
+	note TEXT NOT NULL DEFAULT '',
+	recorded_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (meeting_id, user_id)
+);
+
+CREATE INDEX idx_attendance_user ON attendance (user_id);
@@ -0,0 +1,17 @@
+-- Comptes du personnel autorisés à utiliser l'API
+CREATE TABLE staff (
+	id SERIAL PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	tokens_revoked_at TIMESTAMPTZ, -- Les jetons émis avant cette date sont refusés
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Jetons révoqués avant leur expiration (déconnexion, rotation des jetons de rafraîchissement)
+CREATE TABLE revoked_tokens (
+	jti TEXT PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
@@ -0,0 +1,3 @@
+-- Rôle de chaque compte du personnel; les comptes existants gardent un accès complet
+ALTER TABLE staff ADD COLUMN role TEXT NOT NULL DEFAULT 'admin'
+	CHECK (role IN ('admin', 'coordinator', 'instructor', 'readonly'));
@@ -0,0 +1,12 @@
+-- Clés d'API des intégrations (borne d'inscription, scripts de rapports)
+CREATE TABLE api_keys (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL, -- Début de la clé, affiché pour la reconnaître
+	key_hash TEXT NOT NULL UNIQUE, -- SHA-256 de la clé; la clé elle-même n'est jamais stockée
+	scopes TEXT NOT NULL, -- Portées séparées par des espaces
+	created_by INTEGER REFERENCES staff (id) ON DELETE SET NULL,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,17 @@
+-- Emails qui ne diffèrent que par la casse ou les espaces : une fois normalisés, ils seraient en double.
+-- La migration échoue en listant ces lignes, à corriger à la main avant de la relancer.
+-- Pour les usagers, l'email n'est unique qu'entre majeurs : des enfants peuvent partager l'email
+-- d'un parent (voir checkEmail).
+SELECT 'users' AS source, id, email FROM users
+WHERE date_naissance <= to_char(CURRENT_DATE - INTERVAL '18 years', 'YYYY-MM-DD')
+	AND LOWER(TRIM(email)) IN (
+		SELECT LOWER(TRIM(email)) FROM users WHERE date_naissance <= to_char(CURRENT_DATE - INTERVAL '18 years', 'YYYY-MM-DD')
+		GROUP BY LOWER(TRIM(email)) HAVING COUNT(DISTINCT email) > 1
+	)
+UNION ALL
+SELECT 'guardians', id, email FROM guardians
+WHERE LOWER(TRIM(email)) IN (SELECT LOWER(TRIM(email)) FROM guardians GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1)
+UNION ALL
+SELECT 'staff', id, email FROM staff
+WHERE LOWER(TRIM(email)) IN (SELECT LOWER(TRIM(email)) FROM staff GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1)
+ORDER BY 1, 3, 2;
@@ -0,0 +1,5 @@
+-- Les emails sont enregistrés sans espaces autour et en minuscules : l'unicité et la connexion
+-- ne dépendent plus de la casse. Les doublons qui en résulteraient sont refusés par 0012_normalize_emails.check.sql.
+UPDATE users SET email = LOWER(TRIM(email));
+UPDATE guardians SET email = LOWER(TRIM(email));
+UPDATE staff SET email = LOWER(TRIM(email));
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE users DROP COLUMN updated_at;
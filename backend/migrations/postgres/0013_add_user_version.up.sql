@@ -0,0 +1,4 @@
+-- Version et date de dernière modification des usagers (ETag, If-Match)
+ALTER TABLE users ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+UPDATE users SET updated_at = created_at;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL,
+	email TEXT NOT NULL UNIQUE,
+	date_naissance TEXT NOT NULL,
+	niveau_natation TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Trigger vide créé par les anciennes versions de initDB (l'âge est calculé côté application)
+DROP TRIGGER IF EXISTS calculate_age;
@@ -0,0 +1,26 @@
+CREATE TABLE courses (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	level TEXT NOT NULL,
+	session TEXT NOT NULL,
+	weekday INTEGER NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+	start_time TEXT NOT NULL,
+	end_time TEXT NOT NULL,
+	start_date TEXT NOT NULL,
+	end_date TEXT NOT NULL,
+	pool TEXT NOT NULL,
+	capacity INTEGER NOT NULL CHECK (capacity > 0),
+	instructor TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_courses_session ON courses (session);
+
+CREATE TABLE enrollments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (course_id, user_id)
+);
+
+CREATE INDEX idx_enrollments_user ON enrollments (user_id);
@@ -0,0 +1,11 @@
+CREATE TABLE waitlist_entries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (course_id, user_id)
+);
+
+CREATE INDEX idx_waitlist_entries_course ON waitlist_entries (course_id, position);
+CREATE INDEX idx_waitlist_entries_user ON waitlist_entries (user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE evaluations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	level TEXT NOT NULL,
+	criteria TEXT NOT NULL DEFAULT '[]',
+	passed BOOLEAN NOT NULL,
+	evaluator TEXT NOT NULL,
+	evaluated_on TEXT NOT NULL,
+	previous_level TEXT NOT NULL,
+	new_level TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_evaluations_user ON evaluations (user_id, evaluated_on);
@@ -0,0 +1,2 @@
+ALTER TABLE guardians DROP COLUMN tokens_revoked_at;
+ALTER TABLE guardians DROP COLUMN password_hash;
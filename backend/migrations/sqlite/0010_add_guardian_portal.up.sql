@@ -0,0 +1,3 @@
+-- Accès des tuteurs au portail parents : sans mot de passe, le tuteur n'a pas accès
+ALTER TABLE guardians ADD COLUMN password_hash TEXT;
+ALTER TABLE guardians ADD COLUMN tokens_revoked_at DATETIME; -- Les jetons émis avant cette date sont refusés
@@ -0,0 +1,12 @@
+-- Clés d'API des intégrations (borne d'inscription, scripts de rapports)
+CREATE TABLE api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL, -- Début de la clé, affiché pour la reconnaître
+	key_hash TEXT NOT NULL UNIQUE, -- SHA-256 de la clé; la clé elle-même n'est jamais stockée
+	scopes TEXT NOT NULL, -- Portées séparées par des espaces
+	created_by INTEGER REFERENCES staff (id) ON DELETE SET NULL,
+	last_used_at DATETIME,
+	revoked_at DATETIME,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- La casse d'origine des emails n'est pas conservée : rien à rétablir
+SELECT 1;
@@ -0,0 +1,17 @@
+CREATE TABLE users_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	date_naissance TEXT NOT NULL,
+	niveau_natation TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO users_new (id, first_name, last_name, email, date_naissance, niveau_natation, created_at)
+SELECT id, first_name, last_name, email, date_naissance, niveau_natation, created_at FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE INDEX idx_users_email ON users (email);
@@ -0,0 +1,22 @@
+-- Version et date de dernière modification des usagers (ETag, If-Match).
+-- SQLite n'accepte pas CURRENT_TIMESTAMP comme défaut d'une colonne ajoutée : la table est reconstruite
+-- (les clés étrangères sont désactivées par le moteur de migrations).
+CREATE TABLE users_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	date_naissance TEXT NOT NULL,
+	niveau_natation TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1
+);
+
+INSERT INTO users_new (id, first_name, last_name, email, date_naissance, niveau_natation, created_at, updated_at)
+SELECT id, first_name, last_name, email, date_naissance, niveau_natation, created_at, created_at FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE INDEX idx_users_email ON users (email);
@@ -4,22 +4,25 @@ import "time"
 
 // User représente un usager
 type User struct {
-	ID            int       `json:"id"`
-	FirstName     string    `json:"first_name"`
-	LastName      string    `json:"last_name"`
-	Email         string    `json:"email"`
-	DateNaissance string    `json:"date_naissance"` // Format: YYYY-MM-DD
-	Age           int       `json:"age"`            // Calculé à partir de date_naissance
-	NiveauNatation string   `json:"niveau_natation"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID             int           `json:"id"`
+	FirstName      string        `json:"first_name"`
+	LastName       string        `json:"last_name"`
+	Email          string        `json:"email"`
+	DateNaissance  string        `json:"date_naissance"` // Format: YYYY-MM-DD
+	Age            int           `json:"age"`            // Calculé à partir de date_naissance
+	NiveauNatation string        `json:"niveau_natation"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Version        int           `json:"version"`            // Incrémentée à chaque modification; sert d'ETag
+	Warnings       []AgeMismatch `json:"warnings,omitempty"` // Âge incompatible avec le niveau (mode AGE_CHECK=warn)
 }
 
 // UserRequest représente les données pour créer/modifier un usager
 type UserRequest struct {
-	FirstName     string `json:"first_name" binding:"required"`
-	LastName      string `json:"last_name" binding:"required"`
-	Email         string `json:"email" binding:"required,email"`
-	DateNaissance string `json:"date_naissance" binding:"required"`
+	FirstName      string `json:"first_name"` // Noms et email normalisés puis vérifiés (validateUserRequest)
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`                             // Facultatif : un enfant peut être joint par ses tuteurs
+	DateNaissance  string `json:"date_naissance" binding:"required"` // YYYY-MM-DD, dans le passé et plausible (validateUserRequest)
 	NiveauNatation string `json:"niveau_natation" binding:"required"`
 }
 
@@ -32,3 +35,343 @@ type UsersResponse struct {
 	TotalPages int    `json:"total_pages"`
 }
 
+// Course représente un cours de natation offert pendant une session
+type Course struct {
+	ID         int       `json:"id"`
+	Level      string    `json:"level"`      // Niveau de natation requis
+	Session    string    `json:"session"`    // Ex: "Automne 2024"
+	Weekday    int       `json:"weekday"`    // 1 = lundi ... 7 = dimanche
+	StartTime  string    `json:"start_time"` // Format: HH:MM
+	EndTime    string    `json:"end_time"`   // Format: HH:MM
+	StartDate  string    `json:"start_date"` // Début de la session, format: YYYY-MM-DD
+	EndDate    string    `json:"end_date"`   // Fin de la session, format: YYYY-MM-DD
+	Pool       string    `json:"pool"`
+	Capacity   int       `json:"capacity"`
+	Instructor string    `json:"instructor"`
+	Enrolled   int       `json:"enrolled"`   // Nombre d'usagers inscrits
+	Waitlisted int       `json:"waitlisted"` // Nombre d'usagers en liste d'attente
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CourseRequest représente les données pour créer/modifier un cours
+type CourseRequest struct {
+	Level      string `json:"level" binding:"required"`
+	Session    string `json:"session" binding:"required"`
+	Weekday    int    `json:"weekday" binding:"required,min=1,max=7"`
+	StartTime  string `json:"start_time" binding:"required,datetime=15:04"`
+	EndTime    string `json:"end_time" binding:"required,datetime=15:04"`
+	StartDate  string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate    string `json:"end_date" binding:"required,datetime=2006-01-02"`
+	Pool       string `json:"pool" binding:"required"`
+	Capacity   int    `json:"capacity" binding:"required,min=1"`
+	Instructor string `json:"instructor"`
+}
+
+// CourseFilter regroupe les filtres de la liste des cours
+type CourseFilter struct {
+	Session string
+	Level   string
+	UserID  int // Cours auxquels l'usager est inscrit, si non nul
+}
+
+// Enrollment représente l'inscription d'un usager à un cours
+type Enrollment struct {
+	ID        int           `json:"id"`
+	CourseID  int           `json:"course_id"`
+	UserID    int           `json:"user_id"`
+	User      *User         `json:"user,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Warnings  []AgeMismatch `json:"warnings,omitempty"` // Âge incompatible avec le niveau du cours (mode AGE_CHECK=warn)
+}
+
+// EnrollmentRequest représente les données pour inscrire un usager à un cours
+type EnrollmentRequest struct {
+	UserID int `json:"user_id" binding:"required,min=1"`
+}
+
+// WaitlistEntry représente un usager en liste d'attente d'un cours complet
+type WaitlistEntry struct {
+	ID        int           `json:"id"`
+	CourseID  int           `json:"course_id"`
+	UserID    int           `json:"user_id"`
+	Position  int           `json:"position"` // Rang dans la liste d'attente, à partir de 1
+	User      *User         `json:"user,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Warnings  []AgeMismatch `json:"warnings,omitempty"` // Âge incompatible avec le niveau du cours (mode AGE_CHECK=warn)
+}
+
+// WaitlistOrderRequest représente le nouvel ordre complet d'une liste d'attente
+type WaitlistOrderRequest struct {
+	UserIDs []int `json:"user_ids" binding:"required"`
+}
+
+// EnrollmentResult est le résultat d'une demande d'inscription :
+// soit une inscription, soit une place en liste d'attente si le cours est complet
+type EnrollmentResult struct {
+	Enrollment *Enrollment
+	Waitlist   *WaitlistEntry
+}
+
+// CriterionResult représente le résultat d'un critère d'évaluation
+type CriterionResult struct {
+	Name    string `json:"name" binding:"required"`
+	Passed  bool   `json:"passed"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Evaluation représente l'évaluation d'un usager à son niveau de natation
+type Evaluation struct {
+	ID            int               `json:"id"`
+	UserID        int               `json:"user_id"`
+	Level         string            `json:"level"` // Niveau évalué
+	Criteria      []CriterionResult `json:"criteria"`
+	Passed        bool              `json:"passed"`
+	Evaluator     string            `json:"evaluator"`
+	EvaluatedOn   string            `json:"evaluated_on"`   // Format: YYYY-MM-DD
+	PreviousLevel string            `json:"previous_level"` // Niveau de l'usager avant l'évaluation
+	NewLevel      string            `json:"new_level"`      // Niveau de l'usager après l'évaluation
+	CreatedAt     time.Time         `json:"created_at"`
+	Warnings      []AgeMismatch     `json:"warnings,omitempty"` // Âge incompatible avec le nouveau niveau (mode AGE_CHECK=warn)
+}
+
+// EvaluationRequest représente les données pour enregistrer une évaluation
+type EvaluationRequest struct {
+	Level       string            `json:"level"` // Optionnel : code ou libellé du niveau actuel de l'usager
+	Criteria    []CriterionResult `json:"criteria" binding:"dive"`
+	Passed      *bool             `json:"passed" binding:"required"`
+	Evaluator   string            `json:"evaluator" binding:"required"`
+	EvaluatedOn string            `json:"evaluated_on" binding:"omitempty,datetime=2006-01-02"` // Défaut: aujourd'hui
+}
+
+// Guardian représente un parent ou tuteur, contact d'un ou plusieurs usagers
+type Guardian struct {
+	ID           int       `json:"id"`
+	FirstName    string    `json:"first_name"`
+	LastName     string    `json:"last_name"`
+	Email        string    `json:"email"`
+	Phone        string    `json:"phone"`
+	PortalAccess bool      `json:"portal_access"` // Le tuteur a un mot de passe pour le portail parents
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GuardianRequest représente les données pour créer/modifier un tuteur
+type GuardianRequest struct {
+	FirstName string `json:"first_name"` // Noms et email normalisés puis vérifiés (validatePersonFields)
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+// GuardianLinkRequest représente les données pour rattacher un usager à un tuteur
+type GuardianLinkRequest struct {
+	UserID       int    `json:"user_id" binding:"required"`
+	Relationship string `json:"relationship"` // ex: "mère", "père", "tuteur"
+}
+
+// Child représente un usager rattaché à un tuteur
+type Child struct {
+	User
+	Relationship string `json:"relationship"`
+}
+
+// UserGuardian représente un tuteur d'un usager
+type UserGuardian struct {
+	Guardian
+	Relationship string `json:"relationship"`
+}
+
+// Statuts de présence à une séance
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceExcused = "excused" // Absence motivée, exclue du taux de présence
+)
+
+// Meeting représente une séance d'un cours
+type Meeting struct {
+	ID        int       `json:"id"`
+	CourseID  int       `json:"course_id"`
+	Date      string    `json:"date"` // Format: YYYY-MM-DD
+	StartTime string    `json:"start_time"`
+	EndTime   string    `json:"end_time"`
+	Present   int       `json:"present"`
+	Absent    int       `json:"absent"`
+	Excused   int       `json:"excused"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MeetingsRequest représente les options de génération du calendrier d'un cours
+type MeetingsRequest struct {
+	ExcludeDates []string `json:"exclude_dates" binding:"dive,datetime=2006-01-02"` // Jours fériés, congés...
+}
+
+// AttendanceMark représente la présence d'un usager à marquer
+type AttendanceMark struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Status string `json:"status" binding:"required,oneof=present absent excused"`
+	Note   string `json:"note"`
+}
+
+// AttendanceRequest représente les présences d'une séance à enregistrer en une fois
+type AttendanceRequest struct {
+	Records []AttendanceMark `json:"records" binding:"required,min=1,dive"`
+}
+
+// AttendanceRecord représente la présence enregistrée d'un usager à une séance
+type AttendanceRecord struct {
+	MeetingID  int       `json:"meeting_id"`
+	UserID     int       `json:"user_id"`
+	Status     string    `json:"status"`
+	Note       string    `json:"note"`
+	User       *User     `json:"user,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// AttendanceStats regroupe les présences et le taux de présence (present / (present + absent))
+type AttendanceStats struct {
+	Present int      `json:"present"`
+	Absent  int      `json:"absent"`
+	Excused int      `json:"excused"`
+	Rate    *float64 `json:"rate"` // nil si aucune présence ou absence n'est enregistrée
+}
+
+// UserAttendanceStats représente les présences d'un usager à un cours
+type UserAttendanceStats struct {
+	UserID int   `json:"user_id"`
+	User   *User `json:"user,omitempty"`
+	AttendanceStats
+}
+
+// CourseAttendanceStats représente les présences d'un usager à l'un de ses cours
+type CourseAttendanceStats struct {
+	CourseID int    `json:"course_id"`
+	Level    string `json:"level"`
+	Session  string `json:"session"`
+	AttendanceStats
+}
+
+// CourseAttendanceReport représente les taux de présence d'un cours, au total et par usager
+type CourseAttendanceReport struct {
+	CourseID int `json:"course_id"`
+	Meetings int `json:"meetings"`
+	AttendanceStats
+	Users []UserAttendanceStats `json:"users"`
+}
+
+// UserAttendanceReport représente les taux de présence d'un usager, au total et par cours
+type UserAttendanceReport struct {
+	UserID int `json:"user_id"`
+	AttendanceStats
+	Courses []CourseAttendanceStats `json:"courses"`
+}
+
+// Staff représente un compte du personnel autorisé à utiliser l'API
+type Staff struct {
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	Role            string     `json:"role"`                  // admin, coordinator, instructor ou readonly
+	Permissions     []string   `json:"permissions,omitempty"` // Permissions accordées par le rôle
+	PasswordHash    string     `json:"-"`
+	TokensRevokedAt *time.Time `json:"-"` // Les jetons émis avant cette date sont refusés
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// LoginRequest représente les identifiants de connexion d'un membre du personnel
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest représente une demande de renouvellement des jetons
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest représente une demande de déconnexion; le jeton de rafraîchissement est optionnel
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenPair représente les jetons émis à la connexion ou au renouvellement
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"` // Toujours "Bearer"
+	ExpiresIn    int    `json:"expires_in"` // Durée de validité du jeton d'accès, en secondes
+}
+
+// TokenResponse représente les jetons émis à un membre du personnel
+type TokenResponse struct {
+	TokenPair
+	Staff Staff `json:"staff"`
+}
+
+// GuardianAccount représente les identifiants d'un tuteur pour le portail parents
+type GuardianAccount struct {
+	Guardian
+	PasswordHash    string     // Vide si le tuteur n'a pas accès au portail
+	TokensRevokedAt *time.Time // Les jetons émis avant cette date sont refusés
+}
+
+// PortalTokenResponse représente les jetons émis à un tuteur sur le portail parents
+type PortalTokenResponse struct {
+	TokenPair
+	Guardian Guardian `json:"guardian"`
+}
+
+// PortalAccessRequest représente le mot de passe initial d'un tuteur, défini par le personnel
+type PortalAccessRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// PortalPasswordRequest représente un changement de mot de passe par le tuteur
+type PortalPasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// PortalContactRequest représente les coordonnées d'un enfant modifiables par son tuteur.
+// La date de naissance et le niveau restent gérés par le personnel.
+type PortalContactRequest struct {
+	FirstName string `json:"first_name"` // Noms et email normalisés puis vérifiés (validateUserFields)
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"` // Facultatif, comme pour UserRequest
+}
+
+// APIKey représente une clé d'API d'une intégration. La clé n'est affichée qu'à sa création ou à sa rotation.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // Début de la clé, pour la reconnaître
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *int       `json:"created_by"` // Compte du personnel qui a créé la clé
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyRequest représente les données pour créer une clé d'API
+type APIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=users:read users:write export"`
+}
+
+// APIKeyResponse représente une clé d'API créée ou renouvelée, avec la clé en clair
+type APIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// HealthResponse représente l'état du serveur (/healthz) ou de ses dépendances (/readyz)
+type HealthResponse struct {
+	Status     string                     `json:"status"` // ok ou error
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth représente l'état d'une dépendance vérifiée par /readyz
+type ComponentHealth struct {
+	Status     string `json:"status"` // ok ou error
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
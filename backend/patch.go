@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Formats des modifications partielles (PATCH)
+const (
+	mergePatchContentType = "application/merge-patch+json" // JSON Merge Patch (RFC 7396)
+	jsonPatchContentType  = "application/json-patch+json"  // JSON Patch (RFC 6902)
+)
+
+// acceptPatch liste les formats acceptés, pour l'en-tête Accept-Patch (RFC 5789)
+const acceptPatch = mergePatchContentType + ", " + jsonPatchContentType
+
+// patchDocument est une modification partielle lue depuis le corps d'une requête PATCH
+type patchDocument interface {
+	// apply retourne le document modifié; doc peut être modifié en place
+	apply(doc any) (any, error)
+}
+
+// parsePatch lit une modification partielle d'après son type de contenu. application/json est lu
+// comme un JSON Merge Patch : un objet avec les seuls champs à modifier.
+// Les erreurs de forme du patch sont des apiError (voir respondError).
+func parsePatch(contentType string, body []byte) (patchDocument, error) {
+	if len(body) == 0 {
+		return nil, newAPIError(http.StatusBadRequest, CodeInvalidBody, "Corps de la requête manquant")
+	}
+	switch contentType {
+	case mergePatchContentType, "application/json":
+		var patch any
+		if err := json.Unmarshal(body, &patch); err != nil {
+			return nil, newAPIError(http.StatusBadRequest, CodeInvalidBody, "JSON invalide : %s", err.Error())
+		}
+		return mergePatch{patch: patch}, nil
+	case jsonPatchContentType:
+		var ops jsonPatch
+		if err := json.Unmarshal(body, &ops); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return nil, newAPIError(http.StatusBadRequest, CodeInvalidPatch, "JSON Patch : un tableau d'opérations est attendu")
+			}
+			return nil, newAPIError(http.StatusBadRequest, CodeInvalidBody, "JSON invalide : %s", err.Error())
+		}
+		if err := ops.check(); err != nil {
+			return nil, err
+		}
+		return ops, nil
+	}
+	return nil, newAPIError(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+		"Type de contenu non pris en charge : %s (types acceptés : %s)", contentType, acceptPatch)
+}
+
+// mergePatch est un JSON Merge Patch (RFC 7396) : les champs présents remplacent ceux du document,
+// récursivement pour les objets, et null retire le champ
+type mergePatch struct {
+	patch any
+}
+
+func (p mergePatch) apply(doc any) (any, error) {
+	return mergeValues(doc, p.patch), nil
+}
+
+// mergeValues applique patch à target selon l'algorithme de la RFC 7396
+func mergeValues(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = mergeValues(targetObj[name], value)
+	}
+	return targetObj
+}
+
+// patchOperation est une opération d'un JSON Patch (RFC 6902)
+type patchOperation struct {
+	Op    string          `json:"op"` // add, remove, replace, move, copy ou test
+	Path  string          `json:"path"`
+	From  string          `json:"from"`  // move et copy
+	Value json.RawMessage `json:"value"` // add, replace et test; nil si absent, "null" si null
+}
+
+// jsonPatch est un JSON Patch (RFC 6902) : une suite d'opérations appliquées dans l'ordre.
+// Si une opération échoue, aucune modification n'est enregistrée.
+type jsonPatch []patchOperation
+
+// check vérifie la forme des opérations avant de les appliquer
+func (p jsonPatch) check() error {
+	for i, op := range p {
+		n := i + 1 // Numéro de l'opération dans les messages
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return newAPIError(http.StatusBadRequest, CodeInvalidPatch, "Opération %d : champ value requis", n)
+			}
+		case "move", "copy":
+			if _, ok := parsePointer(op.From); !ok {
+				return newAPIError(http.StatusBadRequest, CodeInvalidPatch, "Opération %d : chemin invalide : %s", n, op.From)
+			}
+		case "remove":
+		default:
+			return newAPIError(http.StatusBadRequest, CodeInvalidPatch, "Opération %d : opération inconnue : %s", n, op.Op)
+		}
+		if _, ok := parsePointer(op.Path); !ok {
+			return newAPIError(http.StatusBadRequest, CodeInvalidPatch, "Opération %d : chemin invalide : %s", n, op.Path)
+		}
+	}
+	return nil
+}
+
+func (p jsonPatch) apply(doc any) (any, error) {
+	for i, op := range p {
+		n := i + 1
+		path, _ := parsePointer(op.Path)
+		notFound := func(pointer string) error {
+			return newAPIError(http.StatusConflict, CodePatchConflict, "Opération %d : chemin introuvable : %s", n, pointer)
+		}
+
+		var value any
+		if op.Value != nil {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, newAPIError(http.StatusBadRequest, CodeInvalidBody, "JSON invalide : %s", err.Error())
+			}
+		}
+		var ok bool
+		switch op.Op {
+		case "add":
+			doc, ok = addValue(doc, path, value)
+		case "remove":
+			doc, _, ok = removeValue(doc, path)
+		case "replace":
+			if _, ok = getValue(doc, path); ok {
+				doc, _, _ = removeValue(doc, path)
+				doc, ok = addValue(doc, path, value)
+			}
+		case "move":
+			from, _ := parsePointer(op.From)
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, newAPIError(http.StatusBadRequest, CodeInvalidPatch, "Opération %d : impossible de déplacer une valeur dans elle-même", n)
+			}
+			if doc, value, ok = removeValue(doc, from); !ok {
+				return nil, notFound(op.From)
+			}
+			doc, ok = addValue(doc, path, value)
+		case "copy":
+			from, _ := parsePointer(op.From)
+			if value, ok = getValue(doc, from); !ok {
+				return nil, notFound(op.From)
+			}
+			doc, ok = addValue(doc, path, copyValue(value))
+		case "test":
+			var current any
+			if current, ok = getValue(doc, path); ok && !reflect.DeepEqual(current, value) {
+				return nil, newAPIError(http.StatusConflict, CodePatchTestFailed, "Opération %d : la valeur de %s n'est pas celle attendue", n, op.Path)
+			}
+		}
+		if !ok {
+			return nil, notFound(op.Path)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer découpe un JSON Pointer (RFC 6901), ex: "/a~1b/0" -> ["a/b", "0"].
+// Le pointeur vide désigne le document entier.
+func parsePointer(pointer string) ([]string, bool) {
+	if pointer == "" {
+		return nil, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, true
+}
+
+// arrayIndex retourne l'indice d'un tableau de longueur n désigné par token.
+// "-" désigne la fin du tableau, permise seulement pour un ajout (insert).
+func arrayIndex(token string, n int, insert bool) (int, bool) {
+	if token == "-" && insert {
+		return n, true
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > n || (i == n && !insert) {
+		return 0, false
+	}
+	return i, true
+}
+
+// getValue retourne la valeur désignée par path
+func getValue(doc any, path []string) (any, bool) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			var ok bool
+			if doc, ok = node[token]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, ok := arrayIndex(token, len(node), false)
+			if !ok {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// addValue ajoute value à path et retourne le document modifié : le champ d'un objet est créé
+// ou remplacé, la valeur est insérée dans un tableau. Le parent de path doit exister.
+func addValue(doc any, path []string, value any) (any, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+	token, last := path[0], len(path) == 1
+	switch node := doc.(type) {
+	case map[string]any:
+		if last {
+			node[token] = value
+			return node, true
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, false
+		}
+		if node[token], ok = addValue(child, path[1:], value); !ok {
+			return nil, false
+		}
+		return node, true
+	case []any:
+		i, ok := arrayIndex(token, len(node), last)
+		if !ok {
+			return nil, false
+		}
+		if last {
+			node = append(node[:i], append([]any{value}, node[i:]...)...)
+			return node, true
+		}
+		if node[i], ok = addValue(node[i], path[1:], value); !ok {
+			return nil, false
+		}
+		return node, true
+	}
+	return nil, false
+}
+
+// removeValue retire la valeur désignée par path et retourne le document modifié et la valeur retirée
+func removeValue(doc any, path []string) (any, any, bool) {
+	if len(path) == 0 {
+		return nil, doc, true
+	}
+	token, last := path[0], len(path) == 1
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, false
+		}
+		if last {
+			delete(node, token)
+			return node, child, true
+		}
+		var removed any
+		if node[token], removed, ok = removeValue(child, path[1:]); !ok {
+			return nil, nil, false
+		}
+		return node, removed, true
+	case []any:
+		i, ok := arrayIndex(token, len(node), false)
+		if !ok {
+			return nil, nil, false
+		}
+		if last {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, true
+		}
+		var removed any
+		if node[i], removed, ok = removeValue(node[i], path[1:]); !ok {
+			return nil, nil, false
+		}
+		return node, removed, true
+	}
+	return nil, nil, false
+}
+
+// copyValue retourne une copie profonde d'une valeur JSON, pour qu'une copie ne partage pas ses objets avec l'original
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for name, child := range v {
+			copied[name] = copyValue(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, child := range v {
+			copied[i] = copyValue(child)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyPatch applique un patch JSON à un document JSON et retourne le résultat en JSON
+func applyPatch(t *testing.T, contentType, doc, patch string) (string, error) {
+	t.Helper()
+	p, err := parsePatch(contentType, []byte(patch))
+	if err != nil {
+		return "", err
+	}
+	var target any
+	require.NoError(t, json.Unmarshal([]byte(doc), &target))
+	result, err := p.apply(target)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	return string(data), nil
+}
+
+func TestMergePatch(t *testing.T) {
+	// Exemples de la RFC 7396
+	tests := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`{"a":"foo"}`, `["c"]`, `["c"]`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range tests {
+		got, err := applyPatch(t, mergePatchContentType, tc.doc, tc.patch)
+		require.NoError(t, err, tc.patch)
+		assert.JSONEq(t, tc.want, got, tc.patch)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	// Exemples de la RFC 6902 (annexe A)
+	tests := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":null}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`, `{"a":{"b":[1]},"c":{"b":[1,2]}}`},
+	}
+	for _, tc := range tests {
+		got, err := applyPatch(t, jsonPatchContentType, tc.doc, tc.patch)
+		require.NoError(t, err, tc.patch)
+		assert.JSONEq(t, tc.want, got, tc.patch)
+	}
+
+	errs := []struct {
+		doc, patch string
+		code       string
+	}{
+		{`{"foo":"bar"}`, `{"op":"add"}`, CodeInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, CodeInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"upsert","path":"/baz","value":1}]`, CodeInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"baz"}]`, CodeInvalidPatch},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`, CodeInvalidPatch},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, CodePatchConflict},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"qux"}]`, CodePatchConflict},
+		{`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/2"}]`, CodePatchConflict},
+		{`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`, CodePatchConflict},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, CodePatchTestFailed},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/foo","value":1,}]`, CodeInvalidBody},
+	}
+	for _, tc := range errs {
+		_, err := applyPatch(t, jsonPatchContentType, tc.doc, tc.patch)
+		var e apiError
+		require.ErrorAs(t, err, &e, tc.patch)
+		assert.Equal(t, tc.code, e.Code, tc.patch)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	w := performRequest(r, "POST", "/api/users", UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	path := "/api/users/" + strconv.Itoa(created.ID)
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		return performRequestWithHeaders(r, "PATCH", path, map[string]string{"Content-Type": contentType}, json.RawMessage(body))
+	}
+
+	// JSON Merge Patch : seuls les champs fournis changent, normalisés comme à la création
+	w = patch(mergePatchContentType, `{"niveau_natation": "NAGEUR_4", "email": " Jean.Dupont@Test.com "}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "NAGEUR 4", u.NiveauNatation)
+	assert.Equal(t, "jean.dupont@test.com", u.Email)
+	assert.Equal(t, created.FirstName, u.FirstName)
+	assert.Equal(t, created.DateNaissance, u.DateNaissance)
+
+	// application/json est lu comme un JSON Merge Patch
+	w = patch("application/json", `{"last_name": "Martin"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "Martin", u.LastName)
+
+	// JSON Patch, avec une opération test
+	w = patch(jsonPatchContentType, `[{"op": "test", "path": "/last_name", "value": "Martin"}, {"op": "replace", "path": "/first_name", "value": "Paul"}]`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "Paul", u.FirstName)
+
+	w = patch(jsonPatchContentType, `[{"op": "test", "path": "/last_name", "value": "Dupont"}, {"op": "replace", "path": "/first_name", "value": "Luc"}]`)
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodePatchTestFailed, decodeProblem(t, w).Code)
+
+	// Le résultat est validé comme à la création
+	w = patch(mergePatchContentType, `{"first_name": null, "date_naissance": "2015-02-30"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p := decodeProblem(t, w)
+	fields := map[string]string{}
+	for _, fe := range p.Errors {
+		fields[fe.Field] = fe.Code
+	}
+	assert.Equal(t, map[string]string{"first_name": "required", "date_naissance": "date"}, fields)
+
+	w = patch(jsonPatchContentType, `[{"op": "remove", "path": "/niveau_natation"}]`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p = decodeProblem(t, w)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, FieldError{Field: "niveau_natation", Code: "required", Message: "Champ requis"}, p.Errors[0])
+
+	w = patch(mergePatchContentType, `{"age": 3, "id": 42}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p = decodeProblem(t, w)
+	require.Len(t, p.Errors, 2)
+	assert.Equal(t, "age", p.Errors[0].Field)
+	assert.Equal(t, "read_only", p.Errors[1].Code)
+
+	w = patch(mergePatchContentType, `{"first_name": 42}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "type", decodeProblem(t, w).Errors[0].Code)
+
+	// Les modifications refusées ne sont pas enregistrées
+	w = performRequest(r, "GET", path, nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "Paul", u.FirstName)
+	assert.Equal(t, "NAGEUR 4", u.NiveauNatation)
+
+	// Type de contenu, corps et usager
+	w = patch("text/plain", `{"first_name": "Luc"}`)
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, CodeUnsupportedMediaType, decodeProblem(t, w).Code)
+	assert.Equal(t, acceptPatch, w.Header().Get("Accept-Patch"))
+
+	w = patch(jsonPatchContentType, `{"op": "remove", "path": "/email"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeInvalidPatch, decodeProblem(t, w).Code)
+
+	w = patch(jsonPatchContentType, `[{"op": "remove", "path": "/phone"}]`)
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodePatchConflict, decodeProblem(t, w).Code)
+
+	w = performRequestWithHeaders(r, "PATCH", "/api/users/999", map[string]string{"Content-Type": mergePatchContentType}, json.RawMessage(`{"last_name": "Martin"}`))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, CodeUserNotFound, decodeProblem(t, w).Code)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGuardianPassword = "mot-de-passe-du-parent"
+
+// loginTestGuardian connecte un tuteur au portail parents et retourne ses jetons
+func loginTestGuardian(t *testing.T, r http.Handler, email string) PortalTokenResponse {
+	w := performRequest(r, "POST", "/api/portal/login", LoginRequest{Email: email, Password: testGuardianPassword})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tokens PortalTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	return tokens
+}
+
+func TestPortalChildrenAccess(t *testing.T) {
+	r, store, _ := setupAuthRouter(t)
+	admin := loginTestStaff(t, r, "admin@test.com").AccessToken
+	createTestStaff(t, store, "moniteur@test.com", RoleInstructor)
+	instructor := loginTestStaff(t, r, "moniteur@test.com").AccessToken
+
+	guardian, err := store.CreateGuardian(context.Background(), GuardianRequest{FirstName: "Julie", LastName: "Roy", Email: "julie.roy@test.com"})
+	require.NoError(t, err)
+	childID := insertTestUser(t, store.db, "lea.roy@test.com", "NAGEUR 3")
+	otherID := insertTestUser(t, store.db, "autre@test.com", "NAGEUR 3")
+	_, err = store.LinkChild(context.Background(), guardian.ID, GuardianLinkRequest{UserID: childID, Relationship: "mère"})
+	require.NoError(t, err)
+
+	// Sans mot de passe, le tuteur n'a pas accès au portail
+	w := performRequest(r, "POST", "/api/portal/login", LoginRequest{Email: "julie.roy@test.com", Password: testGuardianPassword})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	accessPath := "/api/guardians/" + strconv.Itoa(guardian.ID) + "/portal-access"
+	w = performRequestWithToken(r, "PUT", accessPath, instructor, PortalAccessRequest{Password: testGuardianPassword})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequestWithToken(r, "PUT", accessPath, admin, PortalAccessRequest{Password: "court"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequestWithToken(r, "PUT", "/api/guardians/999/portal-access", admin, PortalAccessRequest{Password: testGuardianPassword})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = performRequestWithToken(r, "PUT", accessPath, admin, PortalAccessRequest{Password: testGuardianPassword})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	tokens := loginTestGuardian(t, r, "julie.roy@test.com")
+	assert.Equal(t, guardian.ID, tokens.Guardian.ID)
+	assert.True(t, tokens.Guardian.PortalAccess)
+	parent := tokens.AccessToken
+
+	// Les jetons du portail et ceux du personnel ne sont pas interchangeables
+	w = performRequestWithToken(r, "GET", "/api/users", parent, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/portal/me", admin, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/auth/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequestWithToken(r, "GET", "/api/portal/children", parent, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var household struct {
+		Children []Child `json:"children"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &household))
+	require.Len(t, household.Children, 1)
+	assert.Equal(t, childID, household.Children[0].ID)
+
+	// Un usager non rattaché est introuvable, qu'il existe ou non
+	childPath := "/api/portal/children/" + strconv.Itoa(childID)
+	w = performRequestWithToken(r, "GET", childPath, parent, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	for _, path := range []string{"/api/portal/children/" + strconv.Itoa(otherID), "/api/portal/children/999"} {
+		w = performRequestWithToken(r, "GET", path, parent, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = performRequestWithToken(r, "GET", path+"/evaluations", parent, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+
+	// Le tuteur modifie les coordonnées, pas le niveau ni la date de naissance
+	w = performRequestWithToken(r, "PUT", childPath, parent, UserRequest{
+		FirstName: "Léa", LastName: "Roy", Email: "julie.roy@test.com", DateNaissance: "2020-01-01", NiveauNatation: "NAGEUR 8",
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var child User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &child))
+	assert.Equal(t, "Léa", child.FirstName)
+	assert.Equal(t, "julie.roy@test.com", child.Email)
+	assert.Equal(t, "2015-05-15", child.DateNaissance)
+	assert.Equal(t, "NAGEUR 3", child.NiveauNatation)
+	w = performRequestWithToken(r, "PUT", "/api/portal/children/"+strconv.Itoa(otherID), parent, PortalContactRequest{FirstName: "X", LastName: "Y", Email: "x@test.com"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Horaire, évaluations et présences de l'enfant
+	course := testCourseRequest("NAGEUR 3", 8)
+	w = performRequestWithToken(r, "POST", "/api/courses", admin, course)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created Course
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	w = performRequestWithToken(r, "POST", "/api/courses/"+strconv.Itoa(created.ID)+"/enrollments", admin, EnrollmentRequest{UserID: childID})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = performRequestWithToken(r, "POST", "/api/courses", admin, course)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = performRequestWithToken(r, "GET", childPath+"/courses", parent, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var schedule struct {
+		Courses []Course `json:"courses"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &schedule))
+	require.Len(t, schedule.Courses, 1)
+	assert.Equal(t, created.ID, schedule.Courses[0].ID)
+
+	w = performRequestWithToken(r, "GET", childPath+"/evaluations", parent, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = performRequestWithToken(r, "GET", childPath+"/attendance", parent, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Aucune opération d'écriture du personnel n'est exposée au portail
+	passed := true
+	w = performRequestWithToken(r, "POST", childPath+"/evaluations", parent, EvaluationRequest{Passed: &passed, Evaluator: "Parent"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPortalPasswordAndRevocation(t *testing.T) {
+	r, store, _ := setupAuthRouter(t)
+	admin := loginTestStaff(t, r, "admin@test.com").AccessToken
+	guardian, err := store.CreateGuardian(context.Background(), GuardianRequest{FirstName: "Marc", LastName: "Roy", Email: "marc.roy@test.com"})
+	require.NoError(t, err)
+	accessPath := "/api/guardians/" + strconv.Itoa(guardian.ID) + "/portal-access"
+	w := performRequestWithToken(r, "PUT", accessPath, admin, PortalAccessRequest{Password: testGuardianPassword})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	tokens := loginTestGuardian(t, r, "marc.roy@test.com")
+	w = performRequest(r, "POST", "/api/portal/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	require.Equal(t, http.StatusOK, w.Code)
+	var refreshed PortalTokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &refreshed))
+	w = performRequest(r, "POST", "/api/portal/refresh", RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = performRequestWithToken(r, "PUT", "/api/portal/password", refreshed.AccessToken, PortalPasswordRequest{CurrentPassword: "mauvais-mot-de-passe", NewPassword: "nouveau-mot-de-passe"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = performRequestWithToken(r, "PUT", "/api/portal/password", refreshed.AccessToken, PortalPasswordRequest{CurrentPassword: testGuardianPassword, NewPassword: "nouveau-mot-de-passe"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Le changement de mot de passe révoque les jetons déjà émis
+	w = performRequestWithToken(r, "GET", "/api/portal/me", refreshed.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/portal/login", LoginRequest{Email: "marc.roy@test.com", Password: "nouveau-mot-de-passe"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+
+	// Le personnel retire l'accès : jetons refusés et connexion impossible
+	w = performRequestWithToken(r, "DELETE", accessPath, admin, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = performRequestWithToken(r, "GET", "/api/portal/me", tokens.AccessToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = performRequest(r, "POST", "/api/portal/login", LoginRequest{Email: "marc.roy@test.com", Password: "nouveau-mot-de-passe"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	g, err := store.GetGuardian(context.Background(), guardian.ID)
+	require.NoError(t, err)
+	assert.False(t, g.PortalAccess)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// problemContentType est le type des réponses d'erreur (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// Codes d'erreur génériques; les erreurs métier ont leur propre code (ex: user_not_found)
+const (
+	CodeInvalidBody          = "invalid_body"           // Corps JSON mal formé
+	CodeValidationFailed     = "validation_failed"      // Un ou plusieurs champs invalides (voir errors)
+	CodeInvalidID            = "invalid_id"             // Identifiant non numérique dans le chemin
+	CodeDuplicateEmail       = "duplicate_email"        // Email déjà utilisé
+	CodeUniqueViolation      = "unique_violation"       // Contrainte d'unicité de la base
+	CodeForeignKeyViolation  = "foreign_key_violation"  // Référence à une ressource inexistante ou encore référencée
+	CodeConstraintViolation  = "constraint_violation"   // Autre contrainte de la base (CHECK, NOT NULL)
+	CodeUnsupportedMediaType = "unsupported_media_type" // Type de contenu du corps non pris en charge
+	CodeInvalidPatch         = "invalid_patch"          // Modification partielle (PATCH) mal formée
+	CodePatchConflict        = "patch_conflict"         // Chemin d'un JSON Patch introuvable dans la ressource
+	CodePatchTestFailed      = "patch_test_failed"      // Opération test d'un JSON Patch non vérifiée
+	CodePreconditionFailed   = "precondition_failed"    // If-Match : la ressource a été modifiée depuis sa lecture
+	CodePreconditionRequired = "precondition_required"  // If-Match absent alors que REQUIRE_IF_MATCH l'exige
+	CodeEditConflict         = "edit_conflict"          // Sans If-Match : la ressource a été modifiée pendant le traitement
+	CodeInternal             = "internal_error"
+)
+
+// Codes des erreurs métier
+const (
+	CodeUserNotFound            = "user_not_found"
+	CodeCourseNotFound          = "course_not_found"
+	CodeGuardianNotFound        = "guardian_not_found"
+	CodeMeetingNotFound         = "meeting_not_found"
+	CodeEnrollmentNotFound      = "enrollment_not_found"
+	CodeWaitlistEntryNotFound   = "waitlist_entry_not_found"
+	CodeLinkNotFound            = "link_not_found"
+	CodeAPIKeyNotFound          = "api_key_not_found"
+	CodeAlreadyEnrolled         = "already_enrolled"
+	CodeAlreadyWaitlisted       = "already_waitlisted"
+	CodeAlreadyLinked           = "already_linked"
+	CodeCapacityBelowEnrollment = "capacity_below_enrollment"
+	CodeInvalidWaitlistOrder    = "invalid_waitlist_order"
+	CodeLevelMismatch           = "level_mismatch"
+	CodeAgeMismatch             = "age_mismatch"
+	CodeNotEnrolled             = "not_enrolled"
+	CodeInvalidDate             = "invalid_date"
+	CodeAuthenticationRequired  = "authentication_required"
+	CodeInvalidToken            = "invalid_token"
+	CodeInvalidCredentials      = "invalid_credentials"
+	CodeInsufficientScope       = "insufficient_scope"
+	CodePermissionDenied        = "permission_denied"
+)
+
+// Problem est le corps des réponses d'erreur (RFC 7807, application/problem+json).
+// Code est stable et destiné aux programmes; Detail est destiné aux personnes et peut changer.
+type Problem struct {
+	Type        string       `json:"type"`  // about:blank : la sémantique est celle du statut HTTP, précisée par Code
+	Title       string       `json:"title"` // Libellé du statut HTTP
+	Status      int          `json:"status"`
+	Detail      string       `json:"detail,omitempty"`
+	Instance    string       `json:"instance,omitempty"` // Chemin de la requête
+	Code        string       `json:"code"`
+	RequestID   string       `json:"request_id,omitempty"`
+	Errors      []FieldError `json:"errors,omitempty"`       // Champs invalides (422)
+	AgeMismatch *AgeMismatch `json:"age_mismatch,omitempty"` // Âge incompatible avec le niveau (422)
+
+	detailArgs []any // Arguments de Detail, formaté après traduction
+}
+
+// FieldError décrit un champ invalide du corps de la requête
+type FieldError struct {
+	Field   string `json:"field"` // Nom JSON du champ, ex: date_naissance ou records[0].status
+	Code    string `json:"code"`  // Règle non respectée, ex: required, email, oneof
+	Message string `json:"message"`
+
+	args []any // Arguments de Message, formaté après traduction
+}
+
+// fieldError crée l'erreur d'un champ; le message, en français, est traduit dans la langue de la requête
+func fieldError(field, code, message string, args ...any) FieldError {
+	return FieldError{Field: field, Code: code, Message: message, args: args}
+}
+
+// apiError associe une erreur métier à une réponse : statut HTTP, code et message (en français, formaté avec Args)
+type apiError struct {
+	Status int
+	Code   string
+	Detail string
+	Args   []any
+}
+
+// newAPIError crée une erreur métier
+func newAPIError(status int, code, detail string, args ...any) apiError {
+	return apiError{Status: status, Code: code, Detail: detail, Args: args}
+}
+
+// Error retourne le message en français, pour qu'une apiError puisse être retournée comme une erreur
+func (e apiError) Error() string {
+	return fmt.Sprintf(e.Detail, e.Args...)
+}
+
+func init() {
+	// Les erreurs de validation désignent les champs par leur nom JSON
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName retourne le nom JSON d'un champ de structure
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// abortProblem répond avec un problème et interrompt la requête. Le détail et les messages des champs
+// sont traduits dans la langue de la requête; le titre, l'instance et l'identifiant de la requête
+// sont complétés à partir du statut et de la requête.
+func abortProblem(c *gin.Context, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	p.Title = http.StatusText(p.Status)
+	p.Detail = tr(c, p.Detail, p.detailArgs...)
+	for i, fe := range p.Errors {
+		p.Errors[i].Message = tr(c, fe.Message, fe.args...)
+	}
+	p.Instance = c.Request.URL.Path
+	p.RequestID = c.GetString(requestIDContextKey)
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// abortError répond avec le statut, le code et le message fournis (en français, formaté avec args)
+func abortError(c *gin.Context, status int, code, detail string, args ...any) {
+	abortProblem(c, Problem{Status: status, Code: code, Detail: detail, detailArgs: args})
+}
+
+// abortAPIError répond avec une erreur métier
+func abortAPIError(c *gin.Context, e apiError) {
+	abortError(c, e.Status, e.Code, e.Detail, e.Args...)
+}
+
+// abortInvalidID répond 400 pour un identifiant du chemin qui n'est pas un nombre
+func abortInvalidID(c *gin.Context, detail string) {
+	abortError(c, http.StatusBadRequest, CodeInvalidID, detail)
+}
+
+// abortValidation répond 422 avec le détail de chaque champ invalide
+func abortValidation(c *gin.Context, fields ...FieldError) {
+	abortProblem(c, Problem{
+		Status: http.StatusUnprocessableEntity,
+		Code:   CodeValidationFailed,
+		Detail: "Un ou plusieurs champs sont invalides",
+		Errors: fields,
+	})
+}
+
+// bindJSON lit le corps JSON de la requête dans obj et le valide (balises binding).
+// En cas d'erreur, répond 400 si le JSON est mal formé, 422 avec le détail des champs sinon, et retourne false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	return bindError(c, c.ShouldBindJSON(obj))
+}
+
+// bindOptionalJSON est comme bindJSON, mais accepte un corps vide (tous les champs sont optionnels)
+func bindOptionalJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	return bindError(c, err)
+}
+
+// bindError répond à une erreur de lecture du corps et retourne false, ou retourne true s'il n'y a pas d'erreur
+func bindError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		abortValidation(c, fieldErrors(validationErrs)...)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		abortValidation(c, fieldError(typeErr.Field, "type", "Type invalide : %s attendu", tr(c, jsonTypeName(typeErr.Type))))
+	case errors.Is(err, io.EOF):
+		abortError(c, http.StatusBadRequest, CodeInvalidBody, "Corps de la requête manquant")
+	default:
+		abortError(c, http.StatusBadRequest, CodeInvalidBody, "JSON invalide : %s", err.Error())
+	}
+	return false
+}
+
+// fieldErrors convertit les erreurs du validateur en erreurs par champ, avec un message en français
+func fieldErrors(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		// Namespace : "UserRequest.date_naissance" ou "AttendanceRequest.records[0].status"
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		message, args := validationMessage(fe)
+		fields = append(fields, fieldError(field, fe.Tag(), message, args...))
+	}
+	return fields
+}
+
+// validationMessage retourne le message (en français) et ses arguments pour une règle de validation non respectée
+func validationMessage(fe validator.FieldError) (string, []any) {
+	switch fe.Tag() {
+	case "required":
+		return "Champ requis", nil
+	case "email":
+		return "Adresse email invalide", nil
+	case "oneof":
+		return "Valeur non permise (valeurs permises : %s)", []any{strings.ReplaceAll(fe.Param(), " ", ", ")}
+	case "datetime":
+		return "Format invalide (attendu : %s)", []any{dateTimeLayout(fe.Param())}
+	case "min":
+		if fe.Kind() == reflect.Slice {
+			return "Au moins %s élément(s) requis", []any{fe.Param()}
+		}
+		return "Valeur minimale : %s", []any{fe.Param()}
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return "Au plus %s élément(s)", []any{fe.Param()}
+		}
+		return "Valeur maximale : %s", []any{fe.Param()}
+	}
+	return "Valeur invalide (règle %s)", []any{fe.Tag()}
+}
+
+// dateTimeLayout traduit un format de date Go en format lisible
+func dateTimeLayout(layout string) string {
+	switch layout {
+	case "2006-01-02":
+		return "YYYY-MM-DD"
+	case "15:04":
+		return "HH:MM"
+	}
+	return layout
+}
+
+// jsonTypeName retourne le nom JSON d'un type Go
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "chaîne"
+	case reflect.Bool:
+		return "booléen"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "entier"
+	case reflect.Float32, reflect.Float64:
+		return "nombre"
+	case reflect.Slice, reflect.Array:
+		return "tableau"
+	}
+	return "objet"
+}
+
+// respondError répond à une erreur qui n'est pas propre au handler : erreur métier (apiError),
+// email déjà utilisé ou contrainte de la base non respectée (409), sinon erreur interne (500, journalisée).
+func respondError(c *gin.Context, err error) {
+	var e apiError
+	if errors.As(err, &e) {
+		abortAPIError(c, e)
+		return
+	}
+	if errors.Is(err, ErrDuplicateEmail) {
+		abortProblem(c, Problem{
+			Status: http.StatusConflict,
+			Code:   CodeDuplicateEmail,
+			Detail: "Cet email est déjà utilisé",
+			Errors: []FieldError{fieldError("email", "unique", "Email déjà utilisé")},
+		})
+		return
+	}
+	if e, ok := constraintError(err); ok {
+		loggerFrom(c.Request.Context()).Warn("contrainte de la base non respectée", "error", err)
+		abortAPIError(c, e)
+		return
+	}
+	internalError(c, err)
+}
+
+// constraintError associe une violation de contrainte du driver (SQLite ou PostgreSQL) à une erreur 409
+func constraintError(err error) (apiError, bool) {
+	var sqliteErr sqlite3.Error
+	var pqErr *pq.Error
+	var kind string
+	switch {
+	case errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint:
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			kind = CodeUniqueViolation
+		case sqlite3.ErrConstraintForeignKey:
+			kind = CodeForeignKeyViolation
+		default:
+			kind = CodeConstraintViolation
+		}
+	case errors.As(err, &pqErr) && pqErr.Code.Class() == "23": // integrity_constraint_violation
+		switch pqErr.Code {
+		case "23505":
+			kind = CodeUniqueViolation
+		case "23503":
+			kind = CodeForeignKeyViolation
+		default:
+			kind = CodeConstraintViolation
+		}
+	default:
+		return apiError{}, false
+	}
+
+	detail := map[string]string{
+		CodeUniqueViolation:     "Une ressource avec ces valeurs existe déjà",
+		CodeForeignKeyViolation: "Opération impossible : la ressource référence une ressource inexistante ou est encore référencée",
+		CodeConstraintViolation: "Opération impossible : une contrainte de la base de données n'est pas respectée",
+	}[kind]
+	return apiError{Status: http.StatusConflict, Code: kind, Detail: detail}, true
+}
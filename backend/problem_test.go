@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeProblem vérifie le type de contenu d'une réponse d'erreur et décode son corps
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p), w.Body.String())
+	assert.Equal(t, w.Code, p.Status)
+	return p
+}
+
+func TestValidationProblem(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	// Chaque champ invalide est décrit par son nom JSON et la règle non respectée
+	w := performRequest(r, "POST", "/api/users", UserRequest{LastName: "Dupont", Email: "pas-un-email", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p := decodeProblem(t, w)
+	assert.Equal(t, "about:blank", p.Type)
+	assert.Equal(t, "Unprocessable Entity", p.Title)
+	assert.Equal(t, CodeValidationFailed, p.Code)
+	assert.Equal(t, "/api/users", p.Instance)
+	fields := map[string]string{}
+	for _, fe := range p.Errors {
+		fields[fe.Field] = fe.Code
+		assert.NotEmpty(t, fe.Message)
+	}
+	assert.Equal(t, map[string]string{"first_name": "required", "email": "email"}, fields)
+
+	// Niveau inconnu : même format, règle propre à l'API
+	w = performRequest(r, "POST", "/api/users", UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 12"})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p = decodeProblem(t, w)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, FieldError{Field: "niveau_natation", Code: "unknown_level", Message: "Niveau de natation inconnu: NAGEUR 12"}, p.Errors[0])
+
+	// Un champ du mauvais type est aussi une erreur de champ
+	w = performRequest(r, "POST", "/api/users", json.RawMessage(`{"first_name": 42}`))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p = decodeProblem(t, w)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "first_name", p.Errors[0].Field)
+	assert.Equal(t, "type", p.Errors[0].Code)
+
+	// Les champs d'un tableau sont indexés
+	w = performRequest(r, "PUT", "/api/courses/1/meetings/1/attendance", json.RawMessage(`{"records": [{"user_id": 1, "status": "late"}]}`))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p = decodeProblem(t, w)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, FieldError{Field: "records[0].status", Code: "oneof", Message: "Valeur non permise (valeurs permises : present, absent, excused)"}, p.Errors[0])
+}
+
+func TestMalformedBodyProblem(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	for _, body := range []string{`{"first_name": `, ``} {
+		req := httptest.NewRequest("POST", "/api/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+		p := decodeProblem(t, w)
+		assert.Equal(t, CodeInvalidBody, p.Code)
+		assert.Empty(t, p.Errors)
+	}
+
+	w := performRequest(r, "GET", "/api/users/abc", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeInvalidID, decodeProblem(t, w).Code)
+}
+
+func TestConflictProblem(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(requestID())
+	newServer(newSQLiteStore(testDB)).registerRoutes(r)
+
+	adult := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "1980-05-15", NiveauNatation: "NAGEUR 3"}
+	w := performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Un email déjà utilisé donne 409 avec le champ en cause et l'identifiant de la requête
+	w = performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusConflict, w.Code)
+	p := decodeProblem(t, w)
+	assert.Equal(t, CodeDuplicateEmail, p.Code)
+	assert.Equal(t, w.Header().Get(requestIDHeader), p.RequestID)
+	assert.NotEmpty(t, p.RequestID)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, "email", p.Errors[0].Field)
+
+	// Les erreurs métier ont leur code
+	w = performRequest(r, "GET", "/api/users/999", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, CodeUserNotFound, decodeProblem(t, w).Code)
+}
+
+func TestConstraintError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		code string
+	}{
+		"sqlite unique":      {sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, CodeUniqueViolation},
+		"sqlite foreign key": {sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey}, CodeForeignKeyViolation},
+		"sqlite check":       {sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintCheck}, CodeConstraintViolation},
+		"postgres unique":    {fmt.Errorf("insertion: %w", &pq.Error{Code: "23505"}), CodeUniqueViolation},
+		"postgres fk":        {&pq.Error{Code: "23503"}, CodeForeignKeyViolation},
+		"postgres not null":  {&pq.Error{Code: "23502"}, CodeConstraintViolation},
+	}
+	for name, tc := range tests {
+		e, ok := constraintError(tc.err)
+		require.True(t, ok, name)
+		assert.Equal(t, http.StatusConflict, e.Status, name)
+		assert.Equal(t, tc.code, e.Code, name)
+	}
+
+	// Une erreur métier, même enveloppée, garde son statut et son code
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("PATCH", "/api/users/1", nil)
+	respondError(c, fmt.Errorf("patch: %w", newAPIError(http.StatusConflict, CodePatchConflict, "Opération %d : chemin introuvable : %s", 1, "/phone")))
+	require.Equal(t, http.StatusConflict, w.Code)
+	p := decodeProblem(t, w)
+	assert.Equal(t, CodePatchConflict, p.Code)
+	assert.Equal(t, "Opération 1 : chemin introuvable : /phone", p.Detail)
+
+	// Les autres erreurs restent des erreurs internes
+	for _, err := range []error{errors.New("connexion refusée"), sqlite3.Error{Code: sqlite3.ErrBusy}, &pq.Error{Code: "40001"}} {
+		_, ok := constraintError(err)
+		assert.False(t, ok, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import "sort"
+
+// Rôles du personnel
+const (
+	RoleAdmin       = "admin"
+	RoleCoordinator = "coordinator"
+	RoleInstructor  = "instructor"
+	RoleReadOnly    = "readonly"
+)
+
+// Permissions exigées par les routes d'écriture. La consultation (GET) est permise à tout compte authentifié.
+const (
+	PermWriteUsers        = "users:write"       // Créer et modifier des usagers
+	PermDeleteUsers       = "users:delete"      // Supprimer des usagers
+	PermManageCourses     = "courses:write"     // Créer, modifier et supprimer des cours et leurs séances
+	PermManageEnrollments = "enrollments:write" // Inscriptions et listes d'attente
+	PermManageGuardians   = "guardians:write"   // Tuteurs et rattachement des enfants
+	PermRecordEvaluations = "evaluations:write" // Enregistrer des évaluations
+	PermRecordAttendance  = "attendance:write"  // Saisir les présences
+	PermManageAPIKeys     = "apikeys:write"     // Créer, renouveler et révoquer les clés d'API
+)
+
+// Portées des clés d'API. Une clé n'accède qu'aux routes des usagers (et au catalogue des niveaux).
+const (
+	ScopeReadUsers  = "users:read"  // Consulter les usagers
+	ScopeWriteUsers = "users:write" // Créer et modifier des usagers
+	ScopeExport     = "export"      // Exporter les usagers en CSV
+)
+
+// rolePermissions associe chaque rôle aux permissions qu'il accorde
+var rolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermWriteUsers, PermDeleteUsers, PermManageCourses, PermManageEnrollments,
+		PermManageGuardians, PermRecordEvaluations, PermRecordAttendance, PermManageAPIKeys,
+	},
+	RoleCoordinator: {
+		PermWriteUsers, PermManageCourses, PermManageEnrollments,
+		PermManageGuardians, PermRecordEvaluations, PermRecordAttendance,
+	},
+	RoleInstructor: {PermRecordEvaluations, PermRecordAttendance},
+	RoleReadOnly:   {},
+}
+
+// validRole indique si le rôle existe
+func validRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// roles retourne les rôles connus, triés
+func roles() []string {
+	names := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasPermission indique si le rôle accorde la permission
+func hasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// hasScope indique si la clé d'API a la portée
+func (k APIKey) hasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// withPermissions retourne le compte avec la liste des permissions de son rôle
+func (st Staff) withPermissions() Staff {
+	st.Permissions = append([]string{}, rolePermissions[st.Role]...)
+	return st
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRolePermissions(t *testing.T) {
+	assert.Equal(t, []string{RoleAdmin, RoleCoordinator, RoleInstructor, RoleReadOnly}, roles())
+	assert.False(t, validRole("directeur"))
+
+	assert.True(t, hasPermission(RoleAdmin, PermDeleteUsers))
+	assert.True(t, hasPermission(RoleCoordinator, PermWriteUsers))
+	assert.False(t, hasPermission(RoleCoordinator, PermDeleteUsers))
+	assert.True(t, hasPermission(RoleInstructor, PermRecordEvaluations))
+	assert.True(t, hasPermission(RoleInstructor, PermRecordAttendance))
+	assert.False(t, hasPermission(RoleInstructor, PermWriteUsers))
+	assert.False(t, hasPermission(RoleReadOnly, PermRecordAttendance))
+	assert.False(t, hasPermission("directeur", PermWriteUsers))
+}
+
+func TestRoleEnforcement(t *testing.T) {
+	r, store, _ := setupAuthRouter(t)
+	for email, role := range map[string]string{
+		"coordo@test.com":   RoleCoordinator,
+		"moniteur@test.com": RoleInstructor,
+		"lecture@test.com":  RoleReadOnly,
+	} {
+		createTestStaff(t, store, email, role)
+	}
+	admin := loginTestStaff(t, r, "admin@test.com").AccessToken
+	coordinator := loginTestStaff(t, r, "coordo@test.com").AccessToken
+	instructor := loginTestStaff(t, r, "moniteur@test.com")
+	readOnly := loginTestStaff(t, r, "lecture@test.com").AccessToken
+
+	assert.Equal(t, RoleInstructor, instructor.Staff.Role)
+	assert.ElementsMatch(t, []string{PermRecordEvaluations, PermRecordAttendance}, instructor.Staff.Permissions)
+
+	userID := insertTestUser(t, store.db, "jean@test.com", "NAGEUR 3")
+	path := "/api/users/" + strconv.Itoa(userID)
+	update := UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2015-05-15", NiveauNatation: "NAGEUR 3"}
+
+	// Tous les rôles peuvent consulter
+	for _, token := range []string{admin, coordinator, instructor.AccessToken, readOnly} {
+		w := performRequestWithToken(r, "GET", path, token, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := performRequestWithToken(r, "PUT", path, readOnly, update)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, CodePermissionDenied, body.Code)
+	assert.Contains(t, body.Detail, PermWriteUsers)
+
+	w = performRequestWithToken(r, "PUT", path, instructor.AccessToken, update)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequestWithToken(r, "PUT", path, coordinator, update)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Un moniteur enregistre les évaluations et les présences
+	passed := false
+	w = performRequestWithToken(r, "POST", path+"/evaluations", instructor.AccessToken, EvaluationRequest{Passed: &passed, Evaluator: "Moniteur"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = performRequestWithToken(r, "POST", path+"/evaluations", readOnly, EvaluationRequest{Passed: &passed, Evaluator: "Lecture"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = performRequestWithToken(r, "POST", "/api/courses", instructor.AccessToken, testCourseRequest("NAGEUR 3", 8))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequestWithToken(r, "POST", "/api/courses", coordinator, testCourseRequest("NAGEUR 3", 8))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var course Course
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &course))
+	coursePath := "/api/courses/" + strconv.Itoa(course.ID)
+
+	w = performRequestWithToken(r, "POST", coursePath+"/enrollments", coordinator, EnrollmentRequest{UserID: userID})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = performRequestWithToken(r, "POST", coursePath+"/meetings", instructor.AccessToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = performRequestWithToken(r, "POST", coursePath+"/meetings", coordinator, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var calendar struct {
+		Meetings []Meeting `json:"meetings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &calendar))
+	attendancePath := coursePath + "/meetings/" + strconv.Itoa(calendar.Meetings[0].ID) + "/attendance"
+	marks := AttendanceRequest{Records: []AttendanceMark{{UserID: userID, Status: AttendancePresent}}}
+	w = performRequestWithToken(r, "PUT", attendancePath, instructor.AccessToken, marks)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = performRequestWithToken(r, "PUT", attendancePath, readOnly, marks)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Seul un administrateur supprime un usager
+	for _, token := range []string{coordinator, instructor.AccessToken, readOnly} {
+		w = performRequestWithToken(r, "DELETE", path, token, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+	w = performRequestWithToken(r, "DELETE", path, admin, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Un changement de rôle s'applique immédiatement, sans nouvelle connexion
+	reader, err := store.GetStaffByEmail(context.Background(), "lecture@test.com")
+	require.NoError(t, err)
+	require.NoError(t, store.SetStaffRole(context.Background(), reader.ID, RoleCoordinator))
+	w = performRequestWithToken(r, "POST", "/api/users", readOnly, update)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newHTTPServer crée le serveur HTTP avec ses délais de lecture, d'écriture et d'inactivité
+func newHTTPServer(addr string, handler http.Handler, t serverTimeouts) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  t.Read,
+		WriteTimeout: t.Write,
+		IdleTimeout:  t.Idle,
+	}
+}
+
+// serve sert les requêtes sur ln jusqu'à l'annulation de ctx (signal d'arrêt), puis arrête le serveur :
+// les nouvelles connexions sont refusées et les requêtes en cours ont jusqu'à drain pour se terminer.
+// Passé ce délai, les connexions restantes sont fermées et une erreur est retournée.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, drain time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Arrêt du serveur, fin des requêtes en cours", "drain", drain.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("requêtes interrompues après %s: %w", drain, err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer démarre serve sur un port libre avec le handler fourni et retourne
+// l'URL du serveur, la fonction d'arrêt (le signal) et le canal du résultat de serve
+func startTestServer(t *testing.T, handler http.Handler, drain time.Duration) (string, context.CancelFunc, <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	t.Cleanup(stop)
+
+	done := make(chan error, 1)
+	srv := newHTTPServer(ln.Addr().String(), handler, serverTimeouts{Read: time.Second, Write: 5 * time.Second, Idle: time.Second})
+	go func() { done <- serve(ctx, srv, ln, drain) }()
+	return "http://" + ln.Addr().String(), stop, done
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	url, stop, done := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "enregistré")
+	}), 5*time.Second)
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(url+"/api/users", "application/json", nil)
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		resCh <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	// Le signal d'arrêt arrive pendant la requête : le serveur attend qu'elle se termine
+	<-started
+	stop()
+	select {
+	case err := <-done:
+		t.Fatalf("serve s'est terminé avant la fin de la requête en cours: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Les nouvelles connexions sont refusées pendant l'arrêt
+	_, err := net.DialTimeout("tcp", url[len("http://"):], time.Second)
+	assert.Error(t, err)
+
+	close(release)
+	res := <-resCh
+	require.NoError(t, res.err)
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Equal(t, "enregistré", res.body)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve ne s'est pas terminé après la requête en cours")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	url, stop, done := startTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}), 50*time.Millisecond)
+
+	errCh := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url + "/api/users/export")
+		if err == nil {
+			resp.Body.Close()
+		}
+		errCh <- err
+	}()
+
+	// La requête dépasse le délai d'arrêt : la connexion est fermée et serve retourne une erreur
+	<-started
+	stop()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve ne s'est pas terminé après le délai d'arrêt")
+	}
+	assert.Error(t, <-errCh)
+}
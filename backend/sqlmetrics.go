@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// queryObserver reçoit la durée de chaque requête SQL, par opération (select, insert, update, delete, other)
+type queryObserver func(operation string, d time.Duration)
+
+// queryOperation retourne l'opération d'une requête SQL d'après son premier mot-clé
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch op := strings.ToLower(fields[0]); op {
+	case "select", "insert", "update", "delete":
+		return op
+	}
+	return "other"
+}
+
+// instrumentedConnector ouvre des connexions du driver sous-jacent qui mesurent la durée des requêtes.
+// La durée mesurée va jusqu'au retour de la requête par le driver, sans le parcours des lignes.
+type instrumentedConnector struct {
+	driver  driver.Driver
+	dsn     string
+	observe queryObserver
+}
+
+func (c instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	var err error
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		var connector driver.Connector
+		if connector, err = dc.OpenConnector(c.dsn); err == nil {
+			conn, err = connector.Connect(ctx)
+		}
+	} else {
+		conn, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, observe: c.observe}, nil
+}
+
+func (c instrumentedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// instrumentedConn transmet chaque appel à la connexion du driver, en mesurant les requêtes
+type instrumentedConn struct {
+	driver.Conn
+	observe queryObserver
+}
+
+func (c *instrumentedConn) timed(query string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if !errors.Is(err, driver.ErrSkip) {
+		c.observe(queryOperation(query), time.Since(start))
+	}
+	return err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip // database/sql prépare alors la requête (instrumentedStmt)
+	}
+	var res driver.Result
+	err := c.timed(query, func() (err error) {
+		res, err = execer.ExecContext(ctx, query, args)
+		return err
+	})
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	var rows driver.Rows
+	err := c.timed(query, func() (err error) {
+		rows, err = queryer.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("le driver ne gère pas les options de transaction")
+	}
+	return c.Conn.Begin() // Repli des drivers sans BeginTx
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip // Conversion par défaut de database/sql
+}
+
+// instrumentedStmt mesure l'exécution d'une requête préparée
+type instrumentedStmt struct {
+	driver.Stmt
+	conn  *instrumentedConn
+	query string
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var res driver.Result
+	err := s.conn.timed(s.query, func() (err error) {
+		if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+			res, err = execer.ExecContext(ctx, args)
+		} else {
+			res, err = s.Stmt.Exec(namedValues(args)) // Repli des drivers sans contexte
+		}
+		return err
+	})
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := s.conn.timed(s.query, func() (err error) {
+		if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = queryer.QueryContext(ctx, args)
+		} else {
+			rows, err = s.Stmt.Query(namedValues(args)) // Repli des drivers sans contexte
+		}
+		return err
+	})
+	return rows, err
+}
+
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// namedValues convertit les arguments pour les drivers qui n'acceptent que des arguments positionnels
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUserNotFound est retournée par un UserStore lorsque l'usager demandé n'existe pas
+var ErrUserNotFound = errors.New("usager non trouvé")
+
+// ErrVersionConflict est retournée lorsque l'usager a été modifié depuis la version lue par l'appelant
+var ErrVersionConflict = errors.New("usager modifié entre-temps")
+
+// ErrDuplicateEmail est retournée lorsqu'un autre usager majeur (ou un autre tuteur) utilise déjà l'email
+var ErrDuplicateEmail = errors.New("email déjà utilisé")
+
+// adultAge est l'âge de la majorité. L'email n'est unique qu'entre usagers majeurs :
+// les enfants d'une même famille peuvent partager l'email d'un parent, ou n'en avoir aucun
+// lorsque leurs coordonnées sont celles de leurs tuteurs.
+const adultAge = 18
+
+// isAdult indique si l'usager né à cette date est majeur aujourd'hui
+func isAdult(dateNaissance string) bool {
+	return calculateAge(dateNaissance) >= adultAge
+}
+
+// adultBirthCutoff retourne la date de naissance la plus récente d'un usager majeur aujourd'hui (YYYY-MM-DD)
+func adultBirthCutoff() string {
+	return time.Now().AddDate(-adultAge, 0, 0).Format("2006-01-02")
+}
+
+// UserFilter regroupe les options de recherche, de filtrage et de pagination de la liste des usagers
+type UserFilter struct {
+	Search  string   // Recherche dans prénom, nom ou email
+	Niveaux []string // Niveaux de natation acceptés (libellés exacts)
+	AgeMin  *int     // Âge minimum (inclus)
+	AgeMax  *int     // Âge maximum (inclus)
+	Limit   int
+	Offset  int
+}
+
+// UserStore définit les opérations de persistance des usagers
+type UserStore interface {
+	// List retourne une page d'usagers correspondant au filtre ainsi que le nombre total de résultats
+	List(ctx context.Context, filter UserFilter) ([]User, int, error)
+	// Get retourne l'usager correspondant à l'ID ou ErrUserNotFound
+	Get(ctx context.Context, id int) (User, error)
+	// Create enregistre un nouvel usager et le retourne
+	Create(ctx context.Context, req UserRequest) (User, error)
+	// Update modifie un usager existant et le retourne, ou ErrUserNotFound. version est la version lue
+	// par l'appelant : ErrVersionConflict si l'usager a été modifié depuis; 0 ne la vérifie pas.
+	Update(ctx context.Context, id int, req UserRequest, version int) (User, error)
+	// Delete supprime un usager, ou retourne ErrUserNotFound; version comme pour Update
+	Delete(ctx context.Context, id int, version int) error
+}
+
+// Erreurs retournées par un CourseStore
+var (
+	ErrCourseNotFound     = errors.New("cours non trouvé")
+	ErrLevelMismatch      = errors.New("le niveau de l'usager ne correspond pas au niveau du cours")
+	ErrAlreadyEnrolled    = errors.New("l'usager est déjà inscrit à ce cours")
+	ErrEnrollmentNotFound = errors.New("inscription non trouvée")
+	ErrCapacityTooLow     = errors.New("la capacité est inférieure au nombre d'inscrits")
+
+	ErrAlreadyWaitlisted     = errors.New("l'usager est déjà en liste d'attente pour ce cours")
+	ErrWaitlistEntryNotFound = errors.New("usager absent de la liste d'attente")
+	ErrInvalidWaitlistOrder  = errors.New("le nouvel ordre doit contenir exactement les usagers en liste d'attente")
+)
+
+// AgeMismatchError est retournée par Enroll lorsque l'âge de l'usager au début du cours
+// ne correspond pas au niveau et que la politique d'âge refuse les incompatibilités
+type AgeMismatchError struct {
+	Mismatch AgeMismatch
+}
+
+func (e *AgeMismatchError) Error() string {
+	return "l'âge de l'usager ne correspond pas au niveau du cours"
+}
+
+// agePolicyStore est implémenté par un CourseStore qui vérifie l'âge des usagers inscrits
+// ou promus depuis la liste d'attente (voir Server.setAgePolicy)
+type agePolicyStore interface {
+	setAgePolicy(p agePolicy)
+}
+
+// CourseStore définit les opérations de persistance des cours et des inscriptions.
+// Un UserStore peut aussi l'implémenter; les routes /api/courses ne sont alors disponibles
+// que si le store fourni au Server le supporte.
+type CourseStore interface {
+	ListCourses(ctx context.Context, filter CourseFilter) ([]Course, error)
+	// GetCourse retourne le cours correspondant à l'ID ou ErrCourseNotFound
+	GetCourse(ctx context.Context, id int) (Course, error)
+	CreateCourse(ctx context.Context, req CourseRequest) (Course, error)
+	// UpdateCourse modifie un cours; ErrCapacityTooLow si la capacité devient inférieure au nombre d'inscrits.
+	// Une hausse de capacité promeut les premiers usagers admissibles de la liste d'attente.
+	UpdateCourse(ctx context.Context, id int, req CourseRequest) (Course, error)
+	// DeleteCourse supprime un cours, ses inscriptions et sa liste d'attente
+	DeleteCourse(ctx context.Context, id int) error
+
+	// ListEnrollments retourne les inscriptions d'un cours avec les usagers inscrits
+	ListEnrollments(ctx context.Context, courseID int) ([]Enrollment, error)
+	// Enroll inscrit un usager de façon atomique en vérifiant son niveau et son âge au début du cours
+	// (*AgeMismatchError en mode reject, avertissement en mode warn).
+	// Si le cours est complet, l'usager est ajouté à la fin de la liste d'attente.
+	Enroll(ctx context.Context, courseID, userID int) (EnrollmentResult, error)
+	// Unenroll annule une inscription et promeut, dans la même transaction, le premier usager
+	// admissible (niveau et âge) de la liste d'attente (retourné, ou nil si personne n'est promu)
+	Unenroll(ctx context.Context, courseID, userID int) (*Enrollment, error)
+
+	// ListWaitlist retourne la liste d'attente d'un cours, dans l'ordre de promotion
+	ListWaitlist(ctx context.Context, courseID int) ([]WaitlistEntry, error)
+	// ReorderWaitlist remplace l'ordre de la liste d'attente; userIDs doit contenir
+	// exactement les usagers en attente, sinon ErrInvalidWaitlistOrder
+	ReorderWaitlist(ctx context.Context, courseID int, userIDs []int) ([]WaitlistEntry, error)
+	// RemoveFromWaitlist retire un usager de la liste d'attente, ou retourne ErrWaitlistEntryNotFound
+	RemoveFromWaitlist(ctx context.Context, courseID, userID int) error
+}
+
+// ErrEvaluationLevelMismatch est retournée lorsqu'une évaluation ne porte pas sur le niveau actuel de l'usager
+var ErrEvaluationLevelMismatch = errors.New("l'évaluation doit porter sur le niveau actuel de l'usager")
+
+// EvaluationStore définit les opérations de persistance des évaluations et de la progression des niveaux
+type EvaluationStore interface {
+	// ListEvaluations retourne l'historique des évaluations d'un usager, de la plus ancienne à la plus récente
+	ListEvaluations(ctx context.Context, userID int) ([]Evaluation, error)
+	// CreateEvaluation enregistre une évaluation au niveau actuel de l'usager. Si elle est réussie,
+	// l'usager passe au niveau suivant dans la même transaction; *AgeMismatchError si son âge à la
+	// date de l'évaluation ne convient pas au niveau suivant (mode reject).
+	CreateEvaluation(ctx context.Context, userID int, req EvaluationRequest) (Evaluation, error)
+}
+
+// Erreurs retournées par un GuardianStore
+var (
+	ErrGuardianNotFound = errors.New("tuteur non trouvé")
+	ErrAlreadyLinked    = errors.New("l'usager est déjà rattaché à ce tuteur")
+	ErrLinkNotFound     = errors.New("l'usager n'est pas rattaché à ce tuteur")
+)
+
+// GuardianStore définit les opérations de persistance des tuteurs et de leurs liens avec les usagers
+type GuardianStore interface {
+	ListGuardians(ctx context.Context, search string) ([]Guardian, error)
+	GetGuardian(ctx context.Context, id int) (Guardian, error)
+	CreateGuardian(ctx context.Context, req GuardianRequest) (Guardian, error)
+	UpdateGuardian(ctx context.Context, id int, req GuardianRequest) (Guardian, error)
+	// DeleteGuardian supprime le tuteur et ses liens; les usagers rattachés sont conservés
+	DeleteGuardian(ctx context.Context, id int) error
+
+	// ListChildren retourne les usagers rattachés au tuteur (le foyer)
+	ListChildren(ctx context.Context, guardianID int) ([]Child, error)
+	LinkChild(ctx context.Context, guardianID int, req GuardianLinkRequest) (Child, error)
+	UnlinkChild(ctx context.Context, guardianID, userID int) error
+	// ListUserGuardians retourne les tuteurs d'un usager
+	ListUserGuardians(ctx context.Context, userID int) ([]UserGuardian, error)
+}
+
+// PortalStore définit les opérations de persistance des accès des tuteurs au portail parents
+type PortalStore interface {
+	// GetGuardianAccount retourne un tuteur et ses identifiants par son email
+	GetGuardianAccount(ctx context.Context, email string) (GuardianAccount, error)
+	GetGuardianAccountByID(ctx context.Context, id int) (GuardianAccount, error)
+	// SetGuardianPassword donne (ou remplace) le mot de passe du tuteur et révoque ses jetons déjà émis
+	SetGuardianPassword(ctx context.Context, id int, passwordHash string) error
+	// RevokeGuardianAccess retire le mot de passe du tuteur et révoque ses jetons déjà émis
+	RevokeGuardianAccess(ctx context.Context, id int) error
+}
+
+// Erreurs retournées par un AttendanceStore
+var (
+	ErrMeetingNotFound = errors.New("séance non trouvée")
+	ErrNotEnrolled     = errors.New("l'usager n'est pas inscrit au cours")
+)
+
+// NotEnrolledError précise l'usager qui n'est pas inscrit au cours; errors.Is(err, ErrNotEnrolled) est vrai
+type NotEnrolledError struct {
+	UserID int
+}
+
+func (e NotEnrolledError) Error() string {
+	return fmt.Sprintf("%v (usager %d)", ErrNotEnrolled, e.UserID)
+}
+
+func (e NotEnrolledError) Unwrap() error {
+	return ErrNotEnrolled
+}
+
+// AttendanceStore définit les opérations de persistance des séances et des présences
+type AttendanceStore interface {
+	// GenerateMeetings crée les séances manquantes du cours, une par semaine entre ses dates de début et de fin.
+	// Les séances existantes sont conservées; les dates exclues ne sont pas créées.
+	GenerateMeetings(ctx context.Context, courseID int, excludeDates []string) ([]Meeting, error)
+	ListMeetings(ctx context.Context, courseID int) ([]Meeting, error)
+
+	ListAttendance(ctx context.Context, courseID, meetingID int) ([]AttendanceRecord, error)
+	// MarkAttendance enregistre (ou remplace) les présences d'une séance en une seule transaction.
+	// Chaque usager doit être inscrit au cours (ErrNotEnrolled).
+	MarkAttendance(ctx context.Context, courseID, meetingID int, marks []AttendanceMark) ([]AttendanceRecord, error)
+
+	CourseAttendance(ctx context.Context, courseID int) (CourseAttendanceReport, error)
+	UserAttendance(ctx context.Context, userID int) (UserAttendanceReport, error)
+}
+
+// ErrStaffNotFound est retournée lorsque le compte du personnel demandé n'existe pas
+var ErrStaffNotFound = errors.New("compte du personnel non trouvé")
+
+// StaffStore définit les opérations de persistance des comptes du personnel et des jetons révoqués
+type StaffStore interface {
+	ListStaff(ctx context.Context) ([]Staff, error)
+	GetStaff(ctx context.Context, id int) (Staff, error)
+	GetStaffByEmail(ctx context.Context, email string) (Staff, error)
+	// CreateStaff crée un compte; ErrDuplicateEmail si l'email est déjà utilisé par un autre compte
+	CreateStaff(ctx context.Context, email, name, role, passwordHash string) (Staff, error)
+	// SetStaffRole change le rôle d'un compte; il s'applique dès la requête suivante
+	SetStaffRole(ctx context.Context, id int, role string) error
+	// SetStaffPassword remplace le mot de passe et révoque les jetons déjà émis pour ce compte
+	SetStaffPassword(ctx context.Context, id int, passwordHash string) error
+	// RevokeStaffTokens révoque tous les jetons émis pour ce compte jusqu'à maintenant
+	RevokeStaffTokens(ctx context.Context, id int) error
+
+	// RevokeToken révoque un jeton (identifié par son jti) jusqu'à son expiration.
+	// Retourne false si le jeton était déjà révoqué.
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error)
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// ErrAPIKeyNotFound est retournée lorsque la clé d'API demandée n'existe pas ou a été révoquée
+var ErrAPIKeyNotFound = errors.New("clé d'API non trouvée")
+
+// APIKeyStore définit les opérations de persistance des clés d'API. Seul le hash des clés est stocké.
+type APIKeyStore interface {
+	// ListAPIKeys retourne toutes les clés, y compris les clés révoquées
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	CreateAPIKey(ctx context.Context, name string, scopes []string, prefix, keyHash string, createdBy *int) (APIKey, error)
+	// RotateAPIKey remplace la clé d'une clé non révoquée; l'ancienne clé est refusée immédiatement
+	RotateAPIKey(ctx context.Context, id int, prefix, keyHash string) (APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	// GetAPIKeyByHash retourne la clé non révoquée correspondant au hash
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error)
+	// TouchAPIKey enregistre l'utilisation de la clé
+	TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error
+}
+
+// HealthStore est implémenté par les stores dont /readyz vérifie l'état
+type HealthStore interface {
+	// Ping vérifie que la base de données répond
+	Ping(ctx context.Context) error
+	// CheckMigrations retourne une erreur si une migration est en attente ou a été modifiée depuis son application
+	CheckMigrations(ctx context.Context) error
+}
+
+// LevelStatsStore est implémenté par les stores qui comptent les usagers par niveau (métriques)
+type LevelStatsStore interface {
+	// CountUsersByLevel retourne le nombre d'usagers par valeur de niveau_natation
+	CountUsersByLevel(ctx context.Context) (map[string]int, error)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore implémente UserStore en mémoire, sans persistance.
+// Utile pour les tests et le développement local.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	users  map[int]User
+	nextID int
+}
+
+// newMemoryStore crée un UserStore vide en mémoire
+func newMemoryStore() *MemoryStore {
+	return &MemoryStore{users: make(map[int]User), nextID: 1}
+}
+
+// matches indique si un usager correspond au filtre (hors pagination)
+func (f UserFilter) matches(u User) bool {
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(u.FirstName), search) &&
+			!strings.Contains(strings.ToLower(u.LastName), search) &&
+			!strings.Contains(strings.ToLower(u.Email), search) {
+			return false
+		}
+	}
+	if len(f.Niveaux) > 0 && !containsString(f.Niveaux, u.NiveauNatation) {
+		return false
+	}
+	if f.AgeMin != nil && u.Age < *f.AgeMin {
+		return false
+	}
+	if f.AgeMax != nil && u.Age > *f.AgeMax {
+		return false
+	}
+	return true
+}
+
+// List retourne les usagers correspondant au filtre, triés du plus récent au plus ancien
+func (s *MemoryStore) List(ctx context.Context, filter UserFilter) ([]User, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := []User{}
+	for _, u := range s.users {
+		u.Age = calculateAge(u.DateNaissance)
+		if filter.matches(u) {
+			matched = append(matched, u)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID > matched[j].ID })
+
+	total := len(matched)
+	start := filter.Offset
+	if start > total {
+		start = total
+	}
+	end := total
+	if filter.Limit > 0 && start+filter.Limit < total {
+		end = start + filter.Limit
+	}
+	return matched[start:end], total, nil
+}
+
+// Get retourne un usager par son ID
+func (s *MemoryStore) Get(ctx context.Context, id int) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	u.Age = calculateAge(u.DateNaissance)
+	return u, nil
+}
+
+// Create enregistre un nouvel usager
+func (s *MemoryStore) Create(ctx context.Context, req UserRequest) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(req.Email, req.DateNaissance, 0) {
+		return User{}, ErrDuplicateEmail
+	}
+
+	u := User{
+		ID:             s.nextID,
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		Email:          req.Email,
+		DateNaissance:  req.DateNaissance,
+		NiveauNatation: req.NiveauNatation,
+		CreatedAt:      time.Now().UTC(),
+		Version:        1,
+	}
+	u.UpdatedAt = u.CreatedAt
+	s.users[u.ID] = u
+	s.nextID++
+
+	u.Age = calculateAge(u.DateNaissance)
+	return u, nil
+}
+
+// Update modifie un usager existant et incrémente sa version
+func (s *MemoryStore) Update(ctx context.Context, id int, req UserRequest, version int) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	if version != 0 && u.Version != version {
+		return User{}, ErrVersionConflict
+	}
+	if s.emailTaken(req.Email, req.DateNaissance, id) {
+		return User{}, ErrDuplicateEmail
+	}
+
+	u.FirstName = req.FirstName
+	u.LastName = req.LastName
+	u.Email = req.Email
+	u.DateNaissance = req.DateNaissance
+	u.NiveauNatation = req.NiveauNatation
+	u.UpdatedAt = time.Now().UTC()
+	u.Version++
+	s.users[id] = u
+
+	u.Age = calculateAge(u.DateNaissance)
+	return u, nil
+}
+
+// Delete supprime un usager
+func (s *MemoryStore) Delete(ctx context.Context, id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if version != 0 && u.Version != version {
+		return ErrVersionConflict
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// emailTaken vérifie qu'aucun autre usager majeur n'utilise l'email d'un usager majeur.
+// Un usager qui garde son email n'est pas vérifié : des frères et sœurs qui partagent l'email
+// d'un parent restent modifiables une fois majeurs. Doit être appelée avec le verrou acquis.
+func (s *MemoryStore) emailTaken(email, dateNaissance string, exceptID int) bool {
+	if email == "" || !isAdult(dateNaissance) || (exceptID != 0 && s.users[exceptID].Email == email) {
+		return false
+	}
+	for id, u := range s.users {
+		if id != exceptID && u.Email == email && isAdult(u.DateNaissance) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString indique si values contient value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
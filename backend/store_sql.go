@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLStore implémente UserStore sur une base de données SQL (SQLite ou PostgreSQL)
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+	ages    agePolicy // Vérification de l'âge à l'inscription et à la promotion depuis la liste d'attente
+}
+
+// newSQLStore crée un UserStore basé sur la connexion et le dialecte fournis
+func newSQLStore(db *sql.DB, d dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: d, ages: defaultAgePolicy()}
+}
+
+// setAgePolicy remplace la politique d'âge appliquée aux inscriptions
+func (s *SQLStore) setAgePolicy(p agePolicy) {
+	s.ages = p
+}
+
+// newSQLiteStore crée un UserStore basé sur la connexion SQLite fournie
+func newSQLiteStore(db *sql.DB) *SQLStore {
+	return newSQLStore(db, sqliteDialect)
+}
+
+const userColumns = "id, first_name, last_name, email, date_naissance, niveau_natation, created_at, updated_at, version"
+
+// prefixedUserColumns retourne userColumns qualifiées par l'alias de table (ex: "u")
+func prefixedUserColumns(alias string) string {
+	return alias + "." + strings.ReplaceAll(userColumns, ", ", ", "+alias+".")
+}
+
+// isUniqueViolation indique si l'erreur du driver est une violation de contrainte UNIQUE
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" // unique_violation
+}
+
+// translateError convertit les erreurs de contrainte du driver en erreurs du UserStore
+func translateError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrDuplicateEmail
+	}
+	return err
+}
+
+// rowScanner est satisfait par *sql.Row et *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// userDest reçoit les colonnes userColumns, éventuellement à la suite d'autres colonnes d'une jointure
+type userDest struct {
+	u              User
+	dateNaissance  sql.NullString
+	niveauNatation sql.NullString
+}
+
+// fields retourne les destinations de Scan dans l'ordre de userColumns
+func (d *userDest) fields() []interface{} {
+	return []interface{}{&d.u.ID, &d.u.FirstName, &d.u.LastName, &d.u.Email, &d.dateNaissance, &d.niveauNatation, &d.u.CreatedAt, &d.u.UpdatedAt, &d.u.Version}
+}
+
+// user retourne l'usager lu, avec son âge calculé
+func (d *userDest) user() User {
+	u := d.u
+	u.DateNaissance = d.dateNaissance.String
+	u.NiveauNatation = d.niveauNatation.String
+	u.Age = calculateAge(u.DateNaissance)
+	return u
+}
+
+// scanUser lit une ligne de la table users et calcule l'âge
+func scanUser(row rowScanner) (User, error) {
+	var d userDest
+	if err := row.Scan(d.fields()...); err != nil {
+		return User{}, err
+	}
+	return d.user(), nil
+}
+
+// List retourne les usagers correspondant au filtre, triés du plus récent au plus ancien
+func (s *SQLStore) List(ctx context.Context, filter UserFilter) ([]User, int, error) {
+	var whereConditions []string
+	var whereArgs []interface{}
+
+	// Recherche globale
+	if filter.Search != "" {
+		like := s.dialect.likeOp
+		whereConditions = append(whereConditions, "(first_name "+like+" ? OR last_name "+like+" ? OR email "+like+" ?)")
+		searchPattern := "%" + filter.Search + "%"
+		whereArgs = append(whereArgs, searchPattern, searchPattern, searchPattern)
+	}
+
+	// Filtre par niveau (un niveau ou tous les niveaux d'un programme)
+	if len(filter.Niveaux) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.Niveaux)), ", ")
+		whereConditions = append(whereConditions, "niveau_natation IN ("+placeholders+")")
+		for _, niveau := range filter.Niveaux {
+			whereArgs = append(whereArgs, niveau)
+		}
+	}
+
+	// Filtre par âge, en années révolues comme l'âge retourné
+	if filter.AgeMin != nil {
+		whereConditions = append(whereConditions, s.dialect.ageExpr+" >= ?")
+		whereArgs = append(whereArgs, *filter.AgeMin)
+	}
+	if filter.AgeMax != nil {
+		whereConditions = append(whereConditions, s.dialect.ageExpr+" <= ?")
+		whereArgs = append(whereArgs, *filter.AgeMax)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	query := `SELECT ` + userColumns + `
+		FROM users
+		` + whereClause + `
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`
+	args := append(append([]interface{}{}, whereArgs...), filter.Limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// Compter le total (avec ou sans recherche/filtres)
+	var total int
+	countQuery := `SELECT COUNT(*) FROM users ` + whereClause
+	if err := s.db.QueryRowContext(ctx, s.dialect.rebind(countQuery), whereArgs...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// Get retourne un usager par son ID
+func (s *SQLStore) Get(ctx context.Context, id int) (User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+userColumns+" FROM users WHERE id = ?"), id))
+	if err == sql.ErrNoRows {
+		return User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+// Create insère un nouvel usager
+func (s *SQLStore) Create(ctx context.Context, req UserRequest) (User, error) {
+	var u User
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := s.checkEmail(ctx, tx, req, 0); err != nil {
+			return err
+		}
+		var err error
+		u, err = scanUser(tx.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO users (first_name, last_name, email, date_naissance, niveau_natation) VALUES (?, ?, ?, ?, ?) RETURNING "+userColumns),
+			req.FirstName, req.LastName, req.Email, req.DateNaissance, req.NiveauNatation))
+		return err
+	})
+	return u, err
+}
+
+// Update modifie un usager existant et incrémente sa version
+func (s *SQLStore) Update(ctx context.Context, id int, req UserRequest, version int) (User, error) {
+	var u User
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := s.checkEmail(ctx, tx, req, id); err != nil {
+			return err
+		}
+		var err error
+		u, err = scanUser(tx.QueryRowContext(ctx, s.dialect.rebind(`UPDATE users
+			SET first_name = ?, last_name = ?, email = ?, date_naissance = ?, niveau_natation = ?,
+				updated_at = CURRENT_TIMESTAMP, version = version + 1
+			WHERE id = ? AND (? = 0 OR version = ?) RETURNING `+userColumns),
+			req.FirstName, req.LastName, req.Email, req.DateNaissance, req.NiveauNatation, id, version, version))
+		if err == sql.ErrNoRows {
+			return s.missingUserError(ctx, tx, id)
+		}
+		return err
+	})
+	return u, err
+}
+
+// missingUserError explique qu'une modification conditionnelle n'a touché aucune ligne :
+// ErrVersionConflict si l'usager existe (sa version a changé), ErrUserNotFound sinon
+func (s *SQLStore) missingUserError(ctx context.Context, tx *sql.Tx, id int) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT 1 FROM users WHERE id = ?"), id).Scan(&exists)
+	switch {
+	case err == sql.ErrNoRows:
+		return ErrUserNotFound
+	case err != nil:
+		return err
+	}
+	return ErrVersionConflict
+}
+
+// checkEmail vérifie qu'aucun autre usager majeur n'utilise l'email d'un usager majeur.
+// Un usager qui garde son email n'est pas vérifié : des frères et sœurs qui partagent l'email
+// d'un parent restent modifiables une fois majeurs.
+// Sous PostgreSQL, un verrou sur l'email sérialise les vérifications concurrentes.
+func (s *SQLStore) checkEmail(ctx context.Context, tx *sql.Tx, req UserRequest, exceptID int) error {
+	if req.Email == "" || !isAdult(req.DateNaissance) {
+		return nil
+	}
+	if exceptID != 0 {
+		var current string
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT email FROM users WHERE id = ?"), exceptID).Scan(&current)
+		if err == nil && current == req.Email {
+			return nil
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+	if s.dialect.lockKey != "" {
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind(s.dialect.lockKey), "users.email:"+req.Email); err != nil {
+			return err
+		}
+	}
+	var count int
+	err := tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT COUNT(*) FROM users
+		WHERE email = ? AND id <> ? AND date_naissance <> '' AND date_naissance <= ?`),
+		req.Email, exceptID, adultBirthCutoff()).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateEmail
+	}
+	return nil
+}
+
+// Delete supprime un usager. Les places qu'il libère dans ses cours sont attribuées
+// à la liste d'attente dans la même transaction.
+func (s *SQLStore) Delete(ctx context.Context, id int, version int) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, s.dialect.rebind("SELECT course_id FROM enrollments WHERE user_id = ? ORDER BY course_id"), id)
+		if err != nil {
+			return err
+		}
+		var courseIDs []int
+		for rows.Next() {
+			var courseID int
+			if err := rows.Scan(&courseID); err != nil {
+				rows.Close()
+				return err
+			}
+			courseIDs = append(courseIDs, courseID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Verrouiller les cours avant l'usager, dans le même ordre que Enroll
+		levels := make([]string, len(courseIDs))
+		capacities := make([]int, len(courseIDs))
+		for i, courseID := range courseIDs {
+			if levels[i], capacities[i], err = s.lockCourse(ctx, tx, courseID); err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM users WHERE id = ? AND (? = 0 OR version = ?)"), id, version, version)
+		if err != nil {
+			return err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return s.missingUserError(ctx, tx, id)
+		}
+
+		for i, courseID := range courseIDs {
+			if _, err := s.promoteFromWaitlist(ctx, tx, courseID, levels[i], capacities[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// withTx exécute fn dans une transaction, validée si fn ne retourne pas d'erreur
+func (s *SQLStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ping vérifie que la base de données répond
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// CheckMigrations vérifie que toutes les migrations embarquées sont appliquées, sans modification
+func (s *SQLStore) CheckMigrations(ctx context.Context) error {
+	m, err := newMigrator(s.db, s.dialect)
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if !st.Applied {
+			return fmt.Errorf("migration %04d_%s en attente", st.Version, st.Name)
+		}
+		if st.Modified {
+			return fmt.Errorf("migration %04d_%s modifiée depuis son application", st.Version, st.Name)
+		}
+	}
+	return nil
+}
+
+// CountUsersByLevel retourne le nombre d'usagers par valeur de niveau_natation
+func (s *SQLStore) CountUsersByLevel(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT niveau_natation, COUNT(*) FROM users GROUP BY niveau_natation")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var level string
+		var count int
+		if err := rows.Scan(&level, &count); err != nil {
+			return nil, err
+		}
+		counts[level] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const apiKeyColumns = "id, name, prefix, scopes, created_by, last_used_at, revoked_at, created_at"
+
+// apiKeyTouchInterval limite l'écriture de last_used_at à une fois par intervalle et par clé
+const apiKeyTouchInterval = time.Minute
+
+// scanAPIKey lit une ligne de la table api_keys
+func scanAPIKey(row rowScanner) (APIKey, error) {
+	var k APIKey
+	var scopes string
+	var createdBy sql.NullInt64
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &createdBy, &lastUsedAt, &revokedAt, &k.CreatedAt); err != nil {
+		return APIKey{}, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		k.CreatedBy = &id
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, nil
+}
+
+// ListAPIKeys retourne les clés d'API, de la plus récente à la plus ancienne
+func (s *SQLStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// CreateAPIKey insère une nouvelle clé d'API
+func (s *SQLStore) CreateAPIKey(ctx context.Context, name string, scopes []string, prefix, keyHash string, createdBy *int) (APIKey, error) {
+	return scanAPIKey(s.db.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by) VALUES (?, ?, ?, ?, ?) RETURNING "+apiKeyColumns),
+		name, prefix, keyHash, strings.Join(scopes, " "), createdBy))
+}
+
+// RotateAPIKey remplace le hash d'une clé non révoquée
+func (s *SQLStore) RotateAPIKey(ctx context.Context, id int, prefix, keyHash string) (APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx, s.dialect.rebind("UPDATE api_keys SET prefix = ?, key_hash = ? WHERE id = ? AND revoked_at IS NULL RETURNING "+apiKeyColumns),
+		prefix, keyHash, id))
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return k, err
+}
+
+// RevokeAPIKey révoque une clé; elle reste listée pour l'historique
+func (s *SQLStore) RevokeAPIKey(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"), time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// GetAPIKeyByHash retourne la clé non révoquée correspondant au hash
+func (s *SQLStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL"), keyHash))
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return k, err
+}
+
+// TouchAPIKey enregistre la date d'utilisation d'une clé, au plus une fois par apiKeyTouchInterval
+func (s *SQLStore) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	usedAt = usedAt.UTC()
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)"),
+		usedAt, id, usedAt.Add(-apiKeyTouchInterval))
+	return err
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// queryRower est satisfait par *sql.DB et *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// isoWeekday retourne le jour de la semaine au format des cours (1 = lundi ... 7 = dimanche)
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// meetingDates retourne les dates des séances d'un cours : chaque semaine, le jour du cours,
+// entre les dates de début et de fin incluses, sauf les dates exclues
+func meetingDates(weekday int, startDate, endDate string, excludeDates []string) ([]string, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, err
+	}
+	excluded := map[string]bool{}
+	for _, d := range excludeDates {
+		excluded[d] = true
+	}
+
+	first := start.AddDate(0, 0, (weekday-isoWeekday(start)+7)%7)
+	var dates []string
+	for d := first; !d.After(end); d = d.AddDate(0, 0, 7) {
+		if date := d.Format("2006-01-02"); !excluded[date] {
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
+}
+
+// computeRate calcule le taux de présence; les absences motivées ne sont pas comptées
+func (st *AttendanceStats) computeRate() {
+	st.Rate = nil
+	if n := st.Present + st.Absent; n > 0 {
+		rate := math.Round(float64(st.Present)/float64(n)*10000) / 10000
+		st.Rate = &rate
+	}
+}
+
+// add ajoute les présences de o
+func (st *AttendanceStats) add(o AttendanceStats) {
+	st.Present += o.Present
+	st.Absent += o.Absent
+	st.Excused += o.Excused
+}
+
+// attendanceSums compte les présences, absences et absences motivées des lignes jointes de la table attendance (alias a)
+const attendanceSums = `COALESCE(SUM(CASE WHEN a.status = 'present' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN a.status = 'absent' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN a.status = 'excused' THEN 1 ELSE 0 END), 0)`
+
+// GenerateMeetings crée les séances manquantes d'un cours
+func (s *SQLStore) GenerateMeetings(ctx context.Context, courseID int, excludeDates []string) ([]Meeting, error) {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var weekday int
+		var startDate, endDate string
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT weekday, start_date, end_date FROM courses WHERE id = ?"+s.dialect.forUpdate), courseID).
+			Scan(&weekday, &startDate, &endDate)
+		if err == sql.ErrNoRows {
+			return ErrCourseNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		dates, err := meetingDates(weekday, startDate, endDate, excludeDates)
+		if err != nil {
+			return err
+		}
+		for _, date := range dates {
+			_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO course_meetings (course_id, meeting_date) VALUES (?, ?)
+				ON CONFLICT (course_id, meeting_date) DO NOTHING`), courseID, date)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ListMeetings(ctx, courseID)
+}
+
+// ListMeetings retourne les séances d'un cours par date, avec le décompte des présences
+func (s *SQLStore) ListMeetings(ctx context.Context, courseID int) ([]Meeting, error) {
+	if _, err := s.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT m.id, m.course_id, m.meeting_date, c.start_time, c.end_time, `+attendanceSums+`, m.created_at
+		FROM course_meetings m
+		JOIN courses c ON c.id = m.course_id
+		LEFT JOIN attendance a ON a.meeting_id = m.id
+		WHERE m.course_id = ?
+		GROUP BY m.id, m.course_id, m.meeting_date, c.start_time, c.end_time, m.created_at
+		ORDER BY m.meeting_date`), courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	meetings := []Meeting{}
+	for rows.Next() {
+		var m Meeting
+		if err := rows.Scan(&m.ID, &m.CourseID, &m.Date, &m.StartTime, &m.EndTime, &m.Present, &m.Absent, &m.Excused, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		meetings = append(meetings, m)
+	}
+	return meetings, rows.Err()
+}
+
+// checkMeeting vérifie que la séance existe et appartient au cours
+func (s *SQLStore) checkMeeting(ctx context.Context, q queryRower, courseID, meetingID int) error {
+	var meetingCourse int
+	err := q.QueryRowContext(ctx, s.dialect.rebind("SELECT course_id FROM course_meetings WHERE id = ?"), meetingID).Scan(&meetingCourse)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && meetingCourse == courseID {
+		return nil
+	}
+
+	var exists int
+	if err := q.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM courses WHERE id = ?"), courseID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrCourseNotFound
+	}
+	return ErrMeetingNotFound
+}
+
+// ListAttendance retourne les présences enregistrées pour une séance, par nom d'usager
+func (s *SQLStore) ListAttendance(ctx context.Context, courseID, meetingID int) ([]AttendanceRecord, error) {
+	if err := s.checkMeeting(ctx, s.db, courseID, meetingID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT a.meeting_id, a.user_id, a.status, a.note, a.recorded_at, `+prefixedUserColumns("u")+`
+		FROM attendance a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.meeting_id = ?
+		ORDER BY u.last_name, u.first_name, u.id`), meetingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []AttendanceRecord{}
+	for rows.Next() {
+		var r AttendanceRecord
+		var d userDest
+		dest := append([]interface{}{&r.MeetingID, &r.UserID, &r.Status, &r.Note, &r.RecordedAt}, d.fields()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		u := d.user()
+		r.User = &u
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// MarkAttendance enregistre les présences d'une séance
+func (s *SQLStore) MarkAttendance(ctx context.Context, courseID, meetingID int, marks []AttendanceMark) ([]AttendanceRecord, error) {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		// Verrouiller le cours pour que les inscriptions ne changent pas pendant l'enregistrement
+		if _, _, err := s.lockCourse(ctx, tx, courseID); err != nil {
+			return err
+		}
+		if err := s.checkMeeting(ctx, tx, courseID, meetingID); err != nil {
+			return err
+		}
+
+		for _, mark := range marks {
+			var enrolled int
+			err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM enrollments WHERE course_id = ? AND user_id = ?"), courseID, mark.UserID).Scan(&enrolled)
+			if err != nil {
+				return err
+			}
+			if enrolled == 0 {
+				return NotEnrolledError{UserID: mark.UserID}
+			}
+
+			_, err = tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO attendance (meeting_id, user_id, status, note) VALUES (?, ?, ?, ?)
+				ON CONFLICT (meeting_id, user_id) DO UPDATE SET status = excluded.status, note = excluded.note, recorded_at = CURRENT_TIMESTAMP`),
+				meetingID, mark.UserID, mark.Status, mark.Note)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ListAttendance(ctx, courseID, meetingID)
+}
+
+// CourseAttendance retourne les taux de présence d'un cours, au total et pour chaque usager
+// inscrit ou ayant des présences enregistrées
+func (s *SQLStore) CourseAttendance(ctx context.Context, courseID int) (CourseAttendanceReport, error) {
+	report := CourseAttendanceReport{CourseID: courseID, Users: []UserAttendanceStats{}}
+	if _, err := s.GetCourse(ctx, courseID); err != nil {
+		return report, err
+	}
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM course_meetings WHERE course_id = ?"), courseID).Scan(&report.Meetings)
+	if err != nil {
+		return report, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT `+prefixedUserColumns("u")+`, `+attendanceSums+`
+		FROM users u
+		LEFT JOIN attendance a ON a.user_id = u.id AND a.meeting_id IN (SELECT id FROM course_meetings WHERE course_id = ?)
+		WHERE a.user_id IS NOT NULL OR u.id IN (SELECT user_id FROM enrollments WHERE course_id = ?)
+		GROUP BY `+prefixedUserColumns("u")+`
+		ORDER BY u.last_name, u.first_name, u.id`), courseID, courseID)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st UserAttendanceStats
+		var d userDest
+		if err := rows.Scan(append(d.fields(), &st.Present, &st.Absent, &st.Excused)...); err != nil {
+			return report, err
+		}
+		u := d.user()
+		st.UserID, st.User = u.ID, &u
+		st.computeRate()
+		report.add(st.AttendanceStats)
+		report.Users = append(report.Users, st)
+	}
+	report.computeRate()
+	return report, rows.Err()
+}
+
+// UserAttendance retourne les taux de présence d'un usager, au total et pour chacun de ses cours
+func (s *SQLStore) UserAttendance(ctx context.Context, userID int) (UserAttendanceReport, error) {
+	report := UserAttendanceReport{UserID: userID, Courses: []CourseAttendanceStats{}}
+	if _, err := s.Get(ctx, userID); err != nil {
+		return report, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT c.id, c.level, c.session, `+attendanceSums+`
+		FROM courses c
+		LEFT JOIN course_meetings m ON m.course_id = c.id
+		LEFT JOIN attendance a ON a.meeting_id = m.id AND a.user_id = ?
+		WHERE a.user_id IS NOT NULL OR c.id IN (SELECT course_id FROM enrollments WHERE user_id = ?)
+		GROUP BY c.id, c.level, c.session
+		ORDER BY c.session, c.id`), userID, userID)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st CourseAttendanceStats
+		if err := rows.Scan(&st.CourseID, &st.Level, &st.Session, &st.Present, &st.Absent, &st.Excused); err != nil {
+			return report, err
+		}
+		st.computeRate()
+		report.add(st.AttendanceStats)
+		report.Courses = append(report.Courses, st)
+	}
+	report.computeRate()
+	return report, rows.Err()
+}
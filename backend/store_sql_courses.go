@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const courseColumns = `c.id, c.level, c.session, c.weekday, c.start_time, c.end_time, c.start_date, c.end_date,
+	c.pool, c.capacity, c.instructor, c.created_at,
+	(SELECT COUNT(*) FROM enrollments e WHERE e.course_id = c.id) AS enrolled,
+	(SELECT COUNT(*) FROM waitlist_entries w WHERE w.course_id = c.id) AS waitlisted`
+
+// scanCourse lit une ligne sélectionnée avec courseColumns
+func scanCourse(row rowScanner) (Course, error) {
+	var c Course
+	err := row.Scan(&c.ID, &c.Level, &c.Session, &c.Weekday, &c.StartTime, &c.EndTime, &c.StartDate, &c.EndDate,
+		&c.Pool, &c.Capacity, &c.Instructor, &c.CreatedAt, &c.Enrolled, &c.Waitlisted)
+	return c, err
+}
+
+// ListCourses retourne les cours triés par session, jour et heure
+func (s *SQLStore) ListCourses(ctx context.Context, filter CourseFilter) ([]Course, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Session != "" {
+		conditions = append(conditions, "c.session = ?")
+		args = append(args, filter.Session)
+	}
+	if filter.Level != "" {
+		conditions = append(conditions, "c.level = ?")
+		args = append(args, filter.Level)
+	}
+	if filter.UserID != 0 {
+		conditions = append(conditions, "c.id IN (SELECT course_id FROM enrollments WHERE user_id = ?)")
+		args = append(args, filter.UserID)
+	}
+
+	query := "SELECT " + courseColumns + " FROM courses c"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY c.session, c.weekday, c.start_time, c.id"
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	courses := []Course{}
+	for rows.Next() {
+		c, err := scanCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, c)
+	}
+	return courses, rows.Err()
+}
+
+// GetCourse retourne un cours par son ID
+func (s *SQLStore) GetCourse(ctx context.Context, id int) (Course, error) {
+	c, err := scanCourse(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+courseColumns+" FROM courses c WHERE c.id = ?"), id))
+	if err == sql.ErrNoRows {
+		return Course{}, ErrCourseNotFound
+	}
+	return c, err
+}
+
+// CreateCourse insère un nouveau cours
+func (s *SQLStore) CreateCourse(ctx context.Context, req CourseRequest) (Course, error) {
+	var id int
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`INSERT INTO courses
+		(level, session, weekday, start_time, end_time, start_date, end_date, pool, capacity, instructor)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		req.Level, req.Session, req.Weekday, req.StartTime, req.EndTime, req.StartDate, req.EndDate,
+		req.Pool, req.Capacity, req.Instructor).Scan(&id)
+	if err != nil {
+		return Course{}, err
+	}
+	return s.GetCourse(ctx, id)
+}
+
+// UpdateCourse modifie un cours existant sans descendre la capacité sous le nombre d'inscrits
+func (s *SQLStore) UpdateCourse(ctx context.Context, id int, req CourseRequest) (Course, error) {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, _, err := s.lockCourse(ctx, tx, id); err != nil {
+			return err
+		}
+
+		var enrolled int
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM enrollments WHERE course_id = ?"), id).Scan(&enrolled)
+		if err != nil {
+			return err
+		}
+		if req.Capacity < enrolled {
+			return ErrCapacityTooLow
+		}
+
+		_, err = tx.ExecContext(ctx, s.dialect.rebind(`UPDATE courses SET level = ?, session = ?, weekday = ?, start_time = ?,
+			end_time = ?, start_date = ?, end_date = ?, pool = ?, capacity = ?, instructor = ? WHERE id = ?`),
+			req.Level, req.Session, req.Weekday, req.StartTime, req.EndTime, req.StartDate, req.EndDate,
+			req.Pool, req.Capacity, req.Instructor, id)
+		if err != nil {
+			return err
+		}
+
+		// Une hausse de capacité libère des places pour la liste d'attente
+		_, err = s.promoteFromWaitlist(ctx, tx, id, req.Level, req.Capacity)
+		return err
+	})
+	if err != nil {
+		return Course{}, err
+	}
+	return s.GetCourse(ctx, id)
+}
+
+// DeleteCourse supprime un cours (les inscriptions sont supprimées en cascade)
+func (s *SQLStore) DeleteCourse(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM courses WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrCourseNotFound
+	}
+	return nil
+}
+
+// ListEnrollments retourne les inscriptions d'un cours, par ordre d'inscription
+func (s *SQLStore) ListEnrollments(ctx context.Context, courseID int) ([]Enrollment, error) {
+	if _, err := s.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT e.id, e.course_id, e.user_id, e.created_at, `+prefixedUserColumns("u")+`
+		FROM enrollments e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.course_id = ?
+		ORDER BY e.created_at, e.id`), courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := []Enrollment{}
+	for rows.Next() {
+		var e Enrollment
+		var d userDest
+		if err := rows.Scan(append([]interface{}{&e.ID, &e.CourseID, &e.UserID, &e.CreatedAt}, d.fields()...)...); err != nil {
+			return nil, err
+		}
+		u := d.user()
+		e.User = &u
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, rows.Err()
+}
+
+// lockCourse verrouille un cours pour la durée de la transaction et retourne son niveau et sa capacité
+func (s *SQLStore) lockCourse(ctx context.Context, tx *sql.Tx, courseID int) (level string, capacity int, err error) {
+	err = tx.QueryRowContext(ctx, s.dialect.rebind("SELECT level, capacity FROM courses WHERE id = ?"+s.dialect.forUpdate), courseID).
+		Scan(&level, &capacity)
+	if err == sql.ErrNoRows {
+		return "", 0, ErrCourseNotFound
+	}
+	return level, capacity, err
+}
+
+// courseStart retourne la date de début d'un cours, à laquelle l'âge des usagers est vérifié.
+// Une date illisible est une erreur : l'âge ne doit pas être vérifié à une autre date.
+func (s *SQLStore) courseStart(ctx context.Context, tx *sql.Tx, courseID int) (time.Time, error) {
+	var startDate string
+	err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT start_date FROM courses WHERE id = ?"), courseID).Scan(&startDate)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrCourseNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cours %d: date de début invalide %q", courseID, startDate)
+	}
+	return start, nil
+}
+
+// Enroll inscrit un usager à un cours, ou l'ajoute à la liste d'attente si le cours est complet.
+// Le cours est verrouillé pendant la transaction afin que deux inscriptions concurrentes
+// ne dépassent pas la capacité.
+func (s *SQLStore) Enroll(ctx context.Context, courseID, userID int) (EnrollmentResult, error) {
+	var result EnrollmentResult
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		level, capacity, err := s.lockCourse(ctx, tx, courseID)
+		if err != nil {
+			return err
+		}
+
+		var userLevel, dateNaissance sql.NullString
+		err = tx.QueryRowContext(ctx, s.dialect.rebind("SELECT niveau_natation, date_naissance FROM users WHERE id = ?"), userID).
+			Scan(&userLevel, &dateNaissance)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var enrolledCount, waitlistedCount int
+		err = tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT
+			(SELECT COUNT(*) FROM enrollments WHERE course_id = ? AND user_id = ?),
+			(SELECT COUNT(*) FROM waitlist_entries WHERE course_id = ? AND user_id = ?)`),
+			courseID, userID, courseID, userID).Scan(&enrolledCount, &waitlistedCount)
+		if err != nil {
+			return err
+		}
+		if enrolledCount > 0 {
+			return ErrAlreadyEnrolled
+		}
+		if waitlistedCount > 0 {
+			return ErrAlreadyWaitlisted
+		}
+
+		if userLevel.String != level {
+			return ErrLevelMismatch
+		}
+		var warnings []AgeMismatch
+		if s.ages.mode != ageCheckOff {
+			start, err := s.courseStart(ctx, tx, courseID)
+			if err != nil {
+				return err
+			}
+			if mismatch := s.ages.check(level, dateNaissance.String, start); mismatch != nil {
+				if s.ages.mode == ageCheckReject {
+					return &AgeMismatchError{Mismatch: *mismatch}
+				}
+				warnings = []AgeMismatch{*mismatch}
+			}
+		}
+
+		var enrolled int
+		err = tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM enrollments WHERE course_id = ?"), courseID).Scan(&enrolled)
+		if err != nil {
+			return err
+		}
+		if enrolled >= capacity {
+			entry, err := s.addToWaitlist(ctx, tx, courseID, userID)
+			entry.Warnings = warnings
+			result.Waitlist = &entry
+			return err
+		}
+
+		e, err := s.insertEnrollment(ctx, tx, courseID, userID)
+		e.Warnings = warnings
+		result.Enrollment = &e
+		return err
+	})
+	return result, err
+}
+
+// insertEnrollment insère une inscription dans la transaction
+func (s *SQLStore) insertEnrollment(ctx context.Context, tx *sql.Tx, courseID, userID int) (Enrollment, error) {
+	var e Enrollment
+	err := tx.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO enrollments (course_id, user_id) VALUES (?, ?) RETURNING id, course_id, user_id, created_at"), courseID, userID).
+		Scan(&e.ID, &e.CourseID, &e.UserID, &e.CreatedAt)
+	if isUniqueViolation(err) {
+		return Enrollment{}, ErrAlreadyEnrolled
+	}
+	return e, err
+}
+
+// Unenroll annule l'inscription d'un usager et promeut le premier usager admissible de la liste d'attente
+func (s *SQLStore) Unenroll(ctx context.Context, courseID, userID int) (*Enrollment, error) {
+	var promoted *Enrollment
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		level, capacity, err := s.lockCourse(ctx, tx, courseID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM enrollments WHERE course_id = ? AND user_id = ?"), courseID, userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrEnrollmentNotFound
+		}
+
+		enrollments, err := s.promoteFromWaitlist(ctx, tx, courseID, level, capacity)
+		if len(enrollments) > 0 {
+			promoted = &enrollments[0]
+		}
+		return err
+	})
+	return promoted, err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const evaluationColumns = "id, user_id, level, criteria, passed, evaluator, evaluated_on, previous_level, new_level, created_at"
+
+// scanEvaluation lit une ligne de la table evaluations
+func scanEvaluation(row rowScanner) (Evaluation, error) {
+	var e Evaluation
+	var criteria string
+	err := row.Scan(&e.ID, &e.UserID, &e.Level, &criteria, &e.Passed, &e.Evaluator, &e.EvaluatedOn, &e.PreviousLevel, &e.NewLevel, &e.CreatedAt)
+	if err != nil {
+		return Evaluation{}, err
+	}
+	e.Criteria = []CriterionResult{}
+	if err := json.Unmarshal([]byte(criteria), &e.Criteria); err != nil {
+		return Evaluation{}, err
+	}
+	return e, nil
+}
+
+// ListEvaluations retourne l'historique des évaluations d'un usager
+func (s *SQLStore) ListEvaluations(ctx context.Context, userID int) ([]Evaluation, error) {
+	if _, err := s.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind("SELECT "+evaluationColumns+" FROM evaluations WHERE user_id = ? ORDER BY evaluated_on, id"), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	evaluations := []Evaluation{}
+	for rows.Next() {
+		e, err := scanEvaluation(rows)
+		if err != nil {
+			return nil, err
+		}
+		evaluations = append(evaluations, e)
+	}
+	return evaluations, rows.Err()
+}
+
+// CreateEvaluation enregistre une évaluation et fait progresser l'usager s'il a réussi.
+// L'âge de l'usager à la date de l'évaluation est vérifié pour le nouveau niveau, selon la politique d'âge.
+func (s *SQLStore) CreateEvaluation(ctx context.Context, userID int, req EvaluationRequest) (Evaluation, error) {
+	var evaluation Evaluation
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var current, dateNaissance sql.NullString
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT niveau_natation, date_naissance FROM users WHERE id = ?"+s.dialect.forUpdate), userID).Scan(&current, &dateNaissance)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if req.Level != "" {
+			// Un code de niveau (NAGEUR_3) désigne le même niveau que son libellé
+			if l, ok := findLevel(req.Level); !ok || l.Label != current.String {
+				return ErrEvaluationLevelMismatch
+			}
+		}
+
+		passed := req.Passed != nil && *req.Passed
+		newLevel := current.String
+		if passed {
+			if next, ok := nextLevel(current.String); ok {
+				newLevel = next
+			}
+		}
+
+		criteria := req.Criteria
+		if criteria == nil {
+			criteria = []CriterionResult{}
+		}
+		criteriaJSON, err := json.Marshal(criteria)
+		if err != nil {
+			return err
+		}
+		evaluatedOn := req.EvaluatedOn
+		if evaluatedOn == "" {
+			evaluatedOn = time.Now().Format("2006-01-02")
+		}
+		var warnings []AgeMismatch
+		if newLevel != current.String && s.ages.mode != ageCheckOff {
+			ref, err := time.Parse("2006-01-02", evaluatedOn)
+			if err != nil {
+				return fmt.Errorf("date d'évaluation illisible: %q", evaluatedOn)
+			}
+			if mismatch := s.ages.check(newLevel, dateNaissance.String, ref); mismatch != nil {
+				if s.ages.mode == ageCheckReject {
+					return &AgeMismatchError{Mismatch: *mismatch}
+				}
+				warnings = []AgeMismatch{*mismatch}
+			}
+		}
+
+		evaluation, err = scanEvaluation(tx.QueryRowContext(ctx, s.dialect.rebind(`INSERT INTO evaluations
+			(user_id, level, criteria, passed, evaluator, evaluated_on, previous_level, new_level)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING `+evaluationColumns),
+			userID, current.String, string(criteriaJSON), passed, req.Evaluator, evaluatedOn, current.String, newLevel))
+		if err != nil {
+			return err
+		}
+		evaluation.Warnings = warnings
+
+		if newLevel != current.String {
+			_, err = tx.ExecContext(ctx, s.dialect.rebind("UPDATE users SET niveau_natation = ?, updated_at = CURRENT_TIMESTAMP, version = version + 1 WHERE id = ?"), newLevel, userID)
+		}
+		return err
+	})
+	return evaluation, err
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const guardianColumns = "id, first_name, last_name, email, phone, password_hash IS NOT NULL, created_at"
+
+// prefixedGuardianColumns retourne guardianColumns qualifiées par l'alias de table (ex: "g")
+func prefixedGuardianColumns(alias string) string {
+	return alias + "." + strings.ReplaceAll(guardianColumns, ", ", ", "+alias+".")
+}
+
+// guardianFields retourne les destinations de Scan dans l'ordre de guardianColumns
+func guardianFields(g *Guardian) []interface{} {
+	return []interface{}{&g.ID, &g.FirstName, &g.LastName, &g.Email, &g.Phone, &g.PortalAccess, &g.CreatedAt}
+}
+
+// scanGuardian lit une ligne de la table guardians
+func scanGuardian(row rowScanner) (Guardian, error) {
+	var g Guardian
+	err := row.Scan(guardianFields(&g)...)
+	return g, err
+}
+
+// ListGuardians retourne les tuteurs triés par nom, filtrés par une recherche sur le nom ou l'email
+func (s *SQLStore) ListGuardians(ctx context.Context, search string) ([]Guardian, error) {
+	query := "SELECT " + guardianColumns + " FROM guardians"
+	var args []interface{}
+	if search != "" {
+		like := s.dialect.likeOp
+		query += " WHERE first_name " + like + " ? OR last_name " + like + " ? OR email " + like + " ?"
+		pattern := "%" + search + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+	query += " ORDER BY last_name, first_name, id"
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	guardians := []Guardian{}
+	for rows.Next() {
+		g, err := scanGuardian(rows)
+		if err != nil {
+			return nil, err
+		}
+		guardians = append(guardians, g)
+	}
+	return guardians, rows.Err()
+}
+
+// GetGuardian retourne un tuteur par son ID
+func (s *SQLStore) GetGuardian(ctx context.Context, id int) (Guardian, error) {
+	g, err := scanGuardian(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+guardianColumns+" FROM guardians WHERE id = ?"), id))
+	if err == sql.ErrNoRows {
+		return Guardian{}, ErrGuardianNotFound
+	}
+	return g, err
+}
+
+// CreateGuardian insère un nouveau tuteur
+func (s *SQLStore) CreateGuardian(ctx context.Context, req GuardianRequest) (Guardian, error) {
+	g, err := scanGuardian(s.db.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO guardians (first_name, last_name, email, phone) VALUES (?, ?, ?, ?) RETURNING "+guardianColumns),
+		req.FirstName, req.LastName, req.Email, req.Phone))
+	if err != nil {
+		return Guardian{}, translateError(err)
+	}
+	return g, nil
+}
+
+// UpdateGuardian modifie un tuteur existant
+func (s *SQLStore) UpdateGuardian(ctx context.Context, id int, req GuardianRequest) (Guardian, error) {
+	g, err := scanGuardian(s.db.QueryRowContext(ctx, s.dialect.rebind("UPDATE guardians SET first_name = ?, last_name = ?, email = ?, phone = ? WHERE id = ? RETURNING "+guardianColumns),
+		req.FirstName, req.LastName, req.Email, req.Phone, id))
+	if err == sql.ErrNoRows {
+		return Guardian{}, ErrGuardianNotFound
+	}
+	if err != nil {
+		return Guardian{}, translateError(err)
+	}
+	return g, nil
+}
+
+// DeleteGuardian supprime un tuteur
+func (s *SQLStore) DeleteGuardian(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM guardians WHERE id = ?"), id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrGuardianNotFound
+	}
+	return nil
+}
+
+// ListChildren retourne les usagers rattachés à un tuteur, du plus âgé au plus jeune
+func (s *SQLStore) ListChildren(ctx context.Context, guardianID int) ([]Child, error) {
+	if _, err := s.GetGuardian(ctx, guardianID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT ug.relationship, `+prefixedUserColumns("u")+`
+		FROM user_guardians ug
+		JOIN users u ON u.id = ug.user_id
+		WHERE ug.guardian_id = ?
+		ORDER BY u.date_naissance, u.id`), guardianID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	children := []Child{}
+	for rows.Next() {
+		var c Child
+		var d userDest
+		if err := rows.Scan(append([]interface{}{&c.Relationship}, d.fields()...)...); err != nil {
+			return nil, err
+		}
+		c.User = d.user()
+		children = append(children, c)
+	}
+	return children, rows.Err()
+}
+
+// LinkChild rattache un usager à un tuteur
+func (s *SQLStore) LinkChild(ctx context.Context, guardianID int, req GuardianLinkRequest) (Child, error) {
+	var child Child
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM guardians WHERE id = ?"), guardianID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrGuardianNotFound
+		}
+
+		u, err := scanUser(tx.QueryRowContext(ctx, s.dialect.rebind("SELECT "+userColumns+" FROM users WHERE id = ?"), req.UserID))
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM user_guardians WHERE user_id = ? AND guardian_id = ?"), req.UserID, guardianID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrAlreadyLinked
+		}
+
+		_, err = tx.ExecContext(ctx, s.dialect.rebind("INSERT INTO user_guardians (user_id, guardian_id, relationship) VALUES (?, ?, ?)"),
+			req.UserID, guardianID, req.Relationship)
+		child = Child{User: u, Relationship: req.Relationship}
+		return err
+	})
+	return child, err
+}
+
+// UnlinkChild détache un usager d'un tuteur
+func (s *SQLStore) UnlinkChild(ctx context.Context, guardianID, userID int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM user_guardians WHERE guardian_id = ? AND user_id = ?"), guardianID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		if _, err := s.GetGuardian(ctx, guardianID); err != nil {
+			return err
+		}
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// ListUserGuardians retourne les tuteurs d'un usager
+func (s *SQLStore) ListUserGuardians(ctx context.Context, userID int) ([]UserGuardian, error) {
+	if _, err := s.Get(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT ug.relationship, `+prefixedGuardianColumns("g")+`
+		FROM user_guardians ug
+		JOIN guardians g ON g.id = ug.guardian_id
+		WHERE ug.user_id = ?
+		ORDER BY g.last_name, g.first_name, g.id`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	guardians := []UserGuardian{}
+	for rows.Next() {
+		var ug UserGuardian
+		if err := rows.Scan(append([]interface{}{&ug.Relationship}, guardianFields(&ug.Guardian)...)...); err != nil {
+			return nil, err
+		}
+		guardians = append(guardians, ug)
+	}
+	return guardians, rows.Err()
+}
+
+// scanGuardianAccount lit un tuteur suivi de ses identifiants du portail
+func scanGuardianAccount(row rowScanner) (GuardianAccount, error) {
+	var acc GuardianAccount
+	var hash sql.NullString
+	var revokedAt sql.NullTime
+	if err := row.Scan(append(guardianFields(&acc.Guardian), &hash, &revokedAt)...); err != nil {
+		return GuardianAccount{}, err
+	}
+	acc.PasswordHash = hash.String
+	if revokedAt.Valid {
+		acc.TokensRevokedAt = &revokedAt.Time
+	}
+	return acc, nil
+}
+
+// GetGuardianAccount retourne un tuteur et ses identifiants du portail par son email
+func (s *SQLStore) GetGuardianAccount(ctx context.Context, email string) (GuardianAccount, error) {
+	return s.getGuardianAccount(ctx, "email = ?", email)
+}
+
+// GetGuardianAccountByID retourne un tuteur et ses identifiants du portail par son ID
+func (s *SQLStore) GetGuardianAccountByID(ctx context.Context, id int) (GuardianAccount, error) {
+	return s.getGuardianAccount(ctx, "id = ?", id)
+}
+
+func (s *SQLStore) getGuardianAccount(ctx context.Context, where string, arg interface{}) (GuardianAccount, error) {
+	acc, err := scanGuardianAccount(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+guardianColumns+", password_hash, tokens_revoked_at FROM guardians WHERE "+where), arg))
+	if err == sql.ErrNoRows {
+		return GuardianAccount{}, ErrGuardianNotFound
+	}
+	return acc, err
+}
+
+// SetGuardianPassword remplace le mot de passe du portail d'un tuteur et révoque ses jetons
+func (s *SQLStore) SetGuardianPassword(ctx context.Context, id int, passwordHash string) error {
+	return s.updateGuardianAccess(ctx, passwordHash, id)
+}
+
+// RevokeGuardianAccess retire l'accès au portail d'un tuteur et révoque ses jetons
+func (s *SQLStore) RevokeGuardianAccess(ctx context.Context, id int) error {
+	return s.updateGuardianAccess(ctx, nil, id)
+}
+
+// updateGuardianAccess remplace le hash du mot de passe (nil pour retirer l'accès) et révoque les jetons émis
+func (s *SQLStore) updateGuardianAccess(ctx context.Context, passwordHash interface{}, id int) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind("UPDATE guardians SET password_hash = ?, tokens_revoked_at = ? WHERE id = ?"),
+		passwordHash, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrGuardianNotFound
+	}
+	return nil
+}
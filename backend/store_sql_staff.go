@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const staffColumns = "id, email, name, role, password_hash, tokens_revoked_at, created_at"
+
+// scanStaff lit une ligne de la table staff
+func scanStaff(row rowScanner) (Staff, error) {
+	var st Staff
+	var revokedAt sql.NullTime
+	if err := row.Scan(&st.ID, &st.Email, &st.Name, &st.Role, &st.PasswordHash, &revokedAt, &st.CreatedAt); err != nil {
+		return Staff{}, err
+	}
+	if revokedAt.Valid {
+		st.TokensRevokedAt = &revokedAt.Time
+	}
+	return st, nil
+}
+
+// ListStaff retourne les comptes du personnel triés par email
+func (s *SQLStore) ListStaff(ctx context.Context) ([]Staff, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+staffColumns+" FROM staff ORDER BY email")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	staff := []Staff{}
+	for rows.Next() {
+		st, err := scanStaff(rows)
+		if err != nil {
+			return nil, err
+		}
+		staff = append(staff, st)
+	}
+	return staff, rows.Err()
+}
+
+// GetStaff retourne un compte du personnel par son ID
+func (s *SQLStore) GetStaff(ctx context.Context, id int) (Staff, error) {
+	st, err := scanStaff(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+staffColumns+" FROM staff WHERE id = ?"), id))
+	if err == sql.ErrNoRows {
+		return Staff{}, ErrStaffNotFound
+	}
+	return st, err
+}
+
+// GetStaffByEmail retourne un compte du personnel par son email, sans tenir compte de la casse
+// ni des espaces autour (les emails sont enregistrés normalisés, voir normalizeEmail)
+func (s *SQLStore) GetStaffByEmail(ctx context.Context, email string) (Staff, error) {
+	st, err := scanStaff(s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT "+staffColumns+" FROM staff WHERE email = ?"), normalizeEmail(email)))
+	if err == sql.ErrNoRows {
+		return Staff{}, ErrStaffNotFound
+	}
+	return st, err
+}
+
+// CreateStaff insère un nouveau compte du personnel, avec son email normalisé
+func (s *SQLStore) CreateStaff(ctx context.Context, email, name, role, passwordHash string) (Staff, error) {
+	st, err := scanStaff(s.db.QueryRowContext(ctx, s.dialect.rebind("INSERT INTO staff (email, name, role, password_hash) VALUES (?, ?, ?, ?) RETURNING "+staffColumns),
+		normalizeEmail(email), name, role, passwordHash))
+	if err != nil {
+		return Staff{}, translateError(err)
+	}
+	return st, nil
+}
+
+// SetStaffRole change le rôle d'un compte
+func (s *SQLStore) SetStaffRole(ctx context.Context, id int, role string) error {
+	return s.updateStaff(ctx, "UPDATE staff SET role = ? WHERE id = ?", role, id)
+}
+
+// SetStaffPassword remplace le mot de passe d'un compte et révoque ses jetons
+func (s *SQLStore) SetStaffPassword(ctx context.Context, id int, passwordHash string) error {
+	return s.updateStaff(ctx, "UPDATE staff SET password_hash = ?, tokens_revoked_at = ? WHERE id = ?", passwordHash, time.Now().UTC(), id)
+}
+
+// RevokeStaffTokens révoque tous les jetons émis jusqu'à maintenant pour un compte
+func (s *SQLStore) RevokeStaffTokens(ctx context.Context, id int) error {
+	return s.updateStaff(ctx, "UPDATE staff SET tokens_revoked_at = ? WHERE id = ?", time.Now().UTC(), id)
+}
+
+// updateStaff exécute une mise à jour d'un compte; ErrStaffNotFound si aucune ligne n'est modifiée
+func (s *SQLStore) updateStaff(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrStaffNotFound
+	}
+	return nil
+}
+
+// RevokeToken enregistre un jeton révoqué et purge ceux qui ont expiré depuis
+func (s *SQLStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	var inserted bool
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM revoked_tokens WHERE expires_at < ?"), time.Now().UTC()); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, s.dialect.rebind("INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?) ON CONFLICT (jti) DO NOTHING"),
+			jti, expiresAt.UTC())
+		if err != nil {
+			return err
+		}
+		n, _ := result.RowsAffected()
+		inserted = n > 0
+		return nil
+	})
+	return inserted, err
+}
+
+// IsTokenRevoked indique si le jeton a été révoqué
+func (s *SQLStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM revoked_tokens WHERE jti = ?"), jti).Scan(&count)
+	return count > 0, err
+}
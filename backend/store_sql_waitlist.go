@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// addToWaitlist ajoute un usager à la fin de la liste d'attente d'un cours verrouillé
+func (s *SQLStore) addToWaitlist(ctx context.Context, tx *sql.Tx, courseID, userID int) (WaitlistEntry, error) {
+	entry := WaitlistEntry{CourseID: courseID, UserID: userID}
+	err := tx.QueryRowContext(ctx, s.dialect.rebind(`INSERT INTO waitlist_entries (course_id, user_id, position)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM waitlist_entries WHERE course_id = ?))
+		RETURNING id, created_at`), courseID, userID, courseID).Scan(&entry.ID, &entry.CreatedAt)
+	if isUniqueViolation(err) {
+		return WaitlistEntry{}, ErrAlreadyWaitlisted
+	}
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM waitlist_entries WHERE course_id = ?"), courseID).Scan(&entry.Position)
+	return entry, err
+}
+
+// promoteFromWaitlist inscrit, dans l'ordre de la liste d'attente, les usagers dont le niveau
+// (et l'âge au début du cours, en mode reject) correspond au cours tant qu'il reste des places.
+// Les usagers non admissibles gardent leur rang. Le cours doit être verrouillé par la transaction.
+func (s *SQLStore) promoteFromWaitlist(ctx context.Context, tx *sql.Tx, courseID int, level string, capacity int) ([]Enrollment, error) {
+	var start time.Time
+	if s.ages.mode == ageCheckReject {
+		var err error
+		if start, err = s.courseStart(ctx, tx, courseID); err != nil {
+			return nil, err
+		}
+	}
+
+	var promoted []Enrollment
+	for {
+		var enrolled int
+		err := tx.QueryRowContext(ctx, s.dialect.rebind("SELECT COUNT(*) FROM enrollments WHERE course_id = ?"), courseID).Scan(&enrolled)
+		if err != nil {
+			return promoted, err
+		}
+		if enrolled >= capacity {
+			return promoted, nil
+		}
+
+		entryID, userID, err := s.nextEligible(ctx, tx, courseID, level, start)
+		if err == sql.ErrNoRows {
+			return promoted, nil
+		}
+		if err != nil {
+			return promoted, err
+		}
+
+		if _, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM waitlist_entries WHERE id = ?"), entryID); err != nil {
+			return promoted, err
+		}
+		e, err := s.insertEnrollment(ctx, tx, courseID, userID)
+		if err != nil {
+			return promoted, err
+		}
+		promoted = append(promoted, e)
+	}
+}
+
+// nextEligible retourne la première entrée de la liste d'attente admissible au cours : même niveau et,
+// en mode reject, âge compatible à la date de début start. sql.ErrNoRows si aucune ne l'est.
+func (s *SQLStore) nextEligible(ctx context.Context, tx *sql.Tx, courseID int, level string, start time.Time) (entryID, userID int, err error) {
+	rows, err := tx.QueryContext(ctx, s.dialect.rebind(`SELECT w.id, w.user_id, u.date_naissance
+		FROM waitlist_entries w
+		JOIN users u ON u.id = w.user_id
+		WHERE w.course_id = ? AND u.niveau_natation = ?
+		ORDER BY w.position, w.id`), courseID, level)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dateNaissance sql.NullString
+		if err := rows.Scan(&entryID, &userID, &dateNaissance); err != nil {
+			return 0, 0, err
+		}
+		if s.ages.mode == ageCheckReject && s.ages.check(level, dateNaissance.String, start) != nil {
+			continue
+		}
+		return entryID, userID, nil
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, sql.ErrNoRows
+}
+
+// ListWaitlist retourne la liste d'attente d'un cours dans l'ordre de promotion
+func (s *SQLStore) ListWaitlist(ctx context.Context, courseID int) ([]WaitlistEntry, error) {
+	if _, err := s.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT w.id, w.course_id, w.user_id, w.created_at, `+prefixedUserColumns("u")+`
+		FROM waitlist_entries w
+		JOIN users u ON u.id = w.user_id
+		WHERE w.course_id = ?
+		ORDER BY w.position, w.id`), courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []WaitlistEntry{}
+	for rows.Next() {
+		var entry WaitlistEntry
+		var d userDest
+		if err := rows.Scan(append([]interface{}{&entry.ID, &entry.CourseID, &entry.UserID, &entry.CreatedAt}, d.fields()...)...); err != nil {
+			return nil, err
+		}
+		u := d.user()
+		entry.User = &u
+		entry.Position = len(entries) + 1
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ReorderWaitlist applique un nouvel ordre complet à la liste d'attente d'un cours
+func (s *SQLStore) ReorderWaitlist(ctx context.Context, courseID int, userIDs []int) ([]WaitlistEntry, error) {
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, _, err := s.lockCourse(ctx, tx, courseID); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, s.dialect.rebind("SELECT user_id FROM waitlist_entries WHERE course_id = ?"), courseID)
+		if err != nil {
+			return err
+		}
+		current := map[int]bool{}
+		for rows.Next() {
+			var userID int
+			if err := rows.Scan(&userID); err != nil {
+				rows.Close()
+				return err
+			}
+			current[userID] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Le nouvel ordre doit être une permutation de la liste actuelle
+		if len(userIDs) != len(current) {
+			return ErrInvalidWaitlistOrder
+		}
+		seen := map[int]bool{}
+		for _, userID := range userIDs {
+			if !current[userID] || seen[userID] {
+				return ErrInvalidWaitlistOrder
+			}
+			seen[userID] = true
+		}
+
+		for i, userID := range userIDs {
+			_, err := tx.ExecContext(ctx, s.dialect.rebind("UPDATE waitlist_entries SET position = ? WHERE course_id = ? AND user_id = ?"), i+1, courseID, userID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ListWaitlist(ctx, courseID)
+}
+
+// RemoveFromWaitlist retire un usager de la liste d'attente d'un cours
+func (s *SQLStore) RemoveFromWaitlist(ctx context.Context, courseID, userID int) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, _, err := s.lockCourse(ctx, tx, courseID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, s.dialect.rebind("DELETE FROM waitlist_entries WHERE course_id = ? AND user_id = ?"), courseID, userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return ErrWaitlistEntryNotFound
+		}
+		return nil
+	})
+}
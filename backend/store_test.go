@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPostgresTestDB ouvre la base PostgreSQL désignée par TEST_POSTGRES_DSN
+// et repart d'un schéma vide migré. Le test est ignoré si la variable n'est pas définie.
+func setupPostgresTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN non défini, test PostgreSQL ignoré")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	require.NoError(t, db.Ping())
+
+	_, err = db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	require.NoError(t, err)
+	migrateTestDB(t, db, postgresDialect)
+	return db
+}
+
+// testUserStore vérifie le comportement commun à toutes les implémentations de UserStore
+func testUserStore(t *testing.T, store UserStore) {
+	ctx := context.Background()
+	yearsAgo := func(n int) string { return time.Now().AddDate(-n, 0, -1).Format("2006-01-02") }
+
+	jean, err := store.Create(ctx, UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: yearsAgo(14), NiveauNatation: "NAGEUR 3"})
+	require.NoError(t, err)
+	assert.Greater(t, jean.ID, 0)
+	assert.Equal(t, 14, jean.Age)
+	assert.False(t, jean.CreatedAt.IsZero())
+
+	_, err = store.Create(ctx, UserRequest{FirstName: "Marie", LastName: "Martin", Email: "marie@test.com", DateNaissance: yearsAgo(4), NiveauNatation: "PRÉSCOLAIRE 2"})
+	require.NoError(t, err)
+	_, err = store.Create(ctx, UserRequest{FirstName: "Luc", LastName: "Bernard", Email: "luc@test.com", DateNaissance: yearsAgo(9), NiveauNatation: "NAGEUR 3"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		filter UserFilter
+		total  int
+	}{
+		{"tous", UserFilter{}, 3},
+		{"recherche insensible à la casse", UserFilter{Search: "jEaN"}, 1},
+		{"niveau", UserFilter{Niveaux: []string{"NAGEUR 3"}}, 2},
+		{"plusieurs niveaux", UserFilter{Niveaux: []string{"NAGEUR 3", "PRÉSCOLAIRE 2"}}, 3},
+		{"âge minimum", UserFilter{AgeMin: intPtr(5)}, 2},
+		{"âge maximum", UserFilter{AgeMax: intPtr(10)}, 2},
+		{"âge et niveau", UserFilter{Niveaux: []string{"NAGEUR 3"}, AgeMin: intPtr(5), AgeMax: intPtr(10)}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.filter.Limit = 10
+			users, total, err := store.List(ctx, tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.total, total)
+			assert.Len(t, users, tc.total)
+		})
+	}
+
+	users, total, err := store.List(ctx, UserFilter{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, users, 1)
+	assert.Equal(t, jean.ID, users[0].ID)
+
+	assert.Equal(t, 1, jean.Version)
+	updated, err := store.Update(ctx, jean.ID, UserRequest{FirstName: "Jean", LastName: "Martin", Email: "jean.martin@test.com", DateNaissance: jean.DateNaissance, NiveauNatation: "NAGEUR 4"}, jean.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "Martin", updated.LastName)
+	assert.Equal(t, "NAGEUR 4", updated.NiveauNatation)
+	assert.Equal(t, 2, updated.Version)
+	assert.False(t, updated.UpdatedAt.Before(jean.UpdatedAt))
+
+	// Une version dépassée n'écrase pas la modification d'une autre requête
+	_, err = store.Update(ctx, jean.ID, UserRequest{FirstName: "Jean", LastName: "Dupont", Email: "jean@test.com", DateNaissance: jean.DateNaissance, NiveauNatation: "NAGEUR 3"}, jean.Version)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.ErrorIs(t, store.Delete(ctx, jean.ID, jean.Version), ErrVersionConflict)
+	current, err := store.Get(ctx, jean.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Martin", current.LastName)
+
+	// L'email n'est unique qu'entre usagers majeurs : des enfants peuvent partager l'email d'un parent
+	// ou ne pas en avoir
+	_, err = store.Update(ctx, jean.ID, UserRequest{FirstName: "Jean", LastName: "Martin", Email: "marie@test.com", DateNaissance: jean.DateNaissance, NiveauNatation: "NAGEUR 4"}, 0)
+	require.NoError(t, err)
+	parent, err := store.Create(ctx, UserRequest{FirstName: "Paul", LastName: "Martin", Email: "marie@test.com", DateNaissance: yearsAgo(40), NiveauNatation: "NAGEUR 9"})
+	require.NoError(t, err)
+	_, err = store.Create(ctx, UserRequest{FirstName: "Autre", LastName: "Parent", Email: "marie@test.com", DateNaissance: yearsAgo(20), NiveauNatation: "NAGEUR 9"})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+	_, err = store.Create(ctx, UserRequest{FirstName: "Léo", LastName: "Martin", DateNaissance: yearsAgo(6), NiveauNatation: "NAGEUR 1"})
+	require.NoError(t, err)
+	_, err = store.Update(ctx, parent.ID, UserRequest{FirstName: "Paul", LastName: "Martin", Email: "marie@test.com", DateNaissance: yearsAgo(41), NiveauNatation: "NAGEUR 9"}, 0)
+	require.NoError(t, err)
+
+	// Devenu majeur, l'enfant reste modifiable tant qu'il garde l'email partagé, mais ne peut pas en prendre un autre déjà utilisé
+	_, err = store.Update(ctx, jean.ID, UserRequest{FirstName: "Jean", LastName: "Martin", Email: "marie@test.com", DateNaissance: yearsAgo(25), NiveauNatation: "NAGEUR 4"}, 0)
+	require.NoError(t, err)
+	_, err = store.Update(ctx, parent.ID, UserRequest{FirstName: "Paul", LastName: "Martin", Email: "jean@test.com", DateNaissance: yearsAgo(41), NiveauNatation: "NAGEUR 9"}, 0)
+	require.NoError(t, err)
+	_, err = store.Update(ctx, jean.ID, UserRequest{FirstName: "Jean", LastName: "Martin", Email: "jean@test.com", DateNaissance: yearsAgo(25), NiveauNatation: "NAGEUR 4"}, 0)
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	_, err = store.Update(ctx, 9999, UserRequest{FirstName: "X", LastName: "Y", Email: "x@test.com", DateNaissance: yearsAgo(10), NiveauNatation: "NAGEUR 1"}, 0)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	require.NoError(t, store.Delete(ctx, jean.ID, 0))
+	_, err = store.Get(ctx, jean.ID)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.ErrorIs(t, store.Delete(ctx, jean.ID, 0), ErrUserNotFound)
+	assert.ErrorIs(t, store.Delete(ctx, parent.ID+1000, 1), ErrUserNotFound)
+
+	// Le filtre d'âge porte sur l'âge retourné, en années révolues : à 10 ans et demi
+	// comme le jour de ses 10 ans, un usager a 10 ans
+	for _, born := range []time.Time{time.Now().AddDate(-10, -6, 0), time.Now().AddDate(-10, 0, 0)} {
+		u, err := store.Create(ctx, UserRequest{FirstName: "Zoé", LastName: "Petit", DateNaissance: born.Format("2006-01-02"), NiveauNatation: "NAGEUR 2"})
+		require.NoError(t, err)
+		assert.Equal(t, 10, u.Age)
+	}
+	_, total, err = store.List(ctx, UserFilter{AgeMin: intPtr(10), AgeMax: intPtr(10), Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	_, total, err = store.List(ctx, UserFilter{AgeMin: intPtr(11), AgeMax: intPtr(39), Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+}
+
+func TestMemoryUserStore(t *testing.T) {
+	testUserStore(t, newMemoryStore())
+}
+
+func TestSQLiteUserStore(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+
+	testUserStore(t, newSQLiteStore(testDB))
+}
+
+func TestPostgresUserStore(t *testing.T) {
+	testDB := setupPostgresTestDB(t)
+	defer testDB.Close()
+
+	testUserStore(t, newSQLStore(testDB, postgresDialect))
+}
+
+func TestDialectRebind(t *testing.T) {
+	query := "SELECT * FROM users WHERE id = ? AND email = ?"
+	assert.Equal(t, query, sqliteDialect.rebind(query))
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1 AND email = $2", postgresDialect.rebind(query))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Limites des champs personnels (usagers et tuteurs)
+const (
+	maxNameLength  = 100 // En caractères
+	maxEmailLength = 254 // Longueur maximale d'une adresse (RFC 5321)
+	maxAgeYears    = 120 // Une date de naissance plus ancienne est une erreur de saisie
+)
+
+// emailValidator vérifie le format des emails après normalisation (même règle que la balise binding "email")
+var emailValidator = validator.New()
+
+// normalizeName retire les espaces autour et en double, et compose les caractères accentués (Unicode NFC) :
+// un « é » saisi avec un accent combinant est enregistré comme le « é » précomposé, ce qui rend
+// la recherche et le tri indépendants de la saisie
+func normalizeName(name string) string {
+	return norm.NFC.String(strings.Join(strings.Fields(name), " "))
+}
+
+// normalizeEmail retire les espaces autour et met l'email en minuscules : l'unicité des emails
+// (usagers majeurs, tuteurs) et la connexion au portail ne dépendent pas de la casse
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateName normalise un nom (prénom ou nom de famille) et vérifie qu'il n'est pas vide,
+// ne dépasse pas maxNameLength caractères et ne contient pas de caractère de contrôle
+func validateName(field string, name *string) []FieldError {
+	*name = normalizeName(*name)
+	switch {
+	case *name == "":
+		return []FieldError{fieldError(field, "required", "Champ requis")}
+	case utf8.RuneCountInString(*name) > maxNameLength:
+		return []FieldError{fieldError(field, "max", "Au plus %d caractères", maxNameLength)}
+	case strings.IndexFunc(*name, unicode.IsControl) >= 0 || !utf8.ValidString(*name):
+		return []FieldError{fieldError(field, "invalid_characters", "Caractères non permis")}
+	}
+	return nil
+}
+
+// validateEmail normalise un email et vérifie son format et sa longueur
+func validateEmail(field string, email *string) []FieldError {
+	*email = normalizeEmail(*email)
+	switch {
+	case *email == "":
+		return []FieldError{fieldError(field, "required", "Champ requis")}
+	case len(*email) > maxEmailLength:
+		return []FieldError{fieldError(field, "max", "Au plus %d caractères", maxEmailLength)}
+	case emailValidator.Var(*email, "email") != nil:
+		return []FieldError{fieldError(field, "email", "Adresse email invalide")}
+	}
+	return nil
+}
+
+// validateBirthDate vérifie qu'une date de naissance est une date réelle au format YYYY-MM-DD
+// (pas de 30 février), qu'elle n'est pas après today et qu'elle date de moins de maxAgeYears ans
+func validateBirthDate(field, value string, today time.Time) []FieldError {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return []FieldError{fieldError(field, "date", "Date invalide (format YYYY-MM-DD)")}
+	}
+	today = calendarDay(today)
+	switch {
+	case date.After(today):
+		return []FieldError{fieldError(field, "past", "La date de naissance ne peut pas être dans le futur")}
+	case date.Before(today.AddDate(-maxAgeYears, 0, 0)):
+		return []FieldError{fieldError(field, "plausible_range", "La date de naissance doit dater de moins de %d ans", maxAgeYears)}
+	}
+	return nil
+}
+
+// validateEvaluationDate vérifie qu'une date d'évaluation (YYYY-MM-DD) n'est pas après today : une évaluation
+// réussie peut faire passer l'usager au niveau suivant. Une date vide désigne aujourd'hui.
+func validateEvaluationDate(field, value string, today time.Time) []FieldError {
+	if value == "" {
+		return nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return []FieldError{fieldError(field, "date", "Date invalide (format YYYY-MM-DD)")}
+	}
+	if date.After(calendarDay(today)) {
+		return []FieldError{fieldError(field, "past", "La date d'évaluation ne peut pas être dans le futur")}
+	}
+	return nil
+}
+
+// calendarDay retourne le jour de t (heure locale) à minuit UTC, pour le comparer aux dates YYYY-MM-DD
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// validatePersonFields normalise et vérifie le prénom, le nom et l'email d'un usager ou d'un tuteur
+func validatePersonFields(firstName, lastName, email *string) []FieldError {
+	var fields []FieldError
+	fields = append(fields, validateName("first_name", firstName)...)
+	fields = append(fields, validateName("last_name", lastName)...)
+	fields = append(fields, validateEmail("email", email)...)
+	return fields
+}
+
+// validateUserFields normalise et vérifie le prénom, le nom et l'email d'un usager.
+// L'email est facultatif : un enfant lié à un tuteur peut ne pas avoir d'email personnel.
+func validateUserFields(firstName, lastName, email *string) []FieldError {
+	var fields []FieldError
+	fields = append(fields, validateName("first_name", firstName)...)
+	fields = append(fields, validateName("last_name", lastName)...)
+	if *email = normalizeEmail(*email); *email != "" {
+		fields = append(fields, validateEmail("email", email)...)
+	}
+	return fields
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePersonFields(t *testing.T) {
+	// « é » saisi avec un accent combinant, espaces en trop
+	first, last, email := "  Ame\u0301lie  ", "Du   Pont", "  Amelie.Dupont@Test.COM "
+	assert.Empty(t, validatePersonFields(&first, &last, &email))
+	assert.Equal(t, "Amélie", first)
+	assert.Equal(t, "Du Pont", last)
+	assert.Equal(t, "amelie.dupont@test.com", email)
+
+	codes := func(fields []FieldError) map[string]string {
+		m := map[string]string{}
+		for _, fe := range fields {
+			m[fe.Field] = fe.Code
+		}
+		return m
+	}
+	first, last, email = "   ", strings.Repeat("é", maxNameLength+1), "pas un email"
+	assert.Equal(t, map[string]string{"first_name": "required", "last_name": "max", "email": "email"}, codes(validatePersonFields(&first, &last, &email)))
+	first, last, email = "Jean\x00", strings.Repeat("é", maxNameLength), strings.Repeat("a", maxEmailLength)+"@test.com"
+	assert.Equal(t, map[string]string{"first_name": "invalid_characters", "email": "max"}, codes(validatePersonFields(&first, &last, &email)))
+}
+
+func TestValidateBirthDate(t *testing.T) {
+	today := time.Date(2024, 6, 15, 18, 30, 0, 0, time.Local)
+	tests := map[string]string{
+		"2015-05-15": "",
+		"2024-06-15": "", // Né aujourd'hui
+		"1904-06-15": "", // Exactement 120 ans
+		"2024-06-16": "past",
+		"1904-06-14": "plausible_range",
+		"2023-02-30": "date",
+		"2015-5-15":  "date",
+		"15/05/2015": "date",
+		"":           "date",
+	}
+	for value, code := range tests {
+		fields := validateBirthDate("date_naissance", value, today)
+		if code == "" {
+			assert.Empty(t, fields, value)
+			continue
+		}
+		require.Len(t, fields, 1, value)
+		assert.Equal(t, "date_naissance", fields[0].Field, value)
+		assert.Equal(t, code, fields[0].Code, value)
+	}
+}
+
+func TestUserFieldValidation(t *testing.T) {
+	testDB := setupTestDB(t)
+	defer testDB.Close()
+	r := setupRouter(testDB)
+
+	// Toutes les erreurs de champ sont retournées ensemble
+	w := performRequest(r, "POST", "/api/users", UserRequest{FirstName: " ", LastName: "Dupont", Email: "jean@test.com", DateNaissance: "2099-01-01", NiveauNatation: "NAGEUR 3"})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p := decodeProblem(t, w)
+	fields := map[string]string{}
+	for _, fe := range p.Errors {
+		fields[fe.Field] = fe.Code
+	}
+	assert.Equal(t, map[string]string{"first_name": "required", "date_naissance": "past"}, fields)
+
+	// Les champs sont enregistrés normalisés
+	adult := UserRequest{FirstName: " Amélie ", LastName: "Dupont", Email: " Amelie@Test.com", DateNaissance: "1980-05-15", NiveauNatation: "NAGEUR 3"}
+	w = performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var u User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &u))
+	assert.Equal(t, "Amélie", u.FirstName)
+	assert.Equal(t, "amelie@test.com", u.Email)
+
+	// L'unicité de l'email des usagers majeurs ne dépend pas de la casse
+	adult.FirstName = "Paul"
+	adult.Email = "AMELIE@test.com"
+	w = performRequest(r, "POST", "/api/users", adult)
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodeDuplicateEmail, decodeProblem(t, w).Code)
+
+	// Même validation pour les tuteurs
+	w = performRequest(r, "POST", "/api/guardians", GuardianRequest{FirstName: "Marie", LastName: strings.Repeat("x", maxNameLength+1), Email: "Marie@Test.com"})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	p = decodeProblem(t, w)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, FieldError{Field: "last_name", Code: "max", Message: "Au plus 100 caractères"}, p.Errors[0])
+	w = performRequest(r, "POST", "/api/guardians", GuardianRequest{FirstName: "Marie", LastName: "Dupont", Email: "Marie@Test.com "})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var g Guardian
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &g))
+	assert.Equal(t, "marie@test.com", g.Email)
+}
@@ -4,8 +4,9 @@
 import { format } from 'https://cdn.jsdelivr.net/npm/date-fns@3.0.0/+esm';
 import { fr } from 'https://cdn.jsdelivr.net/npm/date-fns@3.0.0/locale/fr/+esm';
 
-import { API_BASE_URL, DEFAULT_PAGE, DEFAULT_LIMIT, SEARCH_DEBOUNCE_MS, MESSAGE_DISPLAY_DURATION_MS } from './config.js';
-import { escapeHtml } from './utils.js';
+import { API_BASE_URL, LEVELS_API_URL, DEFAULT_PAGE, DEFAULT_LIMIT, SEARCH_DEBOUNCE_MS, MESSAGE_DISPLAY_DURATION_MS } from './config.js';
+import { escapeHtml, problemMessage } from './utils.js';
+import { apiFetch, can, isLoggedIn, login, logout, onLoginRequired } from './auth.js';
 
 // Éléments DOM
 const usersList = document.getElementById('usersList');
@@ -21,9 +22,15 @@ const filterNiveau = document.getElementById('filterNiveau');
 const filterAgeMin = document.getElementById('filterAgeMin');
 const filterAgeMax = document.getElementById('filterAgeMax');
 const clearFiltersBtn = document.getElementById('clearFilters');
+const niveauNatationSelect = document.getElementById('niveauNatation');
+const loginForm = document.getElementById('loginForm');
+const loginFormElement = document.getElementById('loginFormElement');
+const appContent = document.getElementById('appContent');
+const logoutBtn = document.getElementById('logoutBtn');
 
 // État de l'application
 let editingUserId = null;
+let editingETag = null; // Version de l'usager chargée dans le formulaire (If-Match)
 let currentPage = DEFAULT_PAGE;
 let currentLimit = DEFAULT_LIMIT;
 let currentSearch = '';
@@ -32,11 +39,23 @@ let currentFilterAgeMin = '';
 let currentFilterAgeMax = '';
 let totalPages = 1;
 let searchTimeout = null;
+let levelsLoaded = false;
 
 // Initialisation au chargement de la page
 document.addEventListener('DOMContentLoaded', () => {
+    onLoginRequired(showLogin);
+    if (isLoggedIn()) {
+        logoutBtn.style.display = 'inline-block';
+    }
+    applyPermissions();
+    // Une date de naissance ne peut pas être dans le futur (vérifié aussi par l'API)
+    document.getElementById('dateNaissance').max = new Date().toISOString().slice(0, 10);
+    loadLevels();
     loadUsers();
     
+    loginFormElement.addEventListener('submit', handleLogin);
+    logoutBtn.addEventListener('click', handleLogout);
+    
     addUserBtn.addEventListener('click', () => {
         showForm();
     });
@@ -96,6 +115,88 @@ document.addEventListener('DOMContentLoaded', () => {
     });
 });
 
+// Afficher le formulaire de connexion à la place de l'application
+function showLogin() {
+    appContent.style.display = 'none';
+    loginForm.style.display = 'block';
+    document.getElementById('loginEmail').focus();
+}
+
+// Connecter le membre du personnel puis charger l'application
+async function handleLogin(e) {
+    e.preventDefault();
+    
+    try {
+        const staff = await login(
+            document.getElementById('loginEmail').value.trim(),
+            document.getElementById('loginPassword').value
+        );
+        loginFormElement.reset();
+        loginForm.style.display = 'none';
+        appContent.style.display = 'block';
+        logoutBtn.style.display = 'inline-block';
+        applyPermissions();
+        showMessage(`Connecté en tant que ${staff.name}`, 'success');
+        loadLevels();
+        loadUsers();
+    } catch (error) {
+        showMessage('Erreur: ' + error.message, 'error');
+    }
+}
+
+// Masquer les actions que le rôle du compte connecté ne permet pas
+function applyPermissions() {
+    addUserBtn.style.display = can('users:write') ? '' : 'none';
+}
+
+// Déconnecter le membre du personnel
+async function handleLogout() {
+    await logout().catch(() => {});
+    logoutBtn.style.display = 'none';
+    showLogin();
+}
+
+// Charger le catalogue des niveaux et remplir les listes déroulantes
+async function loadLevels() {
+    if (levelsLoaded) return;
+    try {
+        const response = await apiFetch(LEVELS_API_URL);
+        if (!response.ok) throw new Error(`Erreur ${response.status}: ${response.statusText}`);
+        
+        const data = await response.json();
+        levelsLoaded = true;
+        data.programs.forEach(program => {
+            const programLevels = data.levels.filter(level => level.program === program.code);
+            
+            // Formulaire : les niveaux du programme, avec la tranche d'âge
+            const formGroup = document.createElement('optgroup');
+            formGroup.label = `${program.name} (${formatAgeRange(program)})`;
+            programLevels.forEach(level => formGroup.appendChild(new Option(level.name, level.label)));
+            niveauNatationSelect.appendChild(formGroup);
+            
+            // Filtre : le programme entier, puis chacun de ses niveaux
+            const filterGroup = document.createElement('optgroup');
+            filterGroup.label = program.name;
+            filterGroup.appendChild(new Option(`Tout le programme ${program.name}`, program.code));
+            programLevels.forEach(level => filterGroup.appendChild(new Option(level.name, level.code)));
+            filterNiveau.appendChild(filterGroup);
+        });
+    } catch (error) {
+        showMessage('Erreur lors du chargement des niveaux: ' + error.message, 'error');
+    }
+}
+
+// Décrire la tranche d'âge d'un programme (ex: "3 à 5 ans")
+function formatAgeRange(program) {
+    if (program.max_age === null || program.max_age === undefined) {
+        return `${program.min_age} ans et plus`;
+    }
+    if (program.min_age === 0) {
+        return `jusqu'à ${program.max_age} ans`;
+    }
+    return `${program.min_age} à ${program.max_age} ans`;
+}
+
 // Charger les usagers avec pagination et recherche
 async function loadUsers() {
     try {
@@ -117,15 +218,15 @@ async function loadUsers() {
         }
         
         const url = `${API_BASE_URL}?${params.toString()}`;
-        const response = await fetch(url);
+        const response = await apiFetch(url);
         const contentType = response.headers.get("content-type");
         
         if (!response.ok) {
             let errorMessage = 'Erreur lors du chargement';
-            if (contentType && contentType.includes("application/json")) {
+            if (contentType && contentType.includes("json")) {
                 try {
                     const errorData = await response.json();
-                    errorMessage = errorData.error || errorMessage;
+                    errorMessage = problemMessage(errorData, errorMessage);
                 } catch (e) {
                     // Ignore JSON parse error
                 }
@@ -178,14 +279,14 @@ function displayUsers(users) {
         <div class="user-card">
             <div class="user-info">
                 <h3>${escapeHtml(user.first_name)} ${escapeHtml(user.last_name)}</h3>
-                <p class="email">${escapeHtml(user.email)}</p>
+                ${user.email ? `<p class="email">${escapeHtml(user.email)}</p>` : ''}
                 <p class="age">Âge: ${user.age || 'N/A'} ans</p>
                 <p class="niveau">Niveau: ${escapeHtml(user.niveau_natation || 'N/A')}</p>
                 <p class="date">Créé le ${format(new Date(user.created_at), "d MMMM yyyy 'à' HH'h'mm", { locale: fr })}</p>
             </div>
             <div class="user-actions">
-                <button class="btn btn-edit" onclick="editUser(${user.id})" aria-label="Modifier ${escapeHtml(user.first_name)} ${escapeHtml(user.last_name)}">Modifier</button>
-                <button class="btn btn-delete" onclick="deleteUser(${user.id})" aria-label="Supprimer ${escapeHtml(user.first_name)} ${escapeHtml(user.last_name)}">Supprimer</button>
+                ${can('users:write') ? `<button class="btn btn-edit" onclick="editUser(${user.id})" aria-label="Modifier ${escapeHtml(user.first_name)} ${escapeHtml(user.last_name)}">Modifier</button>` : ''}
+                ${can('users:delete') ? `<button class="btn btn-delete" onclick="deleteUser(${user.id}, ${user.version})" aria-label="Supprimer ${escapeHtml(user.first_name)} ${escapeHtml(user.last_name)}">Supprimer</button>` : ''}
             </div>
         </div>
     `).join('');
@@ -262,8 +363,9 @@ window.goToPage = function(page) {
 };
 
 // Afficher le formulaire
-function showForm(user = null) {
+function showForm(user = null, etag = null) {
     editingUserId = user ? user.id : null;
+    editingETag = etag;
     formTitle.textContent = user ? 'Modifier un usager' : 'Ajouter un usager';
     
     if (user) {
@@ -286,6 +388,7 @@ function hideForm() {
     userForm.style.display = 'none';
     userFormElement.reset();
     editingUserId = null;
+    editingETag = null;
 }
 
 // Gérer la soumission du formulaire
@@ -306,24 +409,36 @@ async function handleFormSubmit(e) {
             : API_BASE_URL;
         
         const method = editingUserId ? 'PUT' : 'POST';
+        const headers = { 'Content-Type': 'application/json' };
+        if (editingUserId && editingETag) {
+            // Refusé (412) si l'usager a été modifié par quelqu'un d'autre depuis l'ouverture du formulaire
+            headers['If-Match'] = editingETag;
+        }
         
-        const response = await fetch(url, {
+        const response = await apiFetch(url, {
             method: method,
-            headers: {
-                'Content-Type': 'application/json'
-            },
+            headers: headers,
             body: JSON.stringify(userData)
         });
         
+        if (response.status === 412) {
+            throw new Error('Cet usager a été modifié par quelqu\'un d\'autre. Rouvrez-le pour voir ses données à jour avant de réessayer.');
+        }
         if (!response.ok) {
             const error = await response.json();
-            throw new Error(error.error || 'Erreur lors de l\'enregistrement');
+            throw new Error(problemMessage(error, 'Erreur lors de l\'enregistrement'));
         }
         
+        // Avertissement si l'âge ne correspond pas au niveau (AGE_CHECK=warn)
+        const savedUser = await response.json();
+        const warnings = (savedUser.warnings || [])
+            .map(w => ` Attention : ${w.age} ans est hors de la tranche d'âge du niveau ${w.level}.`)
+            .join('');
+        
         showMessage(
-            editingUserId 
+            (editingUserId 
                 ? 'Usager modifié avec succès' 
-                : 'Usager créé avec succès',
+                : 'Usager créé avec succès') + warnings,
             'success'
         );
         
@@ -338,30 +453,35 @@ async function handleFormSubmit(e) {
 // Modifier un usager
 window.editUser = async function(id) {
     try {
-        const response = await fetch(`${API_BASE_URL}/${id}`);
+        const response = await apiFetch(`${API_BASE_URL}/${id}`);
         if (!response.ok) throw new Error('Erreur lors du chargement');
         
         const user = await response.json();
-        showForm(user);
+        showForm(user, response.headers.get('ETag'));
     } catch (error) {
         showMessage('Erreur lors du chargement de l\'usager: ' + error.message, 'error');
     }
 };
 
 // Supprimer un usager
-window.deleteUser = async function(id) {
+window.deleteUser = async function(id, version) {
     if (!confirm('Êtes-vous sûr de vouloir supprimer cet usager ?')) {
         return;
     }
     
     try {
-        const response = await fetch(`${API_BASE_URL}/${id}`, {
-            method: 'DELETE'
+        // Refusé (412) si l'usager a été modifié par quelqu'un d'autre depuis l'affichage de la liste
+        const response = await apiFetch(`${API_BASE_URL}/${id}`, {
+            method: 'DELETE',
+            headers: { 'If-Match': `"${version}"` }
         });
         
+        if (response.status === 412) {
+            throw new Error('Cet usager a été modifié par quelqu\'un d\'autre. Rechargez la liste avant de réessayer.');
+        }
         if (!response.ok) {
             const error = await response.json();
-            throw new Error(error.error || 'Erreur lors de la suppression');
+            throw new Error(problemMessage(error, 'Erreur lors de la suppression'));
         }
         
         showMessage('Usager supprimé avec succès', 'success');
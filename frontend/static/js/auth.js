@@ -0,0 +1,132 @@
+// Authentification du personnel : connexion, jetons et requêtes authentifiées
+
+import { AUTH_API_URL } from './config.js';
+import { problemMessage } from './utils.js';
+
+// Les jetons sont conservés pour la durée de l'onglet seulement
+const ACCESS_TOKEN_KEY = 'access_token';
+const REFRESH_TOKEN_KEY = 'refresh_token';
+const PERMISSIONS_KEY = 'permissions';
+
+let loginRequiredHandler = () => {};
+
+/**
+ * Définit la fonction appelée lorsque l'API exige une (nouvelle) connexion
+ * @param {Function} handler - Affiche le formulaire de connexion
+ */
+export function onLoginRequired(handler) {
+    loginRequiredHandler = handler;
+}
+
+/**
+ * Indique si des jetons sont conservés pour cet onglet
+ * @returns {boolean}
+ */
+export function isLoggedIn() {
+    return sessionStorage.getItem(REFRESH_TOKEN_KEY) !== null;
+}
+
+/**
+ * Indique si le rôle du compte connecté accorde la permission (ex: 'users:delete').
+ * Sans connexion (authentification désactivée), tout est permis; le serveur reste seul juge.
+ * @param {string} permission
+ * @returns {boolean}
+ */
+export function can(permission) {
+    if (!isLoggedIn()) return true;
+    return JSON.parse(sessionStorage.getItem(PERMISSIONS_KEY) || '[]').includes(permission);
+}
+
+/**
+ * Connecte un membre du personnel et conserve ses jetons
+ * @param {string} email
+ * @param {string} password
+ * @returns {Promise<Object>} Le compte connecté
+ */
+export async function login(email, password) {
+    const response = await fetch(`${AUTH_API_URL}/login`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json', 'Accept-Language': document.documentElement.lang },
+        body: JSON.stringify({ email, password })
+    });
+    const data = await response.json();
+    if (!response.ok) {
+        throw new Error(problemMessage(data, 'Connexion impossible'));
+    }
+    storeTokens(data);
+    return data.staff;
+}
+
+/**
+ * Révoque les jetons de la session et les oublie
+ */
+export async function logout() {
+    const refreshToken = sessionStorage.getItem(REFRESH_TOKEN_KEY);
+    try {
+        await fetch(`${AUTH_API_URL}/logout`, withToken({
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken })
+        }));
+    } finally {
+        clearTokens();
+    }
+}
+
+/**
+ * Exécute une requête vers l'API avec le jeton d'accès. Si le jeton a expiré, il est
+ * renouvelé une fois avec le jeton de rafraîchissement; sinon une connexion est demandée.
+ * @param {string} url
+ * @param {Object} options - Options de fetch
+ * @returns {Promise<Response>}
+ */
+export async function apiFetch(url, options = {}) {
+    let response = await fetch(url, withToken(options));
+    if (response.status === 401 && await refreshTokens()) {
+        response = await fetch(url, withToken(options));
+    }
+    if (response.status === 401) {
+        clearTokens();
+        loginRequiredHandler();
+        throw new Error('Veuillez vous connecter');
+    }
+    return response;
+}
+
+// Renouvelle les jetons; retourne false si le jeton de rafraîchissement est absent ou refusé
+async function refreshTokens() {
+    const refreshToken = sessionStorage.getItem(REFRESH_TOKEN_KEY);
+    if (!refreshToken) return false;
+
+    const response = await fetch(`${AUTH_API_URL}/refresh`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken })
+    });
+    if (!response.ok) return false;
+
+    storeTokens(await response.json());
+    return true;
+}
+
+// Ajoute l'en-tête Authorization et la langue de l'interface (messages de l'API) aux options de fetch
+function withToken(options) {
+    const headers = { 'Accept-Language': document.documentElement.lang, ...options.headers };
+    const token = sessionStorage.getItem(ACCESS_TOKEN_KEY);
+    if (token) {
+        headers.Authorization = `Bearer ${token}`;
+    }
+    return { ...options, headers };
+}
+
+function storeTokens(data) {
+    sessionStorage.setItem(ACCESS_TOKEN_KEY, data.access_token);
+    sessionStorage.setItem(REFRESH_TOKEN_KEY, data.refresh_token);
+    sessionStorage.setItem(PERMISSIONS_KEY, JSON.stringify(data.staff.permissions || []));
+}
+
+function clearTokens() {
+    sessionStorage.removeItem(ACCESS_TOKEN_KEY);
+    sessionStorage.removeItem(REFRESH_TOKEN_KEY);
+    sessionStorage.removeItem(PERMISSIONS_KEY);
+}
@@ -1,6 +1,8 @@
 // Configuration et constantes de l'application
 
 export const API_BASE_URL = '/api/users';
+export const LEVELS_API_URL = '/api/levels';
+export const AUTH_API_URL = '/api/auth';
 
 export const DEFAULT_PAGE = 1;
 export const DEFAULT_LIMIT = 10;
@@ -14,4 +14,22 @@ export function escapeHtml(text) {
     return div.innerHTML;
 }
 
-
+/**
+ * Construit le message d'une réponse d'erreur de l'API (application/problem+json)
+ * @param {Object} problem - Corps de la réponse : detail, errors (champs invalides), request_id
+ * @param {string} fallback - Message par défaut si la réponse n'a pas de détail
+ * @returns {string} Message à afficher
+ */
+export function problemMessage(problem, fallback) {
+    if (!problem) {
+        return fallback;
+    }
+    let message = problem.detail || fallback;
+    if (Array.isArray(problem.errors) && problem.errors.length > 0) {
+        message += ' : ' + problem.errors.map(e => `${e.field} : ${e.message}`).join(', ');
+    }
+    if (problem.status >= 500 && problem.request_id) {
+        message += ` (référence : ${problem.request_id})`;
+    }
+    return message;
+}